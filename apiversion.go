@@ -0,0 +1,210 @@
+package aoai
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Known data plane api-versions.
+const (
+	APIVersion20221201        = "2022-12-01"
+	APIVersion20230515        = "2023-05-15"
+	APIVersion20231201Preview = "2023-12-01-preview"
+	APIVersion20240201        = "2024-02-01"
+	APIVersion20240215Preview = "2024-02-15-preview"
+	APIVersion20240601        = "2024-06-01"
+	APIVersion20240801Preview = "2024-08-01-preview"
+	APIVersion20240901Preview = "2024-09-01-preview"
+	APIVersion20241021        = "2024-10-21"
+	APIVersion20241201Preview = "2024-12-01-preview"
+	APIVersion20250301Preview = "2025-03-01-preview"
+	APIVersion20250401Preview = "2025-04-01-preview"
+)
+
+// Feature is a request capability that is only available from some api-version on.
+type Feature string
+
+const (
+	FeatureTools                    Feature = "tools"
+	FeatureResponseFormatJSONObject Feature = "response_format json_object"
+	FeatureResponseFormatJSONSchema Feature = "response_format json_schema"
+	FeatureDataSources              Feature = "data_sources"
+	FeatureLogprobs                 Feature = "logprobs"
+	FeatureSeed                     Feature = "seed"
+	FeatureStreamOptions            Feature = "stream_options"
+	FeatureMaxCompletionTokens      Feature = "max_completion_tokens"
+	FeatureReasoningEffort          Feature = "reasoning_effort"
+	FeatureDeveloperRole            Feature = "developer role"
+	FeatureResponses                Feature = "responses API"
+)
+
+// featureSince is the first GA and the first preview api-version supporting a feature. An empty GA version means
+// the feature is only available in previews.
+type featureSince struct {
+	ga      string
+	preview string
+}
+
+// features is the capability table of the known api-versions. Preview versions support every feature of the GA
+// versions released before them.
+var features = map[Feature]featureSince{
+	FeatureTools:                    {ga: APIVersion20240201, preview: APIVersion20231201Preview},
+	FeatureResponseFormatJSONObject: {ga: APIVersion20240201, preview: APIVersion20231201Preview},
+	FeatureResponseFormatJSONSchema: {ga: APIVersion20241021, preview: APIVersion20240801Preview},
+	FeatureDataSources:              {ga: APIVersion20240201, preview: APIVersion20240215Preview},
+	FeatureLogprobs:                 {ga: APIVersion20240201, preview: APIVersion20231201Preview},
+	FeatureSeed:                     {ga: APIVersion20240201, preview: APIVersion20231201Preview},
+	FeatureStreamOptions:            {ga: APIVersion20241021, preview: APIVersion20240901Preview},
+	FeatureMaxCompletionTokens:      {ga: APIVersion20241021, preview: APIVersion20240901Preview},
+	FeatureReasoningEffort:          {preview: APIVersion20241201Preview},
+	FeatureDeveloperRole:            {preview: APIVersion20241201Preview},
+	FeatureResponses:                {preview: APIVersion20250301Preview},
+}
+
+var apiVersionPattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})(-preview)?$`)
+
+// SupportsFeature reports whether apiVersion supports feature. known is false when apiVersion or feature is not
+// in the capability table, in which case supported is true.
+func SupportsFeature(apiVersion string, feature Feature) (supported bool, known bool) {
+	match := apiVersionPattern.FindStringSubmatch(apiVersion)
+	since, ok := features[feature]
+	if match == nil || !ok {
+		return true, false
+	}
+
+	date, preview := match[1], match[2] != ""
+	if since.ga != "" && date >= since.ga {
+		return true, true
+	}
+	if preview && date >= strings.TrimSuffix(since.preview, "-preview") {
+		return true, true
+	}
+	return false, true
+}
+
+// UnsupportedFeatureError is returned when a request uses features the configured api-version does not support.
+type UnsupportedFeatureError struct {
+	APIVersion string
+	Features   []Feature
+}
+
+func (e *UnsupportedFeatureError) Error() string {
+	names := make([]string, len(e.Features))
+	for i, feature := range e.Features {
+		names[i] = string(feature)
+	}
+	return fmt.Sprintf("api-version %s does not support %s", e.APIVersion, strings.Join(names, ", "))
+}
+
+type FeatureCheck int
+
+const (
+	// FeatureCheckOff sends requests without checking their features.
+	FeatureCheckOff FeatureCheck = iota
+	// FeatureCheckWarn reports requests using unsupported features to the function set by
+	// WithFeatureCheckWarning, or to the standard logger, and sends them anyway.
+	FeatureCheckWarn
+	// FeatureCheckError returns an *UnsupportedFeatureError instead of sending the request.
+	FeatureCheckError
+)
+
+// WithFeatureCheck checks the features of chat and responses requests against the capability table of the
+// configured api-version.
+func WithFeatureCheck(mode FeatureCheck) Option {
+	return func(a *AzureOpenAI) {
+		a.featureCheck = mode
+	}
+}
+
+// WithFeatureCheckWarning makes FeatureCheckWarn report requests using unsupported features to warn instead of
+// the standard logger, e.g. to use the logger of the application.
+func WithFeatureCheckWarning(warn func(err *UnsupportedFeatureError)) Option {
+	return func(a *AzureOpenAI) {
+		a.featureWarn = warn
+	}
+}
+
+func (a *AzureOpenAI) checkFeatures(features []Feature) error {
+	if a.featureCheck == FeatureCheckOff {
+		return nil
+	}
+
+	var unsupported []Feature
+	for _, feature := range features {
+		if supported, _ := SupportsFeature(a.apiVersion, feature); !supported {
+			unsupported = append(unsupported, feature)
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+
+	err := &UnsupportedFeatureError{APIVersion: a.apiVersion, Features: unsupported}
+	if a.featureCheck == FeatureCheckWarn {
+		if a.featureWarn != nil {
+			a.featureWarn(err)
+		} else {
+			log.Printf("aoai: %v", err)
+		}
+		return nil
+	}
+	return err
+}
+
+// Features returns the version dependent features the request uses, including those set through ExtraBody.
+func (r ChatRequest) Features() []Feature {
+	var used []Feature
+	if r.Logprobs || r.TopLogprobs != 0 {
+		used = append(used, FeatureLogprobs)
+	}
+	if r.Seed != nil {
+		used = append(used, FeatureSeed)
+	}
+	if r.MaxCompletionTokens != 0 {
+		used = append(used, FeatureMaxCompletionTokens)
+	}
+	if r.ReasoningEffort != "" {
+		used = append(used, FeatureReasoningEffort)
+	}
+	for _, message := range r.Messages {
+		if message.Role == RoleDeveloper {
+			used = append(used, FeatureDeveloperRole)
+			break
+		}
+	}
+
+	if _, ok := r.ExtraBody["tools"]; ok || len(r.Tools) > 0 {
+		used = append(used, FeatureTools)
+	}
+	if _, ok := r.ExtraBody["data_sources"]; ok {
+		used = append(used, FeatureDataSources)
+	}
+	if _, ok := r.ExtraBody["stream_options"]; ok || r.StreamOptions != nil {
+		used = append(used, FeatureStreamOptions)
+	}
+	if format, ok := r.ExtraBody["response_format"]; ok {
+		switch responseFormatType(format) {
+		case "json_object":
+			used = append(used, FeatureResponseFormatJSONObject)
+		case "json_schema":
+			used = append(used, FeatureResponseFormatJSONSchema)
+		}
+	}
+	return used
+}
+
+// responseFormatType returns the `type` of a response_format value of any JSON encodable form.
+func responseFormatType(format any) string {
+	m, err := json.Marshal(format)
+	if err != nil {
+		return ""
+	}
+	var typed struct {
+		Type string `json:"type"`
+	}
+	_ = json.Unmarshal(m, &typed)
+	return typed.Type
+}
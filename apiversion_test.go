@@ -0,0 +1,95 @@
+package aoai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSupportsFeature(t *testing.T) {
+	tests := []struct {
+		name          string
+		apiVersion    string
+		feature       Feature
+		wantSupported bool
+		wantKnown     bool
+	}{
+		{name: "gaBefore", apiVersion: APIVersion20230515, feature: FeatureTools, wantSupported: false, wantKnown: true},
+		{name: "gaSince", apiVersion: APIVersion20240201, feature: FeatureTools, wantSupported: true, wantKnown: true},
+		{name: "previewSince", apiVersion: APIVersion20231201Preview, feature: FeatureSeed, wantSupported: true, wantKnown: true},
+		{name: "previewAfterGA", apiVersion: APIVersion20240901Preview, feature: FeatureLogprobs, wantSupported: true, wantKnown: true},
+		{name: "previewBefore", apiVersion: APIVersion20240801Preview, feature: FeatureStreamOptions, wantSupported: false, wantKnown: true},
+		{name: "previewOnlyOnGA", apiVersion: APIVersion20241021, feature: FeatureReasoningEffort, wantSupported: false, wantKnown: true},
+		{name: "previewOnly", apiVersion: APIVersion20250401Preview, feature: FeatureResponses, wantSupported: true, wantKnown: true},
+		{name: "unknownVersion", apiVersion: "v1", feature: FeatureTools, wantSupported: true, wantKnown: false},
+		{name: "unknownFeature", apiVersion: APIVersion20230515, feature: "audio", wantSupported: true, wantKnown: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			supported, known := SupportsFeature(tt.apiVersion, tt.feature)
+			if supported != tt.wantSupported || known != tt.wantKnown {
+				t.Errorf("SupportsFeature() = %v, %v, want %v, %v", supported, known, tt.wantSupported, tt.wantKnown)
+			}
+		})
+	}
+}
+
+func TestChatRequest_Features(t *testing.T) {
+	seed := 1
+	request := ChatRequest{
+		Messages:            []ChatMessage{{Role: RoleDeveloper, Content: "Be brief."}},
+		TopLogprobs:         2,
+		Seed:                &seed,
+		MaxCompletionTokens: 100,
+		ExtraBody: map[string]any{
+			"tools":           []any{},
+			"response_format": map[string]any{"type": "json_schema"},
+		},
+	}
+	want := []Feature{FeatureLogprobs, FeatureSeed, FeatureMaxCompletionTokens, FeatureDeveloperRole, FeatureTools, FeatureResponseFormatJSONSchema}
+	if got := request.Features(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Features() = %v, want %v", got, want)
+	}
+}
+
+func TestWithFeatureCheck(t *testing.T) {
+	calls := 0
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	a.apiVersion = APIVersion20240201
+	request := ChatRequest{Messages: []ChatMessage{{Role: RoleUser, Content: "Hi"}}, MaxCompletionTokens: 100, Logprobs: true}
+	var warnings []*UnsupportedFeatureError
+	WithFeatureCheckWarning(func(err *UnsupportedFeatureError) { warnings = append(warnings, err) })(a)
+
+	for _, tt := range []struct {
+		mode      FeatureCheck
+		wantErr   bool
+		wantCalls int
+	}{
+		{mode: FeatureCheckOff, wantCalls: 1},
+		{mode: FeatureCheckWarn, wantCalls: 2},
+		{mode: FeatureCheckError, wantErr: true, wantCalls: 2},
+	} {
+		WithFeatureCheck(tt.mode)(a)
+		_, err := a.ChatCompletion(context.Background(), request)
+		var unsupported *UnsupportedFeatureError
+		if errors.As(err, &unsupported) != tt.wantErr || calls != tt.wantCalls {
+			t.Errorf("mode %d: ChatCompletion() error = %v, calls = %d", tt.mode, err, calls)
+		}
+		if tt.wantErr && !reflect.DeepEqual(unsupported.Features, []Feature{FeatureMaxCompletionTokens}) {
+			t.Errorf("Features = %v", unsupported.Features)
+		}
+	}
+
+	if len(warnings) != 1 || !reflect.DeepEqual(warnings[0].Features, []Feature{FeatureMaxCompletionTokens}) {
+		t.Errorf("warnings = %v, want one in FeatureCheckWarn mode", warnings)
+	}
+
+	if _, err := a.CreateResponse(context.Background(), ResponsesRequest{}); err == nil || calls != 2 {
+		t.Errorf("CreateResponse() error = %v, calls = %d", err, calls)
+	}
+}
@@ -0,0 +1,306 @@
+package aoai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+type Tool struct {
+	// type:
+	//   type: string
+	//   enum:
+	//     - code_interpreter
+	//     - file_search
+	//     - function
+	Type string `json:"type"`
+
+	// function:
+	//   type: FunctionDefinition
+	Function *FunctionDefinition `json:"function,omitempty"`
+}
+
+type FunctionDefinition struct {
+	// name:
+	//   description: The name of the function to be called. Must be a-z, A-Z, 0-9, or contain underscores and dashes.
+	//   type: string
+	Name string `json:"name"`
+
+	// description:
+	//   description: A description of what the function does, used by the model to choose when and how to call it.
+	//   type: string
+	Description string `json:"description,omitempty"`
+
+	// parameters:
+	//   description: The parameters the function accepts, described as a JSON Schema object.
+	//   type: object
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+
+	// strict:
+	//   description: Whether to enable strict schema adherence when generating the function call.
+	//   type: boolean
+	Strict bool `json:"strict,omitempty"`
+}
+
+type ToolCall struct {
+	// index:
+	//   description: The position of the tool call in a streamed chat completion delta.
+	//   type: integer
+	Index *int `json:"index,omitempty"`
+
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// type:
+	//   type: string
+	//   enum:
+	//     - function
+	Type string `json:"type,omitempty"`
+
+	// function:
+	//   type: FunctionCall
+	Function FunctionCall `json:"function,omitempty"`
+}
+
+type FunctionCall struct {
+	// name:
+	//   type: string
+	Name string `json:"name,omitempty"`
+
+	// arguments:
+	//   description: The arguments to call the function with, as generated by the model in JSON format.
+	//   type: string
+	Arguments string `json:"arguments,omitempty"`
+}
+
+type ToolResources struct {
+	// code_interpreter:
+	//   type: CodeInterpreterResources
+	CodeInterpreter *CodeInterpreterResources `json:"code_interpreter,omitempty"`
+
+	// file_search:
+	//   type: FileSearchResources
+	FileSearch *FileSearchResources `json:"file_search,omitempty"`
+}
+
+type CodeInterpreterResources struct {
+	// file_ids:
+	//   type: array
+	//   maxItems: 20
+	//   items:
+	//     type: string
+	FileIDs []string `json:"file_ids,omitempty"`
+}
+
+type FileSearchResources struct {
+	// vector_store_ids:
+	//   type: array
+	//   maxItems: 1
+	//   items:
+	//     type: string
+	VectorStoreIDs []string `json:"vector_store_ids,omitempty"`
+}
+
+type AssistantRequest struct {
+	// model:
+	//   description: The deployment name of the model to use.
+	//   type: string
+	Model string `json:"model,omitempty"`
+
+	// name:
+	//   type: string
+	//   maxLength: 256
+	//   nullable: true
+	Name string `json:"name,omitempty"`
+
+	// description:
+	//   type: string
+	//   maxLength: 512
+	//   nullable: true
+	Description string `json:"description,omitempty"`
+
+	// instructions:
+	//   description: The system instructions that the assistant uses.
+	//   type: string
+	//   maxLength: 256000
+	//   nullable: true
+	Instructions string `json:"instructions,omitempty"`
+
+	// tools:
+	//   type: []Tool
+	//   maxItems: 128
+	Tools []Tool `json:"tools,omitempty"`
+
+	// tool_resources:
+	//   type: ToolResources
+	//   nullable: true
+	ToolResources *ToolResources `json:"tool_resources,omitempty"`
+
+	// metadata:
+	//   description: Up to 16 key-value pairs attached to the object.
+	//   type: object
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// temperature:
+	//   type: number
+	//   minimum: 0
+	//   maximum: 2
+	//   nullable: true
+	Temperature float64 `json:"temperature,omitempty"`
+
+	// top_p:
+	//   type: number
+	//   minimum: 0
+	//   maximum: 1
+	//   nullable: true
+	TopP float64 `json:"top_p,omitempty"`
+}
+
+// AssistantUpdateRequest modifies an assistant. Unlike AssistantRequest, a nil field is left unchanged and a non-nil
+// field is sent even if empty, so that `temperature` and `top_p` can be set to 0 and instructions or tools can be
+// cleared.
+type AssistantUpdateRequest struct {
+	// model:
+	//   description: The deployment name of the model to use.
+	//   type: string
+	Model *string `json:"model,omitempty"`
+
+	// name:
+	//   type: string
+	//   maxLength: 256
+	//   nullable: true
+	Name *string `json:"name,omitempty"`
+
+	// description:
+	//   type: string
+	//   maxLength: 512
+	//   nullable: true
+	Description *string `json:"description,omitempty"`
+
+	// instructions:
+	//   description: The system instructions that the assistant uses.
+	//   type: string
+	//   maxLength: 256000
+	//   nullable: true
+	Instructions *string `json:"instructions,omitempty"`
+
+	// tools:
+	//   type: []Tool
+	//   maxItems: 128
+	Tools *[]Tool `json:"tools,omitempty"`
+
+	// tool_resources:
+	//   type: ToolResources
+	//   nullable: true
+	ToolResources *ToolResources `json:"tool_resources,omitempty"`
+
+	// metadata:
+	//   description: Up to 16 key-value pairs attached to the object.
+	//   type: object
+	Metadata *map[string]string `json:"metadata,omitempty"`
+
+	// temperature:
+	//   type: number
+	//   minimum: 0
+	//   maximum: 2
+	//   nullable: true
+	Temperature *float64 `json:"temperature,omitempty"`
+
+	// top_p:
+	//   type: number
+	//   minimum: 0
+	//   maximum: 1
+	//   nullable: true
+	TopP *float64 `json:"top_p,omitempty"`
+}
+
+type Assistant struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - assistant
+	Object string `json:"object,omitempty"`
+
+	// created_at:
+	//   type: integer
+	//   format: unixtime
+	CreatedAt int `json:"created_at,omitempty"`
+
+	// name:
+	//   type: string
+	Name string `json:"name,omitempty"`
+
+	// description:
+	//   type: string
+	Description string `json:"description,omitempty"`
+
+	// model:
+	//   type: string
+	Model string `json:"model,omitempty"`
+
+	// instructions:
+	//   type: string
+	Instructions string `json:"instructions,omitempty"`
+
+	// tools:
+	//   type: []Tool
+	Tools []Tool `json:"tools,omitempty"`
+
+	// tool_resources:
+	//   type: ToolResources
+	ToolResources *ToolResources `json:"tool_resources,omitempty"`
+
+	// metadata:
+	//   type: object
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// temperature:
+	//   type: number
+	Temperature float64 `json:"temperature,omitempty"`
+
+	// top_p:
+	//   type: number
+	TopP float64 `json:"top_p,omitempty"`
+}
+
+func (a *AzureOpenAI) assistantsEndpoint(path string, values url.Values) string {
+	if values == nil {
+		values = url.Values{"api-version": []string{a.apiVersion}}
+	}
+	return fmt.Sprintf("%s%s?%s", a.resourceEndpoint(), path, values.Encode())
+}
+
+func (a *AzureOpenAI) CreateAssistant(ctx context.Context, request AssistantRequest) (*Assistant, error) {
+	if request.Model == "" {
+		request.Model = a.deploymentName
+	}
+	endpoint := a.assistantsEndpoint("/assistants", nil)
+	return callPost[AssistantRequest, Assistant](ctx, a, OperationCreateAssistant, endpoint, request)
+}
+
+func (a *AzureOpenAI) GetAssistant(ctx context.Context, assistantID string) (*Assistant, error) {
+	endpoint := a.assistantsEndpoint("/assistants/"+url.PathEscape(assistantID), nil)
+	return callGet[Assistant](ctx, a, OperationGetAssistant, endpoint)
+}
+
+// UpdateAssistant modifies an assistant. Only the non-nil fields of request are changed.
+func (a *AzureOpenAI) UpdateAssistant(ctx context.Context, assistantID string, request AssistantUpdateRequest) (*Assistant, error) {
+	endpoint := a.assistantsEndpoint("/assistants/"+url.PathEscape(assistantID), nil)
+	return callPost[AssistantUpdateRequest, Assistant](ctx, a, OperationUpdateAssistant, endpoint, request)
+}
+
+func (a *AzureOpenAI) DeleteAssistant(ctx context.Context, assistantID string) (*DeleteResponse, error) {
+	endpoint := a.assistantsEndpoint("/assistants/"+url.PathEscape(assistantID), nil)
+	return callDelete[DeleteResponse](ctx, a, OperationDeleteAssistant, endpoint)
+}
+
+func (a *AzureOpenAI) ListAssistants(ctx context.Context, options ListOptions) (*ListResponse[Assistant], error) {
+	endpoint := a.assistantsEndpoint("/assistants", options.values(a.apiVersion))
+	return callGet[ListResponse[Assistant]](ctx, a, OperationListAssistants, endpoint)
+}
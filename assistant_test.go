@@ -0,0 +1,218 @@
+package aoai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAssistantsService emulates an assistant whose runs call a single function before completing.
+type fakeAssistantsService struct {
+	mu          sync.Mutex
+	runStatus   string
+	toolOutputs []ToolOutput
+	cancelled   bool
+	updateBody  []byte
+}
+
+func (s *fakeAssistantsService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("OpenAI-Beta") != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method + " " + r.URL.Path {
+	case "POST /openai/assistants":
+		var request AssistantRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		_ = json.NewEncoder(w).Encode(Assistant{ID: "asst-1", Model: request.Model, Tools: request.Tools})
+	case "POST /openai/assistants/asst-1":
+		s.updateBody, _ = io.ReadAll(r.Body)
+		var request AssistantRequest
+		_ = json.Unmarshal(s.updateBody, &request)
+		_ = json.NewEncoder(w).Encode(Assistant{ID: "asst-1", Model: "gpt-4o", Instructions: request.Instructions, Temperature: request.Temperature})
+	case "POST /openai/threads":
+		var request ThreadRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		_ = json.NewEncoder(w).Encode(Thread{ID: "thread-1", Metadata: map[string]string{"attachments": request.Messages[0].Attachments[0].FileID}})
+	case "POST /openai/threads/thread-1/runs":
+		s.runStatus = RunStatusQueued
+		_ = json.NewEncoder(w).Encode(Run{ID: "run-1", ThreadID: "thread-1", Status: s.runStatus})
+	case "GET /openai/threads/thread-1/runs/run-1":
+		run := Run{ID: "run-1", ThreadID: "thread-1", Status: s.runStatus}
+		switch s.runStatus {
+		case RunStatusQueued:
+			s.runStatus = RunStatusRequiresAction
+		case RunStatusRequiresAction:
+			run.RequiredAction = &RequiredAction{Type: "submit_tool_outputs"}
+			run.RequiredAction.SubmitToolOutputs.ToolCalls = []ToolCall{
+				{ID: "call-1", Type: "function", Function: FunctionCall{Name: "get_weather", Arguments: `{"city":"Tokyo"}`}},
+			}
+		}
+		_ = json.NewEncoder(w).Encode(run)
+	case "POST /openai/threads/thread-1/runs/run-1/submit_tool_outputs":
+		var request SubmitToolOutputsRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		s.toolOutputs = request.ToolOutputs
+		s.runStatus = RunStatusCompleted
+		_ = json.NewEncoder(w).Encode(Run{ID: "run-1", Status: RunStatusInProgress})
+	case "POST /openai/threads/thread-1/runs/run-1/cancel":
+		s.cancelled = true
+		s.runStatus = RunStatusCancelled
+		_ = json.NewEncoder(w).Encode(Run{ID: "run-1", Status: RunStatusCancelling})
+	case "GET /openai/threads/thread-1/messages":
+		if r.URL.Query().Get("order") != "asc" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"code":"bad_request","message":"unexpected order"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"msg-2","role":"assistant","content":[{"type":"text","text":{"value":"Sunny ","annotations":[]}},{"type":"text","text":{"value":"in Tokyo"}}]}],"first_id":"msg-2","last_id":"msg-2"}`))
+	case "GET /openai/threads/thread-1/runs/run-1/steps":
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"step-1","type":"tool_calls","step_details":{"type":"tool_calls","tool_calls":[{"id":"call-1","type":"function","function":{"name":"get_weather","arguments":"{}","output":"sunny"}}]}}]}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"code":"404","message":"not found"}}`))
+	}
+}
+
+func TestAzureOpenAI_Assistants(t *testing.T) {
+	service := &fakeAssistantsService{}
+	a := newTestAzureOpenAI(t, service)
+	ctx := context.Background()
+	options := PollOptions{Interval: time.Millisecond}
+
+	assistant, err := a.CreateAssistant(ctx, AssistantRequest{
+		Instructions: "You are a weather bot.",
+		Tools: []Tool{{Type: "function", Function: &FunctionDefinition{
+			Name:       "get_weather",
+			Parameters: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`),
+		}}},
+	})
+	if err != nil || assistant.Model != a.deploymentName || assistant.Tools[0].Function.Name != "get_weather" {
+		t.Fatalf("CreateAssistant() = %+v, %v", assistant, err)
+	}
+
+	instructions, temperature := "Be brief.", 0.0
+	assistant, err = a.UpdateAssistant(ctx, assistant.ID, AssistantUpdateRequest{
+		Instructions: &instructions,
+		Tools:        &[]Tool{},
+		Temperature:  &temperature,
+	})
+	if err != nil || assistant.Instructions != "Be brief." {
+		t.Errorf("UpdateAssistant() = %+v, %v", assistant, err)
+	}
+	if got := string(service.updateBody); got != `{"instructions":"Be brief.","tools":[],"temperature":0}` {
+		t.Errorf("UpdateAssistant() sent %s", got)
+	}
+
+	thread, err := a.CreateThread(ctx, ThreadRequest{Messages: []MessageRequest{{
+		Role:        "user",
+		Content:     "How is the weather in Tokyo?",
+		Attachments: []MessageAttachment{{FileID: "file-1", Tools: []Tool{{Type: "file_search"}}}},
+	}}})
+	if err != nil || thread.Metadata["attachments"] != "file-1" {
+		t.Fatalf("CreateThread() = %+v, %v", thread, err)
+	}
+
+	run, err := a.CreateRun(ctx, thread.ID, RunRequest{AssistantID: assistant.ID})
+	if err != nil {
+		t.Fatalf("CreateRun() error = %v", err)
+	}
+
+	run, err = a.CompleteRun(ctx, thread.ID, run.ID, options, func(ctx context.Context, call ToolCall) (string, error) {
+		if call.Function.Name != "get_weather" || call.Function.Arguments != `{"city":"Tokyo"}` {
+			t.Errorf("CompleteRun() called tool %+v", call)
+		}
+		return "sunny", nil
+	})
+	if err != nil || run.Status != RunStatusCompleted {
+		t.Fatalf("CompleteRun() = %+v, %v", run, err)
+	}
+	if len(service.toolOutputs) != 1 || service.toolOutputs[0] != (ToolOutput{ToolCallID: "call-1", Output: "sunny"}) {
+		t.Errorf("CompleteRun() submitted %+v", service.toolOutputs)
+	}
+
+	messages, err := a.ListMessages(ctx, thread.ID, ListOptions{Order: "asc"})
+	if err != nil || messages.Data[0].Text() != "Sunny in Tokyo" {
+		t.Errorf("ListMessages() = %+v, %v", messages, err)
+	}
+
+	steps, err := a.ListRunSteps(ctx, thread.ID, run.ID, ListOptions{})
+	if err != nil || steps.Data[0].StepDetails.ToolCalls[0].Function.Output != "sunny" {
+		t.Errorf("ListRunSteps() = %+v, %v", steps, err)
+	}
+
+	if _, err := a.CreateRun(ctx, thread.ID, RunRequest{AssistantID: assistant.ID, Stream: true}); err == nil {
+		t.Errorf("CreateRun() accepted a streaming request")
+	}
+}
+
+func TestAzureOpenAI_CompleteRun_handlerError(t *testing.T) {
+	service := &fakeAssistantsService{}
+	a := newTestAzureOpenAI(t, service)
+	ctx := context.Background()
+
+	run, err := a.CreateRun(ctx, "thread-1", RunRequest{AssistantID: "asst-1"})
+	if err != nil {
+		t.Fatalf("CreateRun() error = %v", err)
+	}
+
+	e := errors.New("weather service is down")
+	_, err = a.CompleteRun(ctx, "thread-1", run.ID, PollOptions{Interval: time.Millisecond}, func(ctx context.Context, call ToolCall) (string, error) {
+		return "", e
+	})
+	if !errors.Is(err, e) {
+		t.Errorf("CompleteRun() error = %v, want %v", err, e)
+	}
+	if !service.cancelled {
+		t.Errorf("CompleteRun() did not cancel the run")
+	}
+}
+
+func TestAzureOpenAI_CompleteRun_hungCancel(t *testing.T) {
+	service := &fakeAssistantsService{}
+	release := make(chan struct{})
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/cancel") {
+			<-release
+			return
+		}
+		service.ServeHTTP(w, r)
+	}))
+	t.Cleanup(func() { close(release) })
+	timeout := cancelRunTimeout
+	cancelRunTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { cancelRunTimeout = timeout })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run, err := a.CreateRun(ctx, "thread-1", RunRequest{AssistantID: "asst-1"})
+	if err != nil {
+		t.Fatalf("CreateRun() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := a.CompleteRun(ctx, "thread-1", run.ID, PollOptions{Interval: time.Millisecond}, func(ctx context.Context, call ToolCall) (string, error) {
+			cancel()
+			return "", ctx.Err()
+		})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("CompleteRun() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("CompleteRun() blocked on a hung cancel")
+	}
+}
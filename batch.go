@@ -0,0 +1,444 @@
+package aoai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	BatchStatusValidating = "validating"
+	BatchStatusFailed     = "failed"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+type BatchRequest struct {
+	// input_file_id:
+	//   description: The ID of an uploaded JSONL file with purpose `batch`.
+	//   type: string
+	InputFileID string `json:"input_file_id"`
+
+	// endpoint:
+	//   description: The endpoint to be used for all requests in the batch.
+	//   type: string
+	//   enum:
+	//     - /chat/completions
+	//     - /embeddings
+	Endpoint string `json:"endpoint"`
+
+	// completion_window:
+	//   description: The time frame within which the batch should be processed.
+	//   type: string
+	//   default: 24h
+	CompletionWindow string `json:"completion_window"`
+
+	// metadata:
+	//   type: object
+	//   additionalProperties:
+	//     type: string
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type Batch struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - batch
+	Object string `json:"object,omitempty"`
+
+	// endpoint:
+	//   type: string
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// errors:
+	//   type: BatchErrors
+	Errors *BatchErrors `json:"errors,omitempty"`
+
+	// input_file_id:
+	//   type: string
+	InputFileID string `json:"input_file_id,omitempty"`
+
+	// completion_window:
+	//   type: string
+	CompletionWindow string `json:"completion_window,omitempty"`
+
+	// status:
+	//   type: string
+	//   enum:
+	//     - validating
+	//     - failed
+	//     - in_progress
+	//     - finalizing
+	//     - completed
+	//     - expired
+	//     - cancelling
+	//     - cancelled
+	Status string `json:"status,omitempty"`
+
+	// output_file_id:
+	//   description: The ID of the file containing the outputs of successfully executed requests.
+	//   type: string
+	OutputFileID string `json:"output_file_id,omitempty"`
+
+	// error_file_id:
+	//   description: The ID of the file containing the outputs of requests with errors.
+	//   type: string
+	ErrorFileID string `json:"error_file_id,omitempty"`
+
+	// created_at:
+	//   type: integer
+	//   format: unixtime
+	CreatedAt int `json:"created_at,omitempty"`
+
+	// in_progress_at:
+	//   type: integer
+	//   format: unixtime
+	InProgressAt int `json:"in_progress_at,omitempty"`
+
+	// expires_at:
+	//   type: integer
+	//   format: unixtime
+	ExpiresAt int `json:"expires_at,omitempty"`
+
+	// finalizing_at:
+	//   type: integer
+	//   format: unixtime
+	FinalizingAt int `json:"finalizing_at,omitempty"`
+
+	// completed_at:
+	//   type: integer
+	//   format: unixtime
+	CompletedAt int `json:"completed_at,omitempty"`
+
+	// failed_at:
+	//   type: integer
+	//   format: unixtime
+	FailedAt int `json:"failed_at,omitempty"`
+
+	// expired_at:
+	//   type: integer
+	//   format: unixtime
+	ExpiredAt int `json:"expired_at,omitempty"`
+
+	// cancelling_at:
+	//   type: integer
+	//   format: unixtime
+	CancellingAt int `json:"cancelling_at,omitempty"`
+
+	// cancelled_at:
+	//   type: integer
+	//   format: unixtime
+	CancelledAt int `json:"cancelled_at,omitempty"`
+
+	// request_counts:
+	//   type: BatchRequestCounts
+	RequestCounts BatchRequestCounts `json:"request_counts,omitempty"`
+
+	// metadata:
+	//   type: object
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Terminal reports whether the batch reached a state it will never leave.
+func (b *Batch) Terminal() bool {
+	switch b.Status {
+	case BatchStatusFailed, BatchStatusCompleted, BatchStatusExpired, BatchStatusCancelled:
+		return true
+	}
+	return false
+}
+
+type BatchRequestCounts struct {
+	// total:
+	//   type: integer
+	Total int `json:"total,omitempty"`
+
+	// completed:
+	//   type: integer
+	Completed int `json:"completed,omitempty"`
+
+	// failed:
+	//   type: integer
+	Failed int `json:"failed,omitempty"`
+}
+
+type BatchErrors struct {
+	// object:
+	//   type: string
+	Object string `json:"object,omitempty"`
+
+	// data:
+	//   type: []BatchError
+	Data []BatchError `json:"data,omitempty"`
+}
+
+type BatchError struct {
+	// code:
+	//   type: string
+	Code string `json:"code,omitempty"`
+
+	// message:
+	//   type: string
+	Message string `json:"message,omitempty"`
+
+	// param:
+	//   type: string
+	Param string `json:"param,omitempty"`
+
+	// line:
+	//   description: The line number of the input file where the error occurred.
+	//   type: integer
+	Line int `json:"line,omitempty"`
+}
+
+// BatchInputLine is a single line of a batch input file.
+type BatchInputLine[T any] struct {
+	// custom_id:
+	//   description: A developer-provided ID used to match outputs to inputs. Must be unique within the batch.
+	//   type: string
+	CustomID string `json:"custom_id"`
+
+	// method:
+	//   type: string
+	//   enum:
+	//     - POST
+	Method string `json:"method"`
+
+	// url:
+	//   type: string
+	URL string `json:"url"`
+
+	// body:
+	//   type: T
+	Body T `json:"body"`
+}
+
+// BatchOutputLine is a single line of a batch output or error file.
+type BatchOutputLine struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// custom_id:
+	//   type: string
+	CustomID string `json:"custom_id,omitempty"`
+
+	// response:
+	//   type: BatchOutputResponse
+	Response *BatchOutputResponse `json:"response,omitempty"`
+
+	// error:
+	//   type: Error
+	Error *Error `json:"error,omitempty"`
+}
+
+type BatchOutputResponse struct {
+	// status_code:
+	//   type: integer
+	StatusCode int `json:"status_code,omitempty"`
+
+	// request_id:
+	//   type: string
+	RequestID string `json:"request_id,omitempty"`
+
+	// body:
+	//   description: The response body of the request, a ChatResponse on success or an ErrorResponse otherwise.
+	//   type: object
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// ChatBatchResult maps the outcome of a chat completion batch back to the custom IDs of its requests.
+type ChatBatchResult struct {
+	Batch     *Batch
+	Responses map[string]ChatResponse
+	Errors    map[string]*Error
+}
+
+func (a *AzureOpenAI) CreateBatch(ctx context.Context, request BatchRequest) (*Batch, error) {
+	if request.CompletionWindow == "" {
+		request.CompletionWindow = "24h"
+	}
+	endpoint := fmt.Sprintf("%s/batches?api-version=%s", a.resourceEndpoint(), a.apiVersion)
+	return callPost[BatchRequest, Batch](ctx, a, OperationCreateBatch, endpoint, request)
+}
+
+func (a *AzureOpenAI) GetBatch(ctx context.Context, batchID string) (*Batch, error) {
+	endpoint := fmt.Sprintf("%s/batches/%s?api-version=%s", a.resourceEndpoint(), url.PathEscape(batchID), a.apiVersion)
+	return callGet[Batch](ctx, a, OperationGetBatch, endpoint)
+}
+
+func (a *AzureOpenAI) ListBatches(ctx context.Context, options ListOptions) (*ListResponse[Batch], error) {
+	endpoint := fmt.Sprintf("%s/batches?%s", a.resourceEndpoint(), options.values(a.apiVersion).Encode())
+	return callGet[ListResponse[Batch]](ctx, a, OperationListBatches, endpoint)
+}
+
+func (a *AzureOpenAI) CancelBatch(ctx context.Context, batchID string) (*Batch, error) {
+	endpoint := fmt.Sprintf("%s/batches/%s/cancel?api-version=%s", a.resourceEndpoint(), url.PathEscape(batchID), a.apiVersion)
+	return callPost[struct{}, Batch](ctx, a, OperationCancelBatch, endpoint, struct{}{})
+}
+
+// WaitBatch polls the batch with backoff until it reaches a terminal state.
+func (a *AzureOpenAI) WaitBatch(ctx context.Context, batchID string, options PollOptions) (*Batch, error) {
+	return poll[Batch](ctx, options, func(ctx context.Context) (*Batch, error) {
+		return a.GetBatch(ctx, batchID)
+	}, (*Batch).Terminal)
+}
+
+// MarshalChatBatchInput builds a JSONL batch input file from chat requests keyed by custom ID.
+// Lines are ordered by custom ID, and requests without a model are sent to the client's deployment.
+func (a *AzureOpenAI) MarshalChatBatchInput(requests map[string]ChatRequest) ([]byte, error) {
+	customIDs := make([]string, 0, len(requests))
+	for customID := range requests {
+		if customID == "" {
+			return nil, fmt.Errorf("custom_id must not be empty")
+		}
+		customIDs = append(customIDs, customID)
+	}
+	sort.Strings(customIDs)
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, customID := range customIDs {
+		request := requests[customID]
+		if request.Stream {
+			return nil, fmt.Errorf("streaming is not supported in batches: custom_id %q", customID)
+		}
+		reasoning := a.isReasoningRequest(request)
+		if request.Model == "" {
+			request.Model = a.deploymentName
+		}
+		if reasoning {
+			var err error
+			if request, err = prepareReasoningRequest(request, a.apiVersion); err != nil {
+				return nil, fmt.Errorf("custom_id %q: %w", customID, err)
+			}
+		}
+
+		line := BatchInputLine[ChatRequest]{
+			CustomID: customID,
+			Method:   "POST",
+			URL:      "/chat/completions",
+			Body:     request,
+		}
+		if err := encoder.Encode(line); err != nil {
+			return nil, err
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+// RunChatBatch uploads requests as a batch input file, creates a batch, waits until it terminates and
+// collects its results. Only a batch in the `failed` state is reported as an error; an expired or cancelled
+// batch returns whatever results were produced before it stopped.
+func (a *AzureOpenAI) RunChatBatch(ctx context.Context, requests map[string]ChatRequest, options PollOptions) (*ChatBatchResult, error) {
+	input, err := a.MarshalChatBatchInput(requests)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := a.UploadFile(ctx, "batch.jsonl", "batch", bytes.NewReader(input))
+	if err != nil {
+		return nil, err
+	}
+
+	batch, err := a.CreateBatch(ctx, BatchRequest{
+		InputFileID: file.ID,
+		Endpoint:    "/chat/completions",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	batch, err = a.WaitBatch(ctx, batch.ID, options)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Status == BatchStatusFailed {
+		return &ChatBatchResult{Batch: batch}, batchFailedError(batch)
+	}
+	return a.GetChatBatchResult(ctx, batch)
+}
+
+// GetChatBatchResult downloads the output and error files of a chat completion batch.
+func (a *AzureOpenAI) GetChatBatchResult(ctx context.Context, batch *Batch) (*ChatBatchResult, error) {
+	result := &ChatBatchResult{
+		Batch:     batch,
+		Responses: map[string]ChatResponse{},
+		Errors:    map[string]*Error{},
+	}
+
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		content, err := a.GetFileContent(ctx, fileID)
+		if err != nil {
+			return nil, err
+		}
+		if err := result.collect(content); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (r *ChatBatchResult) collect(content []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		m := strings.TrimSpace(scanner.Text())
+		if m == "" {
+			continue
+		}
+
+		var line BatchOutputLine
+		if err := json.Unmarshal([]byte(m), &line); err != nil {
+			return err
+		}
+
+		switch {
+		case line.Error != nil:
+			r.Errors[line.CustomID] = line.Error
+		case line.Response == nil:
+			r.Errors[line.CustomID] = &Error{Message: "batch output line has neither response nor error"}
+		case line.Response.StatusCode != 200:
+			var errorResponse ErrorResponse
+			if err := json.Unmarshal(line.Response.Body, &errorResponse); err != nil {
+				return err
+			}
+			r.Errors[line.CustomID] = &errorResponse.Error
+		default:
+			var response ChatResponse
+			if err := json.Unmarshal(line.Response.Body, &response); err != nil {
+				return err
+			}
+			r.Responses[line.CustomID] = response
+		}
+	}
+	return scanner.Err()
+}
+
+func batchFailedError(batch *Batch) error {
+	if batch.Errors == nil || len(batch.Errors.Data) == 0 {
+		return fmt.Errorf("batch %s failed", batch.ID)
+	}
+	messages := make([]string, 0, len(batch.Errors.Data))
+	for _, e := range batch.Errors.Data {
+		messages = append(messages, fmt.Sprintf("line %d: %s: %s", e.Line, e.Code, e.Message))
+	}
+	return fmt.Errorf("batch %s failed: %s", batch.ID, strings.Join(messages, "; "))
+}
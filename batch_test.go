@@ -0,0 +1,180 @@
+package aoai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBatchService emulates the files and batches endpoints of a resource.
+type fakeBatchService struct {
+	mu     sync.Mutex
+	input  []byte
+	polls  int
+	status string
+}
+
+func (s *fakeBatchService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/openai/files":
+		file, _, err := r.FormFile("file")
+		if err != nil || r.FormValue("purpose") != "batch" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"code":"invalid","message":"bad upload"}}`))
+			return
+		}
+		s.input, _ = io.ReadAll(file)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"file-input","object":"file","purpose":"batch","status":"processed"}`))
+
+	case r.Method == "POST" && r.URL.Path == "/openai/batches":
+		var request BatchRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		_ = json.NewEncoder(w).Encode(Batch{ID: "batch-1", InputFileID: request.InputFileID, Status: BatchStatusValidating})
+
+	case r.Method == "GET" && r.URL.Path == "/openai/batches/batch-1":
+		s.polls++
+		batch := Batch{ID: "batch-1", Status: BatchStatusInProgress}
+		if s.polls >= 3 {
+			batch.Status = s.status
+			if s.status == BatchStatusCompleted {
+				batch.OutputFileID = "file-output"
+				batch.ErrorFileID = "file-error"
+			} else {
+				batch.Errors = &BatchErrors{Data: []BatchError{{Code: "invalid_request", Message: "bad line", Line: 2}}}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(batch)
+
+	case r.Method == "GET" && r.URL.Path == "/openai/files/file-output/content":
+		scanner := bufio.NewScanner(bytes.NewReader(s.input))
+		for scanner.Scan() {
+			var line BatchInputLine[ChatRequest]
+			_ = json.Unmarshal(scanner.Bytes(), &line)
+			if line.CustomID == "bad" {
+				continue
+			}
+			body := fmt.Sprintf(`{"id":"chatcmpl","model":%q,"choices":[{"message":{"role":"assistant","content":"echo %s"}}]}`,
+				line.Body.Model, line.Body.Messages[0].Content)
+			_, _ = fmt.Fprintf(w, `{"id":"req-%s","custom_id":%q,"response":{"status_code":200,"body":%s}}`+"\n", line.CustomID, line.CustomID, body)
+		}
+
+	case r.Method == "GET" && r.URL.Path == "/openai/files/file-error/content":
+		_, _ = w.Write([]byte(`{"id":"req-bad","custom_id":"bad","response":{"status_code":400,"body":{"error":{"code":"content_filter","message":"filtered"}}}}` + "\n"))
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"code":"404","message":"not found"}}`))
+	}
+}
+
+func TestAzureOpenAI_MarshalChatBatchInput(t *testing.T) {
+	a := &AzureOpenAI{deploymentName: "gpt-4o-batch"}
+
+	tests := []struct {
+		name     string
+		requests map[string]ChatRequest
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "validCase",
+			requests: map[string]ChatRequest{
+				"b": {Messages: []ChatMessage{{Role: "user", Content: "second"}}},
+				"a": {Messages: []ChatMessage{{Role: "user", Content: "first"}}, Model: "other"},
+			},
+			want: `{"custom_id":"a","method":"POST","url":"/chat/completions","body":{"messages":[{"role":"user","content":"first"}],"model":"other"}}` + "\n" +
+				`{"custom_id":"b","method":"POST","url":"/chat/completions","body":{"messages":[{"role":"user","content":"second"}],"model":"gpt-4o-batch"}}` + "\n",
+		},
+		{
+			name:     "emptyCustomID",
+			requests: map[string]ChatRequest{"": {}},
+			wantErr:  true,
+		},
+		{
+			name:     "streamingRequest",
+			requests: map[string]ChatRequest{"a": {Stream: true}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.MarshalChatBatchInput(tt.requests)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MarshalChatBatchInput() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("MarshalChatBatchInput() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAzureOpenAI_RunChatBatch(t *testing.T) {
+	options := PollOptions{Interval: time.Millisecond, MaxInterval: 2 * time.Millisecond, Multiplier: 2}
+	requests := map[string]ChatRequest{
+		"task-0": {Messages: []ChatMessage{{Role: "user", Content: "hello"}}},
+		"task-1": {Messages: []ChatMessage{{Role: "user", Content: "world"}}},
+		"bad":    {Messages: []ChatMessage{{Role: "user", Content: "filtered"}}},
+	}
+
+	t.Run("completed", func(t *testing.T) {
+		service := &fakeBatchService{status: BatchStatusCompleted}
+		a := newTestAzureOpenAI(t, service)
+
+		got, err := a.RunChatBatch(context.Background(), requests, options)
+		if err != nil {
+			t.Fatalf("RunChatBatch() error = %v", err)
+		}
+		if got.Batch.Status != BatchStatusCompleted || service.polls != 3 {
+			t.Errorf("RunChatBatch() status = %s after %d polls", got.Batch.Status, service.polls)
+		}
+		if len(got.Responses) != 2 {
+			t.Fatalf("RunChatBatch() responses = %v", got.Responses)
+		}
+		if content := got.Responses["task-1"].Choices[0].Message.Content; content != "echo world" {
+			t.Errorf("RunChatBatch() task-1 = %s", content)
+		}
+		if model := got.Responses["task-0"].Model; model != a.deploymentName {
+			t.Errorf("RunChatBatch() model = %s, want %s", model, a.deploymentName)
+		}
+		if e, ok := got.Errors["bad"]; !ok || e.Code != "content_filter" {
+			t.Errorf("RunChatBatch() errors = %v", got.Errors)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		a := newTestAzureOpenAI(t, &fakeBatchService{status: BatchStatusFailed})
+
+		got, err := a.RunChatBatch(context.Background(), requests, options)
+		if err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Fatalf("RunChatBatch() error = %v", err)
+		}
+		if got == nil || got.Batch.Status != BatchStatusFailed {
+			t.Errorf("RunChatBatch() = %v", got)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		a := newTestAzureOpenAI(t, &fakeBatchService{status: BatchStatusInProgress})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := a.RunChatBatch(ctx, requests, options); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("RunChatBatch() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
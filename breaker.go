@@ -0,0 +1,166 @@
+package aoai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen fails requests immediately until the probe interval has passed.
+	CircuitOpen
+
+	// CircuitHalfOpen lets a single probe through, which closes the circuit on success and opens it again on
+	// failure.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// ErrCircuitOpen is returned for requests that a CircuitBreaker does not let through.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker fails requests to an endpoint immediately after consecutive failures, instead of letting every
+// request wait for a degraded region to time out. 5xx responses, transport errors and exceeded deadlines are
+// failures; other responses, including 429, are successes. It is safe for concurrent use.
+type CircuitBreaker struct {
+	failureThreshold int
+	probeInterval    time.Duration
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+// NewCircuitBreaker returns a CircuitBreaker that opens after failureThreshold consecutive failures and probes the
+// endpoint again every probeInterval while open.
+func NewCircuitBreaker(failureThreshold int, probeInterval time.Duration) *CircuitBreaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	return &CircuitBreaker{failureThreshold: failureThreshold, probeInterval: probeInterval, now: time.Now}
+}
+
+// WithCircuitBreaker guards completion, chat and embedding requests with breaker. A Pool skips clients whose
+// circuit is open.
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(a *AzureOpenAI) {
+		a.breaker = breaker
+	}
+}
+
+// CircuitState returns the state of the circuit breaker of the client, or CircuitClosed without one.
+func (a *AzureOpenAI) CircuitState() CircuitState {
+	if a.breaker == nil {
+		return CircuitClosed
+	}
+	return a.breaker.State()
+}
+
+// State returns the current state. An open circuit whose probe interval has passed is reported as half-open.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current()
+}
+
+func (b *CircuitBreaker) current() CircuitState {
+	if b.state == CircuitOpen && !b.now().Before(b.openedAt.Add(b.probeInterval)) {
+		b.state = CircuitHalfOpen
+	}
+	return b.state
+}
+
+// allow reports whether a request may be sent, and whether it is the probe of a half-open circuit.
+func (b *CircuitBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.current() {
+	case CircuitOpen:
+		return false, ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.probing {
+			return false, ErrCircuitOpen
+		}
+		b.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+type circuitResult int
+
+const (
+	circuitSuccess circuitResult = iota
+	circuitFailure
+	circuitIgnored
+)
+
+// record counts the result of a request let through by allow. While the circuit is not closed, only the probe
+// changes its state; requests sent before it opened are ignored.
+func (b *CircuitBreaker) record(probe bool, result circuitResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	} else if b.state != CircuitClosed {
+		return
+	}
+
+	switch result {
+	case circuitSuccess:
+		b.state = CircuitClosed
+		b.failures = 0
+	case circuitFailure:
+		b.failures++
+		if probe || b.failures >= b.failureThreshold {
+			b.state = CircuitOpen
+			b.openedAt = b.now()
+		}
+	}
+}
+
+type breakerTransport struct {
+	breaker *CircuitBreaker
+	next    http.RoundTripper
+}
+
+func (t breakerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	probe, err := t.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	response, err := t.next.RoundTrip(request)
+	switch {
+	case err == nil && response.StatusCode >= 500:
+		t.breaker.record(probe, circuitFailure)
+	case err == nil:
+		t.breaker.record(probe, circuitSuccess)
+	case errors.Is(request.Context().Err(), context.Canceled):
+		t.breaker.record(probe, circuitIgnored)
+	default:
+		t.breaker.record(probe, circuitFailure)
+	}
+	return response, err
+}
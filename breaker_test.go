@@ -0,0 +1,133 @@
+package aoai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := NewCircuitBreaker(2, time.Minute)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	steps := []struct {
+		name   string
+		result circuitResult
+		want   CircuitState
+	}{
+		{"first failure", circuitFailure, CircuitClosed},
+		{"success resets the count", circuitSuccess, CircuitClosed},
+		{"failure", circuitFailure, CircuitClosed},
+		{"threshold reached", circuitFailure, CircuitOpen},
+	}
+	for _, step := range steps {
+		probe, err := b.allow()
+		if err != nil {
+			t.Fatalf("%s: allow() error = %v", step.name, err)
+		}
+		b.record(probe, step.result)
+		if got := b.State(); got != step.want {
+			t.Errorf("%s: State() = %v, want %v", step.name, got, step.want)
+		}
+	}
+
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("allow() error = %v, want ErrCircuitOpen", err)
+	}
+
+	// a failed probe opens the circuit again, and a request sent before the circuit opened does not interfere
+	now = now.Add(time.Minute)
+	if got := b.State(); got != CircuitHalfOpen {
+		t.Fatalf("State() = %v, want half-open after the probe interval", got)
+	}
+	probe, err := b.allow()
+	if err != nil || !probe {
+		t.Fatalf("allow() = %v, %v, want the probe to pass", probe, err)
+	}
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("allow() error = %v, want a single probe", err)
+	}
+	b.record(false, circuitSuccess)
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) || b.State() != CircuitHalfOpen {
+		t.Errorf("allow() error = %v in state %v, want a stale success to leave the probe running", err, b.State())
+	}
+	b.record(probe, circuitFailure)
+	if got := b.State(); got != CircuitOpen {
+		t.Errorf("State() = %v, want open after a failed probe", got)
+	}
+
+	// an ignored probe frees the probe slot, a successful one closes the circuit
+	now = now.Add(time.Minute)
+	probe, _ = b.allow()
+	b.record(probe, circuitIgnored)
+	probe, err = b.allow()
+	if err != nil || !probe {
+		t.Fatalf("allow() = %v, %v, want another probe", probe, err)
+	}
+	b.record(probe, circuitSuccess)
+	if got := b.State(); got != CircuitClosed {
+		t.Errorf("State() = %v, want closed after a successful probe", got)
+	}
+}
+
+func TestWithCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   CircuitState
+	}{
+		{"server error", http.StatusBadGateway, CircuitOpen},
+		{"too many requests", http.StatusTooManyRequests, CircuitClosed},
+		{"bad request", http.StatusBadRequest, CircuitClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			a := countingBackend(t, &calls, tt.status, nil, `{"error":{"code":"error"}}`)
+			WithCircuitBreaker(NewCircuitBreaker(1, time.Minute))(a)
+
+			if _, err := a.ChatCompletion(context.Background(), poolChatRequest); err == nil {
+				t.Fatal("ChatCompletion() error = nil")
+			}
+			if got := a.CircuitState(); got != tt.want {
+				t.Errorf("CircuitState() = %v, want %v", got, tt.want)
+			}
+			_, err := a.ChatCompletion(context.Background(), poolChatRequest)
+			if open := errors.Is(err, ErrCircuitOpen); open != (tt.want == CircuitOpen) {
+				t.Errorf("ChatCompletion() error = %v", err)
+			}
+			if open := calls == 1; open != (tt.want == CircuitOpen) {
+				t.Errorf("server received %d requests", calls)
+			}
+		})
+	}
+
+	if got := (&AzureOpenAI{}).CircuitState(); got != CircuitClosed {
+		t.Errorf("CircuitState() without a breaker = %v", got)
+	}
+}
+
+func TestPool_CircuitBreaker(t *testing.T) {
+	var broken, healthy int
+	brokenClient := countingBackend(t, &broken, http.StatusOK, nil, okChatBody)
+	breaker := NewCircuitBreaker(1, time.Minute)
+	breaker.record(false, circuitFailure)
+	WithCircuitBreaker(breaker)(brokenClient)
+
+	p := NewPool(PriorityStrategy,
+		Backend{Name: "broken", Client: brokenClient},
+		Backend{Name: "healthy", Client: countingBackend(t, &healthy, http.StatusOK, nil, okChatBody), Priority: 1},
+	)
+	if got := p.Available(); len(got) != 1 || got[0] != "healthy" {
+		t.Errorf("Available() = %v", got)
+	}
+	if _, err := p.ChatCompletion(context.Background(), poolChatRequest); err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if broken != 0 || healthy != 1 {
+		t.Errorf("requests: broken %d, healthy %d", broken, healthy)
+	}
+}
@@ -0,0 +1,108 @@
+package aoai
+
+import "sort"
+
+// ChatStreamAccumulator merges the chunks of a streamed chat completion into a single response.
+type ChatStreamAccumulator struct {
+	response ChatResponse
+	choices  map[int]*ChatChoice
+	usage    *Usage
+}
+
+// Add merges chunk into the accumulated response. Chunks without choices only contribute their usage.
+func (s *ChatStreamAccumulator) Add(chunk ChatResponse) {
+	if s.response.ID == "" {
+		s.response.ID = chunk.ID
+		s.response.Object = "chat.completion"
+		s.response.Created = chunk.Created
+		s.response.Model = chunk.Model
+	}
+	if chunk.SystemFingerprint != "" {
+		s.response.SystemFingerprint = chunk.SystemFingerprint
+	}
+	if chunk.Usage != (Usage{}) {
+		usage := chunk.Usage
+		s.usage = &usage
+	}
+
+	if s.choices == nil {
+		s.choices = map[int]*ChatChoice{}
+	}
+	for _, delta := range chunk.Choices {
+		choice, ok := s.choices[delta.Index]
+		if !ok {
+			choice = &ChatChoice{Index: delta.Index}
+			s.choices[delta.Index] = choice
+		}
+		if delta.Delta.Role != "" {
+			choice.Message.Role = delta.Delta.Role
+		}
+		if delta.Delta.Name != "" {
+			choice.Message.Name = delta.Delta.Name
+		}
+		choice.Message.Content += delta.Delta.Content
+		for _, call := range delta.Delta.ToolCalls {
+			choice.Message.ToolCalls = appendToolCall(choice.Message.ToolCalls, call)
+		}
+		if delta.FinishReason != "" {
+			choice.FinishReason = delta.FinishReason
+		}
+		if delta.Logprobs != nil {
+			if choice.Logprobs == nil {
+				choice.Logprobs = &ChatLogprobs{}
+			}
+			choice.Logprobs.Append(delta.Logprobs)
+		}
+	}
+}
+
+// appendToolCall merges a streamed tool call delta into calls. A delta without index continues the last call
+// unless it starts a new one with an id.
+func appendToolCall(calls []ToolCall, delta ToolCall) []ToolCall {
+	i := len(calls)
+	if delta.Index != nil {
+		i = *delta.Index
+	} else if delta.ID == "" && i > 0 {
+		i--
+	}
+	for len(calls) <= i {
+		calls = append(calls, ToolCall{})
+	}
+
+	call := &calls[i]
+	if delta.ID != "" {
+		call.ID = delta.ID
+	}
+	if delta.Type != "" {
+		call.Type = delta.Type
+	}
+	if delta.Function.Name != "" {
+		call.Function.Name = delta.Function.Name
+	}
+	call.Function.Arguments += delta.Function.Arguments
+	return calls
+}
+
+// Usage returns the usage of the stream, or nil if the service did not report it.
+func (s *ChatStreamAccumulator) Usage() *Usage {
+	return s.usage
+}
+
+// Response returns the accumulated response with its choices ordered by index.
+func (s *ChatStreamAccumulator) Response() *ChatResponse {
+	response := s.response
+	indexes := make([]int, 0, len(s.choices))
+	for index := range s.choices {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	response.Choices = make([]ChatChoice, 0, len(indexes))
+	for _, index := range indexes {
+		response.Choices = append(response.Choices, *s.choices[index])
+	}
+	if s.usage != nil {
+		response.Usage = *s.usage
+	}
+	return &response
+}
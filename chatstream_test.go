@@ -0,0 +1,83 @@
+package aoai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+const recordedChatStream = `data: {"id":"","object":"","created":0,"model":"","prompt_filter_results":[{"prompt_index":0}],"choices":[]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","system_fingerprint":"fp_1","choices":[{"index":0,"delta":{"role":"assistant"}},{"index":1,"delta":{"role":"assistant"}}],"usage":null}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":1,"delta":{"content":"Yo"}},{"index":0,"delta":{"content":"Hel"},"logprobs":{"content":[{"token":"Hel","logprob":-0.1}]}}],"usage":null}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"content":"lo"},"logprobs":{"content":[{"token":"lo","logprob":-0.2}]},"finish_reason":"stop"},{"index":1,"delta":{},"finish_reason":"stop"}],"usage":null}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[],"usage":{"prompt_tokens":9,"completion_tokens":3,"total_tokens":12}}
+
+data: [DONE]
+
+`
+
+func TestAzureOpenAI_ChatCompletionStreamAccumulate(t *testing.T) {
+	var sent ChatRequest
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(recordedChatStream))
+	}))
+	request := ChatRequest{
+		Messages:      []ChatMessage{{Role: RoleUser, Content: "Hi"}},
+		N:             2,
+		Stream:        true,
+		StreamOptions: &StreamOptions{IncludeUsage: true},
+	}
+
+	chunks := 0
+	response, err := a.ChatCompletionStreamAccumulate(context.Background(), request, func(chunk ChatResponse) error {
+		_ = chunk.Choices[0]
+		chunks++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStreamAccumulate() error = %v", err)
+	}
+	if sent.StreamOptions == nil || !sent.StreamOptions.IncludeUsage {
+		t.Errorf("stream_options were not sent: %+v", sent)
+	}
+	if chunks != 3 {
+		t.Errorf("consumer received %d chunks, want 3", chunks)
+	}
+	if response.ID != "chatcmpl-1" || response.SystemFingerprint != "fp_1" || response.Usage.TotalTokens != 12 {
+		t.Errorf("response = %+v", response)
+	}
+	if len(response.Choices) != 2 || response.Choices[0].Message.Content != "Hello" || response.Choices[1].Message.Content != "Yo" {
+		t.Fatalf("choices = %+v", response.Choices)
+	}
+	if response.Choices[0].Message.Role != RoleAssistant || response.Choices[0].FinishReason != "stop" || len(response.Choices[0].Logprobs.Content) != 2 {
+		t.Errorf("choice = %+v", response.Choices[0])
+	}
+
+	chunks = 0
+	err = a.ChatCompletionStream(context.Background(), request, func(chunk ChatResponse) error {
+		_ = chunk.Choices[0]
+		chunks++
+		return nil
+	})
+	if err != nil || chunks != 3 {
+		t.Errorf("ChatCompletionStream() error = %v, chunks = %d", err, chunks)
+	}
+}
+
+func TestChatStreamAccumulator_withoutUsage(t *testing.T) {
+	accumulator := &ChatStreamAccumulator{}
+	accumulator.Add(ChatResponse{ID: "1", Choices: []ChatChoice{{Delta: ChatMessage{Role: RoleAssistant, Content: "Hi"}}}})
+	if accumulator.Usage() != nil {
+		t.Errorf("Usage() = %+v, want nil", accumulator.Usage())
+	}
+	if got := accumulator.Response().Choices[0].Message.Content; got != "Hi" {
+		t.Errorf("Response() content = %q", got)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"io"
 	"net/http"
 	"strings"
+
+	"github.com/gorilla/websocket"
 )
 
 type AzureOpenAI struct {
@@ -18,10 +20,38 @@ type AzureOpenAI struct {
 	apiVersion         string
 	useActiveDirectory bool
 	accessToken        string
+	dialer             *websocket.Dialer
+	validate           bool
+	featureCheck       FeatureCheck
+	featureWarn        func(err *UnsupportedFeatureError)
+	limiter            *RateLimiter
+	breaker            *CircuitBreaker
+	reasoningModel     *bool
+	middleware         []Middleware
+
+	deploymentsAPIVersion string
+}
+
+// Option configures an AzureOpenAI client.
+type Option func(*AzureOpenAI)
+
+// WithValidation makes the client call Validate on completion, chat and embedding requests and return its error
+// instead of sending an invalid request.
+func WithValidation() Option {
+	return func(a *AzureOpenAI) {
+		a.validate = true
+	}
 }
 
-func NewWithActiveDirectory(resourceName string, deploymentName string, apiVersion string, accessToken string) *AzureOpenAI {
-	return &AzureOpenAI{
+// WithHTTPClient replaces the default http.Client.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(a *AzureOpenAI) {
+		a.httpClient = httpClient
+	}
+}
+
+func NewWithActiveDirectory(resourceName string, deploymentName string, apiVersion string, accessToken string, options ...Option) *AzureOpenAI {
+	a := &AzureOpenAI{
 		httpClient:         &http.Client{},
 		resourceName:       resourceName,
 		deploymentName:     deploymentName,
@@ -29,10 +59,14 @@ func NewWithActiveDirectory(resourceName string, deploymentName string, apiVersi
 		useActiveDirectory: true,
 		accessToken:        accessToken,
 	}
+	for _, option := range options {
+		option(a)
+	}
+	return a
 }
 
-func New(resourceName string, deploymentName string, apiVersion string, accessToken string) *AzureOpenAI {
-	return &AzureOpenAI{
+func New(resourceName string, deploymentName string, apiVersion string, accessToken string, options ...Option) *AzureOpenAI {
+	a := &AzureOpenAI{
 		httpClient:         &http.Client{},
 		resourceName:       resourceName,
 		deploymentName:     deploymentName,
@@ -40,10 +74,18 @@ func New(resourceName string, deploymentName string, apiVersion string, accessTo
 		useActiveDirectory: false,
 		accessToken:        accessToken,
 	}
+	for _, option := range options {
+		option(a)
+	}
+	return a
+}
+
+func (a *AzureOpenAI) resourceEndpoint() string {
+	return fmt.Sprintf("https://%s.openai.azure.com/openai", a.resourceName)
 }
 
 func (a *AzureOpenAI) endpoint() string {
-	return fmt.Sprintf("https://%s.openai.azure.com/openai/deployments/%s", a.resourceName, a.deploymentName)
+	return fmt.Sprintf("%s/deployments/%s", a.resourceEndpoint(), a.deploymentName)
 }
 
 func (a *AzureOpenAI) header() http.Header {
@@ -58,47 +100,148 @@ func (a *AzureOpenAI) header() http.Header {
 	return header
 }
 
+// inferenceHTTPClient returns the http.Client of completion, chat and embedding requests, whose transport records
+// the request and response of call, reports response headers to the rate limiter and passes requests through the
+// circuit breaker.
+func (a *AzureOpenAI) inferenceHTTPClient(call *Call) *http.Client {
+	client := *a.httpClient
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if a.limiter != nil {
+		next = observingTransport{limiter: a.limiter, next: next}
+	}
+	if a.breaker != nil {
+		next = breakerTransport{breaker: a.breaker, next: next}
+	}
+	client.Transport = captureTransport{call: call, next: next}
+	return &client
+}
+
+// restHTTPClient returns the http.Client of the other REST requests, whose transport records the request and
+// response of call.
+func (a *AzureOpenAI) restHTTPClient(call *Call) *http.Client {
+	client := *a.httpClient
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client.Transport = captureTransport{call: call, next: next}
+	return &client
+}
+
+func (a *AzureOpenAI) validateRequest(request interface{ Validate() error }) error {
+	if !a.validate {
+		return nil
+	}
+	return request.Validate()
+}
+
 func (a *AzureOpenAI) Completion(ctx context.Context, request CompletionRequest) (*CompletionResponse, error) {
 	if request.Stream {
 		return nil, fmt.Errorf("streaming is not supported. Try `CompletionStream` instead")
 	}
+	if err := a.validateRequest(request); err != nil {
+		return nil, err
+	}
 
 	endpoint := fmt.Sprintf("%s/completions?api-version=%s", a.endpoint(), a.apiVersion)
-	return postJsonRequest[CompletionRequest, CompletionResponse](ctx, a.httpClient, endpoint, a.header(), request)
-
+	return callJson[CompletionRequest, CompletionResponse](ctx, a, OperationCompletion, endpoint, request, (*RateLimiter).estimateCompletion)
 }
 
 func (a *AzureOpenAI) Embedding(ctx context.Context, request EmbeddingRequest) (*EmbeddingResponse, error) {
+	if err := a.validateRequest(request); err != nil {
+		return nil, err
+	}
+
 	endpoint := fmt.Sprintf("%s/embeddings?api-version=%s", a.endpoint(), a.apiVersion)
-	return postJsonRequest[EmbeddingRequest, EmbeddingResponse](ctx, a.httpClient, endpoint, a.header(), request)
+	return callJson[EmbeddingRequest, EmbeddingResponse](ctx, a, OperationEmbedding, endpoint, request, (*RateLimiter).estimateEmbedding)
 }
 
 func (a *AzureOpenAI) ChatCompletion(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
 	if request.Stream {
 		return nil, fmt.Errorf("streaming is not supported. Try `ChatCompletionStream` instead")
 	}
+	if err := a.validateRequest(request); err != nil {
+		return nil, err
+	}
+	if a.isReasoningRequest(request) {
+		var err error
+		if request, err = prepareReasoningRequest(request, a.apiVersion); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.checkFeatures(request.Features()); err != nil {
+		return nil, err
+	}
 
 	endpoint := fmt.Sprintf("%s/chat/completions?api-version=%s", a.endpoint(), a.apiVersion)
-	return postJsonRequest[ChatRequest, ChatResponse](ctx, a.httpClient, endpoint, a.header(), request)
+	return callJson[ChatRequest, ChatResponse](ctx, a, OperationChatCompletion, endpoint, request, (*RateLimiter).estimateChat)
 }
 
 func (a *AzureOpenAI) CompletionStream(ctx context.Context, request CompletionRequest, consumer func(CompletionResponse) error) error {
 	if !request.Stream {
 		return fmt.Errorf("streaming is not enabled. Try `Completion` instead")
 	}
+	if err := a.validateRequest(request); err != nil {
+		return err
+	}
 
 	endpoint := fmt.Sprintf("%s/completions?api-version=%s", a.endpoint(), a.apiVersion)
-	return postJsonRequestStream[CompletionRequest, CompletionResponse](ctx, a.httpClient, endpoint, a.header(), request, consumer)
+	return callJsonStream[CompletionRequest, CompletionResponse](ctx, a, OperationCompletionStream, endpoint, request, (*RateLimiter).estimateCompletion, consumer)
 }
 
 // ChatCompletionStream
 
+// ChatCompletionStream passes the chunks of a chat completion to consumer. Chunks without choices, such as the
+// final usage chunk requested with `stream_options`, are not passed, so that consumers can index Choices[0].
+// Use ChatCompletionStreamAccumulate to receive the usage.
 func (a *AzureOpenAI) ChatCompletionStream(ctx context.Context, request ChatRequest, consumer func(ChatResponse) error) error {
+	return a.chatCompletionStream(ctx, request, func(chunk ChatResponse) error {
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		return consumer(chunk)
+	})
+}
+
+// ChatCompletionStreamAccumulate works like ChatCompletionStream and returns the accumulated response, including
+// the usage when `stream_options.include_usage` is set.
+func (a *AzureOpenAI) ChatCompletionStreamAccumulate(ctx context.Context, request ChatRequest, consumer func(ChatResponse) error) (*ChatResponse, error) {
+	accumulator := &ChatStreamAccumulator{}
+	err := a.chatCompletionStream(ctx, request, func(chunk ChatResponse) error {
+		accumulator.Add(chunk)
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		return consumer(chunk)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return accumulator.Response(), nil
+}
+
+func (a *AzureOpenAI) chatCompletionStream(ctx context.Context, request ChatRequest, consumer func(ChatResponse) error) error {
 	if !request.Stream {
 		return fmt.Errorf("streaming is not enabled. Try `ChatCompletion` instead")
 	}
+	if err := a.validateRequest(request); err != nil {
+		return err
+	}
+	if a.isReasoningRequest(request) {
+		var err error
+		if request, err = prepareReasoningRequest(request, a.apiVersion); err != nil {
+			return err
+		}
+	}
+	if err := a.checkFeatures(request.Features()); err != nil {
+		return err
+	}
+
 	endpoint := fmt.Sprintf("%s/chat/completions?api-version=%s", a.endpoint(), a.apiVersion)
-	return postJsonRequestStream[ChatRequest, ChatResponse](ctx, a.httpClient, endpoint, a.header(), request, consumer)
+	return callJsonStream[ChatRequest, ChatResponse](ctx, a, OperationChatCompletionStream, endpoint, request, (*RateLimiter).estimateChat, consumer)
 }
 
 func postJsonRequest[S, T any](ctx context.Context, httpClient *http.Client, endpoint string, header http.Header, request S) (*T, error) {
@@ -112,24 +255,31 @@ func postJsonRequest[S, T any](ctx context.Context, httpClient *http.Client, end
 		return nil, err
 	}
 	httpRequest.Header = header
+	return doJsonRequest[T](httpClient, httpRequest)
+}
 
-	httpResponse, err := httpClient.Do(httpRequest)
+func getJsonRequest[T any](ctx context.Context, httpClient *http.Client, endpoint string, header http.Header) (*T, error) {
+	httpRequest, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer httpResponse.Body.Close()
+	httpRequest.Header = header
+	return doJsonRequest[T](httpClient, httpRequest)
+}
 
-	responseBody, err := io.ReadAll(httpResponse.Body)
+func deleteJsonRequest[T any](ctx context.Context, httpClient *http.Client, endpoint string, header http.Header) (*T, error) {
+	httpRequest, err := http.NewRequestWithContext(ctx, "DELETE", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
+	httpRequest.Header = header
+	return doJsonRequest[T](httpClient, httpRequest)
+}
 
-	if httpResponse.StatusCode != 200 {
-		var errorResponse ErrorResponse
-		if err := json.Unmarshal(responseBody, &errorResponse); err != nil {
-			return nil, err
-		}
-		return nil, &errorResponse.Error
+func doJsonRequest[T any](httpClient *http.Client, httpRequest *http.Request) (*T, error) {
+	responseBody, err := doRequest(httpClient, httpRequest)
+	if err != nil {
+		return nil, err
 	}
 
 	var response T
@@ -139,38 +289,51 @@ func postJsonRequest[S, T any](ctx context.Context, httpClient *http.Client, end
 	return &response, nil
 }
 
-// postJsonRequestStream
-// https://learn.microsoft.com/en-us/azure/cognitive-services/openai/reference
-// Whether to stream back partial progress. If set, tokens will be sent as data-only server-sent events as they become
-// available, with the stream terminated by a `data: [DONE]` message.
-func postJsonRequestStream[S, T any](ctx context.Context, httpClient *http.Client, endpoint string, header http.Header, request S, consumer func(chunk T) error) error {
-	requestBody, err := json.Marshal(request)
+// doRequest sends httpRequest and returns the raw response body, or the decoded
+// API error when the service does not answer with a 2xx status.
+func doRequest(httpClient *http.Client, httpRequest *http.Request) ([]byte, error) {
+	httpResponse, err := httpClient.Do(httpRequest)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer httpResponse.Body.Close()
 
-	httpRequest, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(requestBody))
+	responseBody, err := io.ReadAll(httpResponse.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	httpRequest.Header = header
 
-	httpResponse, err := httpClient.Do(httpRequest)
-	if err != nil {
-		return err
+	if httpResponse.StatusCode < 200 || httpResponse.StatusCode >= 300 {
+		return nil, responseError(httpResponse, responseBody)
 	}
-	defer httpResponse.Body.Close()
+	return responseBody, nil
+}
 
-	if httpResponse.StatusCode != 200 {
-		responseBody, _ := io.ReadAll(httpResponse.Body)
-		var errorResponse ErrorResponse
-		if err := json.Unmarshal(responseBody, &errorResponse); err != nil {
-			return err
-		}
-		return &errorResponse.Error
+// responseError decodes the API error of an unsuccessful response. Bodies that are not an API error, e.g. from a
+// gateway, are reported with the status text.
+func responseError(httpResponse *http.Response, responseBody []byte) error {
+	var errorResponse ErrorResponse
+	if err := json.Unmarshal(responseBody, &errorResponse); err != nil || errorResponse.Error == (Error{}) {
+		errorResponse.Error = Error{Message: http.StatusText(httpResponse.StatusCode)}
 	}
+	e := &errorResponse.Error
+	e.StatusCode = httpResponse.StatusCode
+	e.RetryAfter, _ = parseRetryAfter(httpResponse.Header)
+	return e
+}
 
-	reader := bufio.NewReader(httpResponse.Body)
+// postJsonRequestStream
+// https://learn.microsoft.com/en-us/azure/cognitive-services/openai/reference
+// Whether to stream back partial progress. If set, tokens will be sent as data-only server-sent events as they become
+// available, with the stream terminated by a `data: [DONE]` message.
+func postJsonRequestStream[S, T any](ctx context.Context, httpClient *http.Client, endpoint string, header http.Header, request S, consumer func(chunk T) error) error {
+	body, err := openStream(ctx, httpClient, endpoint, header, request)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	reader := bufio.NewReader(body)
 
 	for {
 		select {
@@ -206,3 +369,97 @@ func postJsonRequestStream[S, T any](ctx context.Context, httpClient *http.Clien
 		}
 	}
 }
+
+// openStream sends request and returns the body of a successful streaming response.
+func openStream[S any](ctx context.Context, httpClient *http.Client, endpoint string, header http.Header, request S) (io.ReadCloser, error) {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header = header
+
+	httpResponse, err := httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpResponse.StatusCode != 200 {
+		defer httpResponse.Body.Close()
+		responseBody, _ := io.ReadAll(httpResponse.Body)
+		return nil, responseError(httpResponse, responseBody)
+	}
+	return httpResponse.Body, nil
+}
+
+// postEventStream
+// Unlike postJsonRequestStream, which decodes every `data:` line into the same type, postEventStream keeps the
+// `event:` field of each server-sent event so that consumers can decode the data by event type. Multiple `data:`
+// lines of one event are joined with '\n', and the stream ends at EOF or with a `data: [DONE]` message.
+func postEventStream[S any](ctx context.Context, httpClient *http.Client, endpoint string, header http.Header, request S, consumer func(event string, data []byte) error) error {
+	body, err := openStream(ctx, httpClient, endpoint, header, request)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	return readEventStream(ctx, body, consumer)
+}
+
+func readEventStream(ctx context.Context, body io.Reader, consumer func(event string, data []byte) error) error {
+	reader := bufio.NewReader(body)
+
+	var (
+		event string
+		data  []string
+	)
+	dispatch := func() error {
+		defer func() {
+			event = ""
+			data = data[:0]
+		}()
+		if len(data) == 0 {
+			return nil
+		}
+		return consumer(event, []byte(strings.Join(data, "\n")))
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		m, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		eof := err == io.EOF
+
+		m = strings.TrimRight(m, "\r\n")
+		switch {
+		case m == "":
+			// events are delimited by a blank line
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(m, ":"):
+			// comment line
+		case strings.HasPrefix(m, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(m, "event:"))
+		case strings.HasPrefix(m, "data:"):
+			value := strings.TrimPrefix(strings.TrimPrefix(m, "data:"), " ")
+			if value == "[DONE]" {
+				return nil
+			}
+			data = append(data, value)
+		}
+
+		if eof {
+			return dispatch()
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"reflect"
 	"testing"
@@ -488,3 +490,29 @@ func TestAzureOpenAI_ChatCompletionStream(t *testing.T) {
 		})
 	}
 }
+
+// rewriteTransport sends requests addressed to an Azure OpenAI resource to a local test server instead.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	request.URL.Scheme = t.target.Scheme
+	request.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(request)
+}
+
+func newTestAzureOpenAI(t *testing.T, handler http.Handler) *AzureOpenAI {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	return &AzureOpenAI{
+		httpClient:     &http.Client{Transport: &rewriteTransport{target: target}},
+		resourceName:   "example-aoai-02",
+		deploymentName: "gpt-35-turbo-0301",
+		apiVersion:     "2024-10-21",
+		accessToken:    "dummy",
+	}
+}
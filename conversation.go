@@ -0,0 +1,259 @@
+package aoai
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ChatStreamer is implemented by AzureOpenAI and by anything else that streams chat completions.
+type ChatStreamer interface {
+	ChatCompletionStreamAccumulate(ctx context.Context, request ChatRequest, consumer func(ChatResponse) error) (*ChatResponse, error)
+}
+
+// Conversation is a chat session whose messages grow with every reply. It is serialized to JSON by a
+// ConversationStore so that it can be resumed by its ID. A Conversation is not safe for concurrent use.
+type Conversation struct {
+	ID       string        `json:"id"`
+	Messages []ChatMessage `json:"messages"`
+
+	// Request holds the parameters of every chat completion, such as MaxTokens or Tools. Its messages are replaced
+	// by the messages of the conversation.
+	Request ChatRequest `json:"request"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewConversation returns an empty conversation. A random ID is generated when id is empty.
+func NewConversation(id string) *Conversation {
+	if id == "" {
+		id = newConversationID()
+	}
+	now := time.Now()
+	return &Conversation{ID: id, CreatedAt: now, UpdatedAt: now}
+}
+
+func newConversationID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (c *Conversation) add(message ChatMessage) {
+	c.Messages = append(c.Messages, message)
+	c.UpdatedAt = time.Now()
+}
+
+// AddSystem appends a system message.
+func (c *Conversation) AddSystem(content string) {
+	c.add(ChatMessage{Role: RoleSystem, Content: content})
+}
+
+// AddUser appends a user message.
+func (c *Conversation) AddUser(content string) {
+	c.add(ChatMessage{Role: RoleUser, Content: content})
+}
+
+// AddToolResult appends the result of the tool call toolCallID.
+func (c *Conversation) AddToolResult(toolCallID string, content string) {
+	c.add(ChatMessage{Role: RoleTool, Content: content, ToolCallID: toolCallID})
+}
+
+// Send requests a chat completion of the conversation and appends the first choice, including its tool calls.
+func (c *Conversation) Send(ctx context.Context, client ChatCompleter) (*ChatResponse, error) {
+	request := c.request()
+	request.Stream = false
+	response, err := client.ChatCompletion(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response, c.addReply(response)
+}
+
+// SendStream works like Send, passing every chunk of the reply to consumer as it arrives.
+func (c *Conversation) SendStream(ctx context.Context, client ChatStreamer, consumer func(ChatResponse) error) (*ChatResponse, error) {
+	request := c.request()
+	request.Stream = true
+	response, err := client.ChatCompletionStreamAccumulate(ctx, request, consumer)
+	if err != nil {
+		return nil, err
+	}
+	return response, c.addReply(response)
+}
+
+// Ask appends a user message and sends the conversation.
+func (c *Conversation) Ask(ctx context.Context, client ChatCompleter, content string) (*ChatResponse, error) {
+	c.AddUser(content)
+	return c.Send(ctx, client)
+}
+
+func (c *Conversation) request() ChatRequest {
+	request := c.Request
+	request.Messages = append([]ChatMessage{}, c.Messages...)
+	return request
+}
+
+func (c *Conversation) addReply(response *ChatResponse) error {
+	if len(response.Choices) == 0 {
+		return fmt.Errorf("chat completion returned no choices")
+	}
+	reply := response.Choices[0].Message
+	if reply.Role == "" {
+		reply.Role = RoleAssistant
+	}
+	c.add(reply)
+	return nil
+}
+
+// ErrConversationNotFound is returned by a ConversationStore for unknown IDs.
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// ConversationStore persists conversations by their ID. Implementations are safe for concurrent use.
+type ConversationStore interface {
+	Load(ctx context.Context, id string) (*Conversation, error)
+	Save(ctx context.Context, conversation *Conversation) error
+	Delete(ctx context.Context, id string) error
+}
+
+// MemoryConversationStore keeps conversations in memory, e.g. for tests or a single process.
+type MemoryConversationStore struct {
+	mu            sync.RWMutex
+	conversations map[string][]byte
+}
+
+// NewMemoryConversationStore returns an empty MemoryConversationStore.
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{conversations: map[string][]byte{}}
+}
+
+func (s *MemoryConversationStore) Load(_ context.Context, id string) (*Conversation, error) {
+	s.mu.RLock()
+	data, ok := s.conversations[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrConversationNotFound, id)
+	}
+
+	var conversation Conversation
+	if err := json.Unmarshal(data, &conversation); err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// Save stores a copy of conversation, so that later changes are not visible until it is saved again.
+func (s *MemoryConversationStore) Save(_ context.Context, conversation *Conversation) error {
+	data, err := json.Marshal(conversation)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conversations[conversation.ID] = data
+	return nil
+}
+
+func (s *MemoryConversationStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.conversations[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrConversationNotFound, id)
+	}
+	delete(s.conversations, id)
+	return nil
+}
+
+// FileConversationStore keeps every conversation in a JSON file of a directory, so that it survives restarts.
+type FileConversationStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewFileConversationStore returns a store that keeps conversations in dir, creating it if needed.
+func NewFileConversationStore(dir string) (*FileConversationStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileConversationStore{dir: dir}, nil
+}
+
+// path returns the file of id. IDs are escaped so that they cannot point outside the directory.
+func (s *FileConversationStore) path(id string) (string, error) {
+	if id == "" {
+		return "", fmt.Errorf("conversation id must not be empty")
+	}
+	return filepath.Join(s.dir, url.PathEscape(id)+".json"), nil
+}
+
+func (s *FileConversationStore) Load(_ context.Context, id string) (*Conversation, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrConversationNotFound, id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var conversation Conversation
+	if err := json.Unmarshal(data, &conversation); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &conversation, nil
+}
+
+// Save writes conversation to a temporary file and renames it, so that a crash never leaves a partial file.
+func (s *FileConversationStore) Save(_ context.Context, conversation *Conversation) error {
+	path, err := s.path(conversation.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(conversation)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	temp, err := os.CreateTemp(s.dir, ".conversation-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		_ = temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+func (s *FileConversationStore) Delete(_ context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrConversationNotFound, id)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
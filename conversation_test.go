@@ -0,0 +1,111 @@
+package aoai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+const recordedToolCallStream = `data: {"id":"chatcmpl-2","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"weather","arguments":""}}]}}]}
+
+data: {"id":"chatcmpl-2","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}
+
+data: {"id":"chatcmpl-2","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Tokyo\"}"}}]},"finish_reason":"tool_calls"}]}
+
+data: [DONE]
+
+`
+
+func TestConversation_Send(t *testing.T) {
+	completer := &fakeChatCompleter{content: "Hello"}
+	c := NewConversation("")
+	c.Request = ChatRequest{MaxTokens: 100}
+	c.AddSystem("Be brief.")
+
+	response, err := c.Ask(context.Background(), completer, "Hi")
+	if err != nil || response.Choices[0].Message.Content != "Hello" {
+		t.Fatalf("Ask() = %+v, %v", response, err)
+	}
+	if c.ID == "" || len(c.Messages) != 3 || !reflect.DeepEqual(c.Messages[2], ChatMessage{Role: RoleAssistant, Content: "Hello"}) {
+		t.Errorf("conversation = %+v", c)
+	}
+	if sent := completer.requests[0]; sent.MaxTokens != 100 || len(sent.Messages) != 2 {
+		t.Errorf("request = %+v", sent)
+	}
+	if c.Request.Messages != nil {
+		t.Errorf("Request.Messages = %+v", c.Request.Messages)
+	}
+}
+
+func TestConversation_SendStream(t *testing.T) {
+	var sent ChatRequest
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(recordedToolCallStream))
+	}))
+	c := NewConversation("c1")
+	c.AddUser("Weather in Tokyo?")
+
+	if _, err := c.SendStream(context.Background(), a, func(ChatResponse) error { return nil }); err != nil {
+		t.Fatalf("SendStream() error = %v", err)
+	}
+	if !sent.Stream {
+		t.Errorf("request was not streamed: %+v", sent)
+	}
+	want := ChatMessage{Role: RoleAssistant, ToolCalls: []ToolCall{
+		{ID: "call_1", Type: "function", Function: FunctionCall{Name: "weather", Arguments: `{"city":"Tokyo"}`}},
+	}}
+	if len(c.Messages) != 2 || !reflect.DeepEqual(c.Messages[1], want) {
+		t.Errorf("reply = %+v, want %+v", c.Messages[1:], want)
+	}
+
+	c.AddToolResult("call_1", "sunny")
+	if got := c.Messages[2]; got.Role != RoleTool || got.ToolCallID != "call_1" {
+		t.Errorf("tool result = %+v", got)
+	}
+}
+
+func TestConversationStore(t *testing.T) {
+	fileStore, err := NewFileConversationStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]ConversationStore{"memory": NewMemoryConversationStore(), "file": fileStore}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			c := NewConversation("../user/1")
+			c.Request = ChatRequest{Temperature: 0.5, ExtraBody: map[string]any{"prediction": "x"}}
+			c.AddUser("Hi")
+			c.add(ChatMessage{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_1", Type: "function", Function: FunctionCall{Name: "f"}}}})
+			if err := store.Save(ctx, c); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+
+			c.AddUser("unsaved")
+			loaded, err := store.Load(ctx, "../user/1")
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if len(loaded.Messages) != 2 || !reflect.DeepEqual(loaded.Messages[1], c.Messages[1]) ||
+				loaded.Request.Temperature != 0.5 || loaded.Request.ExtraBody["prediction"] == nil || !loaded.CreatedAt.Equal(c.CreatedAt) {
+				t.Errorf("Load() = %+v", loaded)
+			}
+
+			if err := store.Delete(ctx, "../user/1"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if _, err := store.Load(ctx, "../user/1"); !errors.Is(err, ErrConversationNotFound) {
+				t.Errorf("Load() error = %v", err)
+			}
+			if err := store.Delete(ctx, "../user/1"); !errors.Is(err, ErrConversationNotFound) {
+				t.Errorf("Delete() error = %v", err)
+			}
+		})
+	}
+}
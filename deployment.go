@@ -0,0 +1,233 @@
+package aoai
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+type Model struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - model
+	Object string `json:"object,omitempty"`
+
+	// created_at:
+	//   type: integer
+	//   format: unixtime
+	CreatedAt int `json:"created_at,omitempty"`
+
+	// status:
+	//   type: string
+	Status string `json:"status,omitempty"`
+
+	// model:
+	//   description: The base model ID if this is a fine-tuned model.
+	//   type: string
+	Model string `json:"model,omitempty"`
+
+	// fine_tune:
+	//   description: The fine-tune job ID if this is a fine-tuned model.
+	//   type: string
+	FineTune string `json:"fine_tune,omitempty"`
+
+	// capabilities:
+	//   type: ModelCapabilities
+	Capabilities ModelCapabilities `json:"capabilities,omitempty"`
+
+	// lifecycle_status:
+	//   type: string
+	//   enum:
+	//     - preview
+	//     - generally-available
+	LifecycleStatus string `json:"lifecycle_status,omitempty"`
+
+	// deprecation:
+	//   type: ModelDeprecation
+	Deprecation ModelDeprecation `json:"deprecation,omitempty"`
+}
+
+type ModelCapabilities struct {
+	// fine_tune:
+	//   type: boolean
+	FineTune bool `json:"fine_tune,omitempty"`
+
+	// inference:
+	//   type: boolean
+	Inference bool `json:"inference,omitempty"`
+
+	// completion:
+	//   type: boolean
+	Completion bool `json:"completion,omitempty"`
+
+	// chat_completion:
+	//   type: boolean
+	ChatCompletion bool `json:"chat_completion,omitempty"`
+
+	// embeddings:
+	//   type: boolean
+	Embeddings bool `json:"embeddings,omitempty"`
+}
+
+type ModelDeprecation struct {
+	// fine_tune:
+	//   description: The end date of fine tune support of this model. Will be null for fine tune models.
+	//   type: integer
+	//   format: unixtime
+	FineTune int `json:"fine_tune,omitempty"`
+
+	// inference:
+	//   description: The end date of inference support of this model.
+	//   type: integer
+	//   format: unixtime
+	Inference int `json:"inference,omitempty"`
+}
+
+// InferenceDeprecatedAt returns when inference support of the model ends, or the zero time if it is unknown.
+func (m *Model) InferenceDeprecatedAt() time.Time {
+	if m.Deprecation.Inference == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(m.Deprecation.Inference), 0)
+}
+
+type Deployment struct {
+	// id:
+	//   description: The deployment name.
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - deployment
+	Object string `json:"object,omitempty"`
+
+	// created_at:
+	//   type: integer
+	//   format: unixtime
+	CreatedAt int `json:"created_at,omitempty"`
+
+	// updated_at:
+	//   type: integer
+	//   format: unixtime
+	UpdatedAt int `json:"updated_at,omitempty"`
+
+	// model:
+	//   description: The ID of the deployed model.
+	//   type: string
+	Model string `json:"model,omitempty"`
+
+	// owner:
+	//   type: string
+	Owner string `json:"owner,omitempty"`
+
+	// status:
+	//   type: string
+	//   enum:
+	//     - notRunning
+	//     - running
+	//     - creating
+	//     - succeeded
+	//     - failed
+	//     - canceled
+	//     - deleted
+	Status string `json:"status,omitempty"`
+
+	// scale_settings:
+	//   type: DeploymentScaleSettings
+	ScaleSettings DeploymentScaleSettings `json:"scale_settings,omitempty"`
+}
+
+type DeploymentScaleSettings struct {
+	// scale_type:
+	//   type: string
+	//   enum:
+	//     - manual
+	//     - standard
+	ScaleType string `json:"scale_type,omitempty"`
+
+	// capacity:
+	//   type: integer
+	Capacity int `json:"capacity,omitempty"`
+}
+
+// DeploymentCheck is the result of CheckDeployment.
+type DeploymentCheck struct {
+	Deployment *Deployment
+	Model      *Model
+	Warnings   []string
+}
+
+func (a *AzureOpenAI) ListModels(ctx context.Context) (*ListResponse[Model], error) {
+	endpoint := fmt.Sprintf("%s/models?api-version=%s", a.resourceEndpoint(), a.apiVersion)
+	return callGet[ListResponse[Model]](ctx, a, OperationListModels, endpoint)
+}
+
+func (a *AzureOpenAI) GetModel(ctx context.Context, modelID string) (*Model, error) {
+	endpoint := fmt.Sprintf("%s/models/%s?api-version=%s", a.resourceEndpoint(), url.PathEscape(modelID), a.apiVersion)
+	return callGet[Model](ctx, a, OperationGetModel, endpoint)
+}
+
+// WithDeploymentsAPIVersion sets the api-version of ListDeployments, GetDeployment and CheckDeployment. The data
+// plane serves deployments only up to api-version `2022-12-01`, which is used by default whatever the api-version
+// of the client.
+func WithDeploymentsAPIVersion(apiVersion string) Option {
+	return func(a *AzureOpenAI) {
+		a.deploymentsAPIVersion = apiVersion
+	}
+}
+
+func (a *AzureOpenAI) deploymentsEndpoint(path string) string {
+	apiVersion := a.deploymentsAPIVersion
+	if apiVersion == "" {
+		apiVersion = APIVersion20221201
+	}
+	return fmt.Sprintf("%s/deployments%s?api-version=%s", a.resourceEndpoint(), path, apiVersion)
+}
+
+// ListDeployments lists the deployments of the resource with the api-version set by WithDeploymentsAPIVersion.
+func (a *AzureOpenAI) ListDeployments(ctx context.Context) (*ListResponse[Deployment], error) {
+	return callGet[ListResponse[Deployment]](ctx, a, OperationListDeployments, a.deploymentsEndpoint(""))
+}
+
+// GetDeployment gets a deployment of the resource with the api-version set by WithDeploymentsAPIVersion.
+func (a *AzureOpenAI) GetDeployment(ctx context.Context, deploymentName string) (*Deployment, error) {
+	return callGet[Deployment](ctx, a, OperationGetDeployment, a.deploymentsEndpoint("/"+url.PathEscape(deploymentName)))
+}
+
+// CheckDeployment verifies that the configured deployment exists and is ready, and looks up its model.
+// Warnings are reported when the model is in preview or its inference support ends within warnWithin.
+func (a *AzureOpenAI) CheckDeployment(ctx context.Context, warnWithin time.Duration) (*DeploymentCheck, error) {
+	deployment, err := a.GetDeployment(ctx, a.deploymentName)
+	if err != nil {
+		return nil, err
+	}
+	if deployment.Status != "" && deployment.Status != "succeeded" && deployment.Status != "running" {
+		return nil, fmt.Errorf("deployment %s is not ready: status %s", a.deploymentName, deployment.Status)
+	}
+
+	model, err := a.GetModel(ctx, deployment.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &DeploymentCheck{Deployment: deployment, Model: model}
+	if model.LifecycleStatus == "preview" {
+		check.Warnings = append(check.Warnings, fmt.Sprintf("model %s is in preview", model.ID))
+	}
+	if deprecatedAt := model.InferenceDeprecatedAt(); !deprecatedAt.IsZero() {
+		if remaining := time.Until(deprecatedAt); remaining <= 0 {
+			check.Warnings = append(check.Warnings, fmt.Sprintf("inference support of model %s ended on %s", model.ID, deprecatedAt.UTC().Format(time.DateOnly)))
+		} else if remaining <= warnWithin {
+			check.Warnings = append(check.Warnings, fmt.Sprintf("inference support of model %s ends on %s", model.ID, deprecatedAt.UTC().Format(time.DateOnly)))
+		}
+	}
+	return check, nil
+}
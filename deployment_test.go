@@ -0,0 +1,117 @@
+package aoai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAzureOpenAI_CheckDeployment(t *testing.T) {
+	soon := time.Now().Add(10 * 24 * time.Hour).Unix()
+	past := time.Now().Add(-24 * time.Hour).Unix()
+	late := time.Now().Add(365 * 24 * time.Hour).Unix()
+
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// deployments are served only up to 2022-12-01, models by current api-versions as well
+		if strings.HasPrefix(r.URL.Path, "/openai/deployments/") && r.URL.Query().Get("api-version") != APIVersion20221201 {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"404","message":"Resource not found"}}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/openai/deployments/gpt-35-turbo-0301":
+			_, _ = w.Write([]byte(`{"id":"gpt-35-turbo-0301","model":"gpt-35-turbo-0301","status":"succeeded"}`))
+		case "/openai/deployments/creating":
+			_, _ = w.Write([]byte(`{"id":"creating","model":"gpt-4","status":"creating"}`))
+		case "/openai/deployments/preview":
+			_, _ = w.Write([]byte(`{"id":"preview","model":"gpt-4o-preview","status":"succeeded"}`))
+		case "/openai/deployments/retired":
+			_, _ = w.Write([]byte(`{"id":"retired","model":"text-davinci-003","status":"succeeded"}`))
+		case "/openai/models/gpt-35-turbo-0301":
+			_, _ = fmt.Fprintf(w, `{"id":"gpt-35-turbo-0301","capabilities":{"chat_completion":true},"lifecycle_status":"generally-available","deprecation":{"inference":%d}}`, soon)
+		case "/openai/models/gpt-4o-preview":
+			_, _ = fmt.Fprintf(w, `{"id":"gpt-4o-preview","lifecycle_status":"preview","deprecation":{"inference":%d}}`, late)
+		case "/openai/models/text-davinci-003":
+			_, _ = fmt.Fprintf(w, `{"id":"text-davinci-003","deprecation":{"inference":%d}}`, past)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"DeploymentNotFound","message":"not found"}}`))
+		}
+	}))
+
+	tests := []struct {
+		name           string
+		deploymentName string
+		want           []string
+		wantErr        bool
+	}{
+		{
+			name:           "deprecationNear",
+			deploymentName: "gpt-35-turbo-0301",
+			want:           []string{"inference support of model gpt-35-turbo-0301 ends on"},
+		},
+		{
+			name:           "preview",
+			deploymentName: "preview",
+			want:           []string{"model gpt-4o-preview is in preview"},
+		},
+		{
+			name:           "deprecated",
+			deploymentName: "retired",
+			want:           []string{"inference support of model text-davinci-003 ended on"},
+		},
+		{
+			name:           "notReady",
+			deploymentName: "creating",
+			wantErr:        true,
+		},
+		{
+			name:           "notFound",
+			deploymentName: "missing",
+			wantErr:        true,
+		},
+	}
+	if a.apiVersion != APIVersion20241021 {
+		t.Fatalf("client api-version = %s, want a current one", a.apiVersion)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a.deploymentName = tt.deploymentName
+			got, err := a.CheckDeployment(context.Background(), 30*24*time.Hour)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckDeployment() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if len(got.Warnings) != len(tt.want) {
+				t.Fatalf("CheckDeployment() warnings = %v, want %v", got.Warnings, tt.want)
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(got.Warnings[i], want) {
+					t.Errorf("CheckDeployment() warning = %s, want %s", got.Warnings[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestWithDeploymentsAPIVersion(t *testing.T) {
+	var apiVersion string
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiVersion = r.URL.Query().Get("api-version")
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+
+	if _, err := a.ListDeployments(context.Background()); err != nil || apiVersion != APIVersion20221201 {
+		t.Errorf("ListDeployments() sent api-version %s, error = %v", apiVersion, err)
+	}
+	WithDeploymentsAPIVersion("2022-06-01-preview")(a)
+	if _, err := a.ListDeployments(context.Background()); err != nil || apiVersion != "2022-06-01-preview" {
+		t.Errorf("ListDeployments() sent api-version %s, error = %v", apiVersion, err)
+	}
+}
@@ -0,0 +1,222 @@
+package aoai
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// jsonFieldNamesCache maps a struct type to the set of its JSON field names.
+var jsonFieldNamesCache sync.Map
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	if names, ok := jsonFieldNamesCache.Load(t); ok {
+		return names.(map[string]bool)
+	}
+
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for embedded := range jsonFieldNames(field.Type) {
+				names[embedded] = true
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	jsonFieldNamesCache.Store(t, names)
+	return names
+}
+
+// unknownFields returns the keys of the JSON object data that do not belong to a field of t, or nil if there are none.
+func unknownFields(data []byte, t reflect.Type) (map[string]json.RawMessage, error) {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	known := jsonFieldNames(t)
+	for name := range fields {
+		if known[name] {
+			delete(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// extraBody collects the unknown keys of a request, so that decoded requests keep parameters this package does not model.
+func extraBody(data []byte, t reflect.Type) (map[string]any, error) {
+	fields, err := unknownFields(data, t)
+	if fields == nil || err != nil {
+		return nil, err
+	}
+	extra := make(map[string]any, len(fields))
+	for name, value := range fields {
+		extra[name] = value
+	}
+	return extra, nil
+}
+
+// mergeExtraBody adds the ExtraBody parameters of a request, or the ExtraFields of a response, to its JSON object.
+// Extra parameters take precedence over fields with the same name.
+func mergeExtraBody[V any](data []byte, extra map[string]V) ([]byte, error) {
+	if len(extra) == 0 {
+		return data, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range extra {
+		m, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = m
+	}
+	return json.Marshal(fields)
+}
+
+func (r ChatRequest) MarshalJSON() ([]byte, error) {
+	type alias ChatRequest
+	data, err := json.Marshal(alias(r))
+	if err != nil {
+		return nil, err
+	}
+	return mergeExtraBody(data, r.ExtraBody)
+}
+
+func (r *ChatRequest) UnmarshalJSON(data []byte) error {
+	type alias ChatRequest
+	if err := json.Unmarshal(data, (*alias)(r)); err != nil {
+		return err
+	}
+
+	var err error
+	r.ExtraBody, err = extraBody(data, reflect.TypeOf(*r))
+	return err
+}
+
+func (r ChatResponse) MarshalJSON() ([]byte, error) {
+	type alias ChatResponse
+	data, err := json.Marshal(alias(r))
+	if err != nil {
+		return nil, err
+	}
+	return mergeExtraBody(data, r.ExtraFields)
+}
+
+func (r *ChatResponse) UnmarshalJSON(data []byte) error {
+	type alias ChatResponse
+	if err := json.Unmarshal(data, (*alias)(r)); err != nil {
+		return err
+	}
+
+	var err error
+	r.Raw = append(json.RawMessage(nil), data...)
+	r.ExtraFields, err = unknownFields(data, reflect.TypeOf(*r))
+	return err
+}
+
+func (c ChatChoice) MarshalJSON() ([]byte, error) {
+	type alias ChatChoice
+	data, err := json.Marshal(alias(c))
+	if err != nil {
+		return nil, err
+	}
+	return mergeExtraBody(data, c.ExtraFields)
+}
+
+func (c *ChatChoice) UnmarshalJSON(data []byte) error {
+	type alias ChatChoice
+	if err := json.Unmarshal(data, (*alias)(c)); err != nil {
+		return err
+	}
+
+	var err error
+	c.ExtraFields, err = unknownFields(data, reflect.TypeOf(*c))
+	return err
+}
+
+func (r CompletionResponse) MarshalJSON() ([]byte, error) {
+	type alias CompletionResponse
+	data, err := json.Marshal(alias(r))
+	if err != nil {
+		return nil, err
+	}
+	return mergeExtraBody(data, r.ExtraFields)
+}
+
+func (r *CompletionResponse) UnmarshalJSON(data []byte) error {
+	type alias CompletionResponse
+	if err := json.Unmarshal(data, (*alias)(r)); err != nil {
+		return err
+	}
+
+	var err error
+	r.Raw = append(json.RawMessage(nil), data...)
+	r.ExtraFields, err = unknownFields(data, reflect.TypeOf(*r))
+	return err
+}
+
+func (c CompletionChoice) MarshalJSON() ([]byte, error) {
+	type alias CompletionChoice
+	data, err := json.Marshal(alias(c))
+	if err != nil {
+		return nil, err
+	}
+	return mergeExtraBody(data, c.ExtraFields)
+}
+
+func (c *CompletionChoice) UnmarshalJSON(data []byte) error {
+	type alias CompletionChoice
+	if err := json.Unmarshal(data, (*alias)(c)); err != nil {
+		return err
+	}
+
+	var err error
+	c.ExtraFields, err = unknownFields(data, reflect.TypeOf(*c))
+	return err
+}
+
+func (r EmbeddingResponse) MarshalJSON() ([]byte, error) {
+	type alias EmbeddingResponse
+	data, err := json.Marshal(alias(r))
+	if err != nil {
+		return nil, err
+	}
+	return mergeExtraBody(data, r.ExtraFields)
+}
+
+func (r *EmbeddingResponse) UnmarshalJSON(data []byte) error {
+	type alias EmbeddingResponse
+	if err := json.Unmarshal(data, (*alias)(r)); err != nil {
+		return err
+	}
+
+	var err error
+	r.Raw = append(json.RawMessage(nil), data...)
+	r.ExtraFields, err = unknownFields(data, reflect.TypeOf(*r))
+	return err
+}
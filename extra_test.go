@@ -0,0 +1,83 @@
+package aoai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestChatRequest_ExtraBody(t *testing.T) {
+	request := ChatRequest{
+		Messages:  []ChatMessage{{Role: RoleUser, Content: "Hi"}},
+		User:      "u",
+		ExtraBody: map[string]any{"verbosity": "low", "user": "override"},
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"messages":[{"role":"user","content":"Hi"}],"user":"override","verbosity":"low"}`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	var decoded ChatRequest
+	if err := json.Unmarshal([]byte(`{"messages":[],"prediction":{"type":"content"}}`), &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded.ExtraBody, map[string]any{"prediction": json.RawMessage(`{"type":"content"}`)}) {
+		t.Errorf("ExtraBody = %v", decoded.ExtraBody)
+	}
+	if data, _ := json.Marshal(decoded); string(data) != `{"prediction":{"type":"content"}}` {
+		t.Errorf("Marshal() after Unmarshal() = %s", data)
+	}
+}
+
+func TestCompletionRequest_ExtraBody(t *testing.T) {
+	data, err := json.Marshal(CompletionRequest{PromptTokens: TokenSlices{{1}}, ExtraBody: map[string]any{"echo": true}})
+	if err != nil || string(data) != `{"echo":true,"prompt":[1]}` {
+		t.Errorf("Marshal() = %s, %v", data, err)
+	}
+	data, err = json.Marshal(EmbeddingRequest{Inputs: []string{"Hi"}, ExtraBody: map[string]any{"dimensions": 256}})
+	if err != nil || string(data) != `{"dimensions":256,"input":"Hi"}` {
+		t.Errorf("Marshal() = %s, %v", data, err)
+	}
+}
+
+func TestAzureOpenAI_ChatCompletion_extraFields(t *testing.T) {
+	const body = `{"id":"chatcmpl-1","service_tier":"default","choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},
+		"content_filter_results":{"hate":{"filtered":false}}}],"prompt_filter_results":[]}`
+	var sent map[string]any
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(m, &sent)
+		_, _ = w.Write([]byte(body))
+	}))
+
+	response, err := a.ChatCompletion(context.Background(), ChatRequest{ExtraBody: map[string]any{"store": true}})
+	if err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if sent["store"] != true {
+		t.Errorf("ChatCompletion() sent %v", sent)
+	}
+	if string(response.Raw) != body {
+		t.Errorf("Raw = %s", response.Raw)
+	}
+	if len(response.ExtraFields) != 2 || string(response.ExtraFields["service_tier"]) != `"default"` {
+		t.Errorf("ExtraFields = %v", response.ExtraFields)
+	}
+	if string(response.Choices[0].ExtraFields["content_filter_results"]) != `{"hate":{"filtered":false}}` {
+		t.Errorf("Choices[0].ExtraFields = %v", response.Choices[0].ExtraFields)
+	}
+
+	data, _ := json.Marshal(response)
+	var roundTrip map[string]any
+	_ = json.Unmarshal(data, &roundTrip)
+	if roundTrip["service_tier"] != "default" || roundTrip["choices"].([]any)[0].(map[string]any)["content_filter_results"] == nil {
+		t.Errorf("Marshal() = %s", data)
+	}
+}
@@ -0,0 +1,140 @@
+package aoai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+)
+
+type File struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - file
+	Object string `json:"object,omitempty"`
+
+	// bytes:
+	//   type: integer
+	//   description: The size of the file, in bytes.
+	Bytes int `json:"bytes,omitempty"`
+
+	// created_at:
+	//   type: integer
+	//   format: unixtime
+	CreatedAt int `json:"created_at,omitempty"`
+
+	// filename:
+	//   type: string
+	Filename string `json:"filename,omitempty"`
+
+	// purpose:
+	//   type: string
+	//   enum:
+	//     - fine-tune
+	//     - fine-tune-results
+	//     - assistants
+	//     - assistants_output
+	//     - batch
+	//     - batch_output
+	Purpose string `json:"purpose,omitempty"`
+
+	// status:
+	//   type: string
+	//   enum:
+	//     - uploaded
+	//     - pending
+	//     - running
+	//     - processed
+	//     - error
+	//     - deleting
+	//     - deleted
+	Status string `json:"status,omitempty"`
+
+	// status_details:
+	//   type: string
+	//   description: Error details when the file could not be processed.
+	StatusDetails string `json:"status_details,omitempty"`
+}
+
+type DeleteResponse struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	Object string `json:"object,omitempty"`
+
+	// deleted:
+	//   type: boolean
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// UploadFile uploads content as a multipart form, e.g. a JSONL input for the Batch API with purpose `batch`.
+func (a *AzureOpenAI) UploadFile(ctx context.Context, filename string, purpose string, content io.Reader) (*File, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("purpose", purpose); err != nil {
+		return nil, err
+	}
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/files?api-version=%s", a.resourceEndpoint(), a.apiVersion)
+	return callRest(ctx, a, OperationUploadFile, nil, func(ctx context.Context, httpClient *http.Client, call *Call) (*File, error) {
+		httpRequest, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return nil, err
+		}
+		httpRequest.Header = call.Header
+		httpRequest.Header.Set("Content-Type", writer.FormDataContentType())
+		return doJsonRequest[File](httpClient, httpRequest)
+	})
+}
+
+func (a *AzureOpenAI) GetFile(ctx context.Context, fileID string) (*File, error) {
+	endpoint := fmt.Sprintf("%s/files/%s?api-version=%s", a.resourceEndpoint(), url.PathEscape(fileID), a.apiVersion)
+	return callGet[File](ctx, a, OperationGetFile, endpoint)
+}
+
+// GetFileContent downloads the raw content of a file, e.g. the output or error file of a batch.
+func (a *AzureOpenAI) GetFileContent(ctx context.Context, fileID string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/files/%s/content?api-version=%s", a.resourceEndpoint(), url.PathEscape(fileID), a.apiVersion)
+	content, err := callRest(ctx, a, OperationGetFileContent, nil, func(ctx context.Context, httpClient *http.Client, call *Call) (*[]byte, error) {
+		httpRequest, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		httpRequest.Header = call.Header
+		content, err := doRequest(httpClient, httpRequest)
+		if err != nil {
+			return nil, err
+		}
+		return &content, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return *content, nil
+}
+
+func (a *AzureOpenAI) DeleteFile(ctx context.Context, fileID string) (*DeleteResponse, error) {
+	endpoint := fmt.Sprintf("%s/files/%s?api-version=%s", a.resourceEndpoint(), url.PathEscape(fileID), a.apiVersion)
+	return callDelete[DeleteResponse](ctx, a, OperationDeleteFile, endpoint)
+}
@@ -0,0 +1,355 @@
+package aoai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+const (
+	FineTuningStatusValidatingFiles = "validating_files"
+	FineTuningStatusPending         = "pending"
+	FineTuningStatusQueued          = "queued"
+	FineTuningStatusRunning         = "running"
+	FineTuningStatusSucceeded       = "succeeded"
+	FineTuningStatusFailed          = "failed"
+	FineTuningStatusCancelled       = "cancelled"
+)
+
+// Hyperparameter is either `auto` or a number.
+type Hyperparameter struct {
+	Auto  bool
+	Value float64
+}
+
+func AutoHyperparameter() *Hyperparameter {
+	return &Hyperparameter{Auto: true}
+}
+
+func NumberHyperparameter(value float64) *Hyperparameter {
+	return &Hyperparameter{Value: value}
+}
+
+func (h Hyperparameter) MarshalJSON() ([]byte, error) {
+	if h.Auto {
+		return []byte(`"auto"`), nil
+	}
+	return json.Marshal(h.Value)
+}
+
+func (h *Hyperparameter) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte(`"auto"`)) {
+		*h = Hyperparameter{Auto: true}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		value, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid hyperparameter: %s", data)
+		}
+		*h = Hyperparameter{Value: value}
+		return nil
+	}
+	var value float64
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*h = Hyperparameter{Value: value}
+	return nil
+}
+
+type FineTuningHyperparameters struct {
+	// n_epochs:
+	//   description: The number of epochs to train the model for.
+	//   oneOf:
+	//     - type: string
+	//       enum:
+	//         - auto
+	//     - type: integer
+	//       minimum: 1
+	//       maximum: 50
+	NEpochs *Hyperparameter `json:"n_epochs,omitempty"`
+
+	// batch_size:
+	//   description: Number of examples in each batch.
+	//   oneOf:
+	//     - type: string
+	//       enum:
+	//         - auto
+	//     - type: integer
+	//       minimum: 1
+	//       maximum: 256
+	BatchSize *Hyperparameter `json:"batch_size,omitempty"`
+
+	// learning_rate_multiplier:
+	//   description: Scaling factor for the learning rate.
+	//   oneOf:
+	//     - type: string
+	//       enum:
+	//         - auto
+	//     - type: number
+	//       minimum: 0
+	//       exclusiveMinimum: true
+	LearningRateMultiplier *Hyperparameter `json:"learning_rate_multiplier,omitempty"`
+}
+
+type FineTuningJobRequest struct {
+	// model:
+	//   description: The name of the base model or a previously fine-tuned model to fine-tune.
+	//   type: string
+	Model string `json:"model"`
+
+	// training_file:
+	//   description: The ID of an uploaded file with purpose `fine-tune` that contains training data.
+	//   type: string
+	TrainingFile string `json:"training_file"`
+
+	// validation_file:
+	//   description: The ID of an uploaded file with purpose `fine-tune` that contains validation data.
+	//   type: string
+	//   nullable: true
+	ValidationFile string `json:"validation_file,omitempty"`
+
+	// hyperparameters:
+	//   type: FineTuningHyperparameters
+	Hyperparameters *FineTuningHyperparameters `json:"hyperparameters,omitempty"`
+
+	// suffix:
+	//   description: A string of up to 18 characters that will be added to your fine-tuned model name.
+	//   type: string
+	//   nullable: true
+	Suffix string `json:"suffix,omitempty"`
+
+	// seed:
+	//   description: The seed controls the reproducibility of the job.
+	//   type: integer
+	//   nullable: true
+	Seed *int `json:"seed,omitempty"`
+}
+
+type FineTuningJob struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - fine_tuning.job
+	Object string `json:"object,omitempty"`
+
+	// created_at:
+	//   type: integer
+	//   format: unixtime
+	CreatedAt int `json:"created_at,omitempty"`
+
+	// finished_at:
+	//   type: integer
+	//   format: unixtime
+	//   nullable: true
+	FinishedAt int `json:"finished_at,omitempty"`
+
+	// estimated_finish:
+	//   type: integer
+	//   format: unixtime
+	//   nullable: true
+	EstimatedFinish int `json:"estimated_finish,omitempty"`
+
+	// model:
+	//   type: string
+	Model string `json:"model,omitempty"`
+
+	// fine_tuned_model:
+	//   description: The name of the fine-tuned model that is being created. Null while the job is running.
+	//   type: string
+	//   nullable: true
+	FineTunedModel string `json:"fine_tuned_model,omitempty"`
+
+	// organization_id:
+	//   type: string
+	OrganizationID string `json:"organization_id,omitempty"`
+
+	// status:
+	//   type: string
+	//   enum:
+	//     - validating_files
+	//     - pending
+	//     - queued
+	//     - running
+	//     - succeeded
+	//     - failed
+	//     - cancelled
+	Status string `json:"status,omitempty"`
+
+	// hyperparameters:
+	//   type: FineTuningHyperparameters
+	Hyperparameters *FineTuningHyperparameters `json:"hyperparameters,omitempty"`
+
+	// training_file:
+	//   type: string
+	TrainingFile string `json:"training_file,omitempty"`
+
+	// validation_file:
+	//   type: string
+	//   nullable: true
+	ValidationFile string `json:"validation_file,omitempty"`
+
+	// result_files:
+	//   type: array
+	//   items:
+	//     type: string
+	ResultFiles []string `json:"result_files,omitempty"`
+
+	// trained_tokens:
+	//   type: integer
+	//   nullable: true
+	TrainedTokens int `json:"trained_tokens,omitempty"`
+
+	// error:
+	//   type: Error
+	//   nullable: true
+	Error *Error `json:"error,omitempty"`
+
+	// suffix:
+	//   type: string
+	Suffix string `json:"suffix,omitempty"`
+
+	// seed:
+	//   type: integer
+	Seed *int `json:"seed,omitempty"`
+}
+
+// Terminal reports whether the job reached a state it will never leave.
+func (j *FineTuningJob) Terminal() bool {
+	switch j.Status {
+	case FineTuningStatusSucceeded, FineTuningStatusFailed, FineTuningStatusCancelled:
+		return true
+	}
+	return false
+}
+
+type FineTuningJobEvent struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - fine_tuning.job.event
+	Object string `json:"object,omitempty"`
+
+	// created_at:
+	//   type: integer
+	//   format: unixtime
+	CreatedAt int `json:"created_at,omitempty"`
+
+	// level:
+	//   type: string
+	//   enum:
+	//     - info
+	//     - warn
+	//     - error
+	Level string `json:"level,omitempty"`
+
+	// message:
+	//   type: string
+	Message string `json:"message,omitempty"`
+
+	// type:
+	//   type: string
+	//   enum:
+	//     - message
+	//     - metrics
+	Type string `json:"type,omitempty"`
+
+	// data:
+	//   description: Machine readable data of the event, e.g. training metrics.
+	//   type: object
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+type FineTuningJobCheckpoint struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - fine_tuning.job.checkpoint
+	Object string `json:"object,omitempty"`
+
+	// created_at:
+	//   type: integer
+	//   format: unixtime
+	CreatedAt int `json:"created_at,omitempty"`
+
+	// fine_tuned_model_checkpoint:
+	//   description: The name of the fine-tuned checkpoint model that is created.
+	//   type: string
+	FineTunedModelCheckpoint string `json:"fine_tuned_model_checkpoint,omitempty"`
+
+	// fine_tuning_job_id:
+	//   type: string
+	FineTuningJobID string `json:"fine_tuning_job_id,omitempty"`
+
+	// step_number:
+	//   type: integer
+	StepNumber int `json:"step_number,omitempty"`
+
+	// metrics:
+	//   type: object
+	//   additionalProperties:
+	//     type: number
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+}
+
+func (a *AzureOpenAI) fineTuningEndpoint(path string, values url.Values) string {
+	if values == nil {
+		values = url.Values{"api-version": []string{a.apiVersion}}
+	}
+	return fmt.Sprintf("%s/fine_tuning/jobs%s?%s", a.resourceEndpoint(), path, values.Encode())
+}
+
+func (a *AzureOpenAI) CreateFineTuningJob(ctx context.Context, request FineTuningJobRequest) (*FineTuningJob, error) {
+	endpoint := a.fineTuningEndpoint("", nil)
+	return callPost[FineTuningJobRequest, FineTuningJob](ctx, a, OperationCreateFineTuningJob, endpoint, request)
+}
+
+func (a *AzureOpenAI) ListFineTuningJobs(ctx context.Context, options ListOptions) (*ListResponse[FineTuningJob], error) {
+	endpoint := a.fineTuningEndpoint("", options.values(a.apiVersion))
+	return callGet[ListResponse[FineTuningJob]](ctx, a, OperationListFineTuningJobs, endpoint)
+}
+
+func (a *AzureOpenAI) GetFineTuningJob(ctx context.Context, jobID string) (*FineTuningJob, error) {
+	endpoint := a.fineTuningEndpoint("/"+url.PathEscape(jobID), nil)
+	return callGet[FineTuningJob](ctx, a, OperationGetFineTuningJob, endpoint)
+}
+
+func (a *AzureOpenAI) CancelFineTuningJob(ctx context.Context, jobID string) (*FineTuningJob, error) {
+	endpoint := a.fineTuningEndpoint("/"+url.PathEscape(jobID)+"/cancel", nil)
+	return callPost[struct{}, FineTuningJob](ctx, a, OperationCancelFineTuningJob, endpoint, struct{}{})
+}
+
+func (a *AzureOpenAI) ListFineTuningJobEvents(ctx context.Context, jobID string, options ListOptions) (*ListResponse[FineTuningJobEvent], error) {
+	endpoint := a.fineTuningEndpoint("/"+url.PathEscape(jobID)+"/events", options.values(a.apiVersion))
+	return callGet[ListResponse[FineTuningJobEvent]](ctx, a, OperationListFineTuningJobEvents, endpoint)
+}
+
+func (a *AzureOpenAI) ListFineTuningJobCheckpoints(ctx context.Context, jobID string, options ListOptions) (*ListResponse[FineTuningJobCheckpoint], error) {
+	endpoint := a.fineTuningEndpoint("/"+url.PathEscape(jobID)+"/checkpoints", options.values(a.apiVersion))
+	return callGet[ListResponse[FineTuningJobCheckpoint]](ctx, a, OperationListFineTuningJobCheckpoints, endpoint)
+}
+
+// WaitFineTuningJob polls the job with backoff until it succeeded, failed or was cancelled.
+func (a *AzureOpenAI) WaitFineTuningJob(ctx context.Context, jobID string, options PollOptions) (*FineTuningJob, error) {
+	return poll[FineTuningJob](ctx, options, func(ctx context.Context) (*FineTuningJob, error) {
+		return a.GetFineTuningJob(ctx, jobID)
+	}, (*FineTuningJob).Terminal)
+}
@@ -0,0 +1,134 @@
+package aoai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHyperparameter_JSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want FineTuningHyperparameters
+	}{
+		{
+			name: "auto",
+			json: `{"n_epochs":"auto","batch_size":"auto","learning_rate_multiplier":"auto"}`,
+			want: FineTuningHyperparameters{
+				NEpochs:                AutoHyperparameter(),
+				BatchSize:              AutoHyperparameter(),
+				LearningRateMultiplier: AutoHyperparameter(),
+			},
+		},
+		{
+			name: "number",
+			json: `{"n_epochs":3,"learning_rate_multiplier":0.5}`,
+			want: FineTuningHyperparameters{
+				NEpochs:                NumberHyperparameter(3),
+				LearningRateMultiplier: NumberHyperparameter(0.5),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got FineTuningHyperparameters
+			if err := json.Unmarshal([]byte(tt.json), &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unmarshal() = %+v, want %+v", got, tt.want)
+			}
+			if m, _ := json.Marshal(got); string(m) != tt.json {
+				t.Errorf("Marshal() = %s, want %s", m, tt.json)
+			}
+		})
+	}
+
+	var h Hyperparameter
+	if err := json.Unmarshal([]byte(`"1.5"`), &h); err != nil || h.Value != 1.5 {
+		t.Errorf("Unmarshal() = %+v, %v", h, err)
+	}
+	if err := json.Unmarshal([]byte(`"many"`), &h); err == nil {
+		t.Errorf("Unmarshal() accepted an invalid hyperparameter")
+	}
+}
+
+func TestAzureOpenAI_FineTuningJobs(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		polls int
+	)
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /openai/fine_tuning/jobs":
+			var request FineTuningJobRequest
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			_ = json.NewEncoder(w).Encode(FineTuningJob{ID: "ftjob-1", Model: request.Model, TrainingFile: request.TrainingFile,
+				Hyperparameters: request.Hyperparameters, Status: FineTuningStatusPending})
+		case "GET /openai/fine_tuning/jobs/ftjob-1":
+			polls++
+			job := FineTuningJob{ID: "ftjob-1", Status: FineTuningStatusRunning}
+			if polls == 2 {
+				job.Status = FineTuningStatusSucceeded
+				job.FineTunedModel = "gpt-35-turbo-0613.ft-1"
+			}
+			_ = json.NewEncoder(w).Encode(job)
+		case "GET /openai/fine_tuning/jobs/ftjob-1/events":
+			if r.URL.Query().Get("after") != "ftevent-1" || r.URL.Query().Get("limit") != "1" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":{"code":"bad_request","message":"unexpected cursor"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"ftevent-2","type":"metrics","data":{"step":10}}],"has_more":true}`))
+		case "GET /openai/fine_tuning/jobs/ftjob-1/checkpoints":
+			_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"ftckpt-1","step_number":10,"metrics":{"train_loss":0.25}}]}`))
+		case "POST /openai/fine_tuning/jobs/ftjob-2/cancel":
+			_, _ = w.Write([]byte(`{"id":"ftjob-2","status":"cancelled"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"404","message":"not found"}}`))
+		}
+	}))
+	ctx := context.Background()
+
+	job, err := a.CreateFineTuningJob(ctx, FineTuningJobRequest{
+		Model:           "gpt-35-turbo-0613",
+		TrainingFile:    "file-train",
+		Hyperparameters: &FineTuningHyperparameters{NEpochs: NumberHyperparameter(2)},
+	})
+	if err != nil || job.ID != "ftjob-1" || job.Hyperparameters.NEpochs.Value != 2 {
+		t.Fatalf("CreateFineTuningJob() = %+v, %v", job, err)
+	}
+
+	job, err = a.WaitFineTuningJob(ctx, job.ID, PollOptions{Interval: time.Millisecond})
+	if err != nil || job.FineTunedModel != "gpt-35-turbo-0613.ft-1" || polls != 2 {
+		t.Errorf("WaitFineTuningJob() = %+v, %v after %d polls", job, err, polls)
+	}
+
+	events, err := a.ListFineTuningJobEvents(ctx, "ftjob-1", ListOptions{After: "ftevent-1", Limit: 1})
+	if err != nil || len(events.Data) != 1 || !events.HasMore || string(events.Data[0].Data) != `{"step":10}` {
+		t.Errorf("ListFineTuningJobEvents() = %+v, %v", events, err)
+	}
+
+	checkpoints, err := a.ListFineTuningJobCheckpoints(ctx, "ftjob-1", ListOptions{})
+	if err != nil || checkpoints.Data[0].Metrics["train_loss"] != 0.25 {
+		t.Errorf("ListFineTuningJobCheckpoints() = %+v, %v", checkpoints, err)
+	}
+
+	job, err = a.CancelFineTuningJob(ctx, "ftjob-2")
+	if err != nil || !job.Terminal() {
+		t.Errorf("CancelFineTuningJob() = %+v, %v", job, err)
+	}
+
+	if _, err := a.GetFineTuningJob(ctx, "missing"); err == nil {
+		t.Errorf("GetFineTuningJob() error = nil, want not found")
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/anaregdesign/go-aoai
 
 go 1.20
+
+require github.com/gorilla/websocket v1.5.3
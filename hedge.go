@@ -0,0 +1,226 @@
+package aoai
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// hedgeWindow is the number of recent latencies from which a Hedge computes its delay, and hedgeMinSamples the
+// number it needs before it stops using its initial delay.
+const (
+	hedgeWindow     = 100
+	hedgeMinSamples = 20
+)
+
+// errHedgeLost aborts a stream that delivered its first chunk after the other stream.
+var errHedgeLost = errors.New("hedged request lost")
+
+// HedgeStats counts the requests of a Hedge.
+type HedgeStats struct {
+	Requests int
+
+	// Hedged is the number of requests that were sent to the secondary deployment as well.
+	Hedged int
+
+	// SecondaryWins is the number of requests answered by the secondary deployment.
+	SecondaryWins int
+
+	// WastedTokens is the usage of the requests that lost. Requests canceled before their usage is known count
+	// their prompt tokens, estimated from its length.
+	WastedTokens int
+}
+
+// Hedge cuts the tail latency of chat completions by sending a request to a secondary deployment as well when the
+// primary one has not answered, or has not streamed its first chunk, within a percentile of its recent latencies.
+// The first answer wins and the other request is canceled. A request won by the secondary deployment counts the
+// time the primary one had been running by then as its latency. It is safe for concurrent use.
+type Hedge struct {
+	primary      *AzureOpenAI
+	secondary    *AzureOpenAI
+	percentile   float64
+	initialDelay time.Duration
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+	stats     HedgeStats
+}
+
+// NewHedge returns a Hedge that sends requests to primary and hedges them on secondary after the percentile, e.g.
+// 0.95, of recent latencies. initialDelay is used until enough latencies are observed.
+func NewHedge(primary *AzureOpenAI, secondary *AzureOpenAI, percentile float64, initialDelay time.Duration) *Hedge {
+	return &Hedge{primary: primary, secondary: secondary, percentile: percentile, initialDelay: initialDelay}
+}
+
+// Delay returns how long a request waits for the primary deployment before it is hedged.
+func (h *Hedge) Delay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < hedgeMinSamples {
+		return h.initialDelay
+	}
+	sorted := append([]time.Duration{}, h.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(math.Ceil(h.percentile*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// Stats returns the counts of the requests so far.
+func (h *Hedge) Stats() HedgeStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stats
+}
+
+func (h *Hedge) observe(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < hedgeWindow {
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % hedgeWindow
+}
+
+func (h *Hedge) ChatCompletion(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+	return h.do(ctx, request, func(ctx context.Context, client *AzureOpenAI, claim func() bool) (*ChatResponse, error) {
+		return client.ChatCompletion(ctx, request)
+	})
+}
+
+// ChatCompletionStreamAccumulate passes the chunks of the stream that delivers its first chunk first to consumer.
+func (h *Hedge) ChatCompletionStreamAccumulate(ctx context.Context, request ChatRequest, consumer func(ChatResponse) error) (*ChatResponse, error) {
+	return h.do(ctx, request, func(ctx context.Context, client *AzureOpenAI, claim func() bool) (*ChatResponse, error) {
+		return client.ChatCompletionStreamAccumulate(ctx, request, func(chunk ChatResponse) error {
+			if !claim() {
+				return errHedgeLost
+			}
+			return consumer(chunk)
+		})
+	})
+}
+
+// hedgeRace is a request sent to one or both deployments. The first attempt to claim it wins and cancels the
+// other.
+type hedgeRace struct {
+	hedge   *Hedge
+	mu      sync.Mutex
+	winner  int
+	started [2]time.Time
+	cancel  [2]context.CancelFunc
+}
+
+func (r *hedgeRace) claim(attempt int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.winner < 0 {
+		r.winner = attempt
+		// the latency of the primary deployment, which is only a lower bound when it loses, keeps the delay from
+		// drifting down to the latency of the requests it answers in time
+		r.hedge.observe(time.Since(r.started[0]))
+		if other := r.cancel[1-attempt]; other != nil {
+			other()
+		}
+	}
+	return r.winner == attempt
+}
+
+func (r *hedgeRace) claimedBy(attempt int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.winner == attempt
+}
+
+type hedgeResult struct {
+	attempt  int
+	response *ChatResponse
+	err      error
+}
+
+func (h *Hedge) do(ctx context.Context, request ChatRequest, call func(ctx context.Context, client *AzureOpenAI, claim func() bool) (*ChatResponse, error)) (*ChatResponse, error) {
+	race := &hedgeRace{hedge: h, winner: -1}
+	results := make(chan hedgeResult, 2)
+	start := func(attempt int, client *AzureOpenAI) {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		race.mu.Lock()
+		race.started[attempt] = time.Now()
+		race.cancel[attempt] = cancel
+		race.mu.Unlock()
+		go func() {
+			defer cancel()
+			claim := func() bool { return race.claim(attempt) }
+			response, err := call(attemptCtx, client, claim)
+			if err == nil && !claim() {
+				err = errHedgeLost
+			}
+			results <- hedgeResult{attempt: attempt, response: response, err: err}
+		}()
+	}
+
+	h.mu.Lock()
+	h.stats.Requests++
+	h.mu.Unlock()
+
+	timer := time.NewTimer(h.Delay())
+	defer timer.Stop()
+	start(0, h.primary)
+	pending := 1
+	select {
+	case result := <-results:
+		return result.response, result.err
+	case <-timer.C:
+	}
+
+	race.mu.Lock()
+	hedged := race.winner < 0
+	race.mu.Unlock()
+	if hedged {
+		h.mu.Lock()
+		h.stats.Hedged++
+		h.mu.Unlock()
+		start(1, h.secondary)
+		pending++
+	}
+
+	var won hedgeResult
+	var errs [2]error
+	for ; pending > 0; pending-- {
+		result := <-results
+		errs[result.attempt] = result.err
+		if race.claimedBy(result.attempt) {
+			won = result
+		} else if race.claimedBy(1 - result.attempt) {
+			h.waste(request, result.response)
+		}
+	}
+	if won.response == nil && won.err == nil {
+		return nil, errors.Join(errs[0], errs[1])
+	}
+	if won.attempt == 1 {
+		h.mu.Lock()
+		h.stats.SecondaryWins++
+		h.mu.Unlock()
+	}
+	return won.response, won.err
+}
+
+// waste records the tokens of a request that lost.
+func (h *Hedge) waste(request ChatRequest, response *ChatResponse) {
+	tokens := estimatePrompt(nil, request)
+	if response != nil && response.Usage.TotalTokens > 0 {
+		tokens = response.Usage.TotalTokens
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stats.WastedTokens += tokens
+}
@@ -0,0 +1,141 @@
+package aoai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// hangingBackend returns a client whose server never answers until the request is canceled. The body is read so
+// that the server notices the client going away.
+func hangingBackend(t *testing.T) *AzureOpenAI {
+	return newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+}
+
+func TestHedge_ChatCompletion(t *testing.T) {
+	tests := []struct {
+		name      string
+		primary   func(t *testing.T, calls *int) *AzureOpenAI
+		secondary func(t *testing.T, calls *int) *AzureOpenAI
+		wantErr   bool
+		want      HedgeStats
+		wantCalls [2]int
+	}{
+		{
+			name: "primary answers in time",
+			primary: func(t *testing.T, calls *int) *AzureOpenAI {
+				return countingBackend(t, calls, http.StatusOK, nil, okChatBody)
+			},
+			secondary: func(t *testing.T, calls *int) *AzureOpenAI { return hangingBackend(t) },
+			want:      HedgeStats{Requests: 1},
+			wantCalls: [2]int{1, 0},
+		},
+		{
+			name:    "secondary wins",
+			primary: func(t *testing.T, calls *int) *AzureOpenAI { return hangingBackend(t) },
+			secondary: func(t *testing.T, calls *int) *AzureOpenAI {
+				return countingBackend(t, calls, http.StatusOK, nil, okChatBody)
+			},
+			want:      HedgeStats{Requests: 1, Hedged: 1, SecondaryWins: 1, WastedTokens: estimatePrompt(nil, poolChatRequest)},
+			wantCalls: [2]int{0, 1},
+		},
+		{
+			name: "both fail",
+			primary: func(t *testing.T, calls *int) *AzureOpenAI {
+				return newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					*calls++
+					time.Sleep(50 * time.Millisecond)
+					w.WriteHeader(http.StatusInternalServerError)
+				}))
+			},
+			secondary: func(t *testing.T, calls *int) *AzureOpenAI {
+				return countingBackend(t, calls, http.StatusServiceUnavailable, nil, `{"error":{"code":"503"}}`)
+			},
+			wantErr:   true,
+			want:      HedgeStats{Requests: 1, Hedged: 1},
+			wantCalls: [2]int{1, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls [2]int
+			h := NewHedge(tt.primary(t, &calls[0]), tt.secondary(t, &calls[1]), 0.95, 10*time.Millisecond)
+
+			response, err := h.ChatCompletion(context.Background(), poolChatRequest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ChatCompletion() error = %v", err)
+			}
+			if err == nil && response.Choices[0].Message.Content != "Hi" {
+				t.Errorf("response = %+v", response)
+			}
+			if got := h.Stats(); got != tt.want {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.want)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("requests = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestHedge_ChatCompletionStreamAccumulate(t *testing.T) {
+	secondary := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(recordedChatStream))
+	}))
+	h := NewHedge(hangingBackend(t), secondary, 0.95, 10*time.Millisecond)
+
+	request := poolChatRequest
+	request.Stream = true
+	chunks := 0
+	response, err := h.ChatCompletionStreamAccumulate(context.Background(), request, func(ChatResponse) error {
+		chunks++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStreamAccumulate() error = %v", err)
+	}
+	if chunks != 3 || response.Choices[0].Message.Content != "Hello" {
+		t.Errorf("received %d chunks, response = %+v", chunks, response)
+	}
+	if got := h.Stats(); got.SecondaryWins != 1 || got.WastedTokens == 0 {
+		t.Errorf("Stats() = %+v", got)
+	}
+}
+
+func TestHedge_observesPrimaryLatency(t *testing.T) {
+	var calls int
+	delay := 20 * time.Millisecond
+	h := NewHedge(hangingBackend(t), countingBackend(t, &calls, http.StatusOK, nil, okChatBody), 0.95, delay)
+
+	if _, err := h.ChatCompletion(context.Background(), poolChatRequest); err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	// the secondary answers right away, but the primary had been running for at least the delay
+	if len(h.latencies) != 1 || h.latencies[0] < delay {
+		t.Errorf("latencies = %v, want one of at least %v", h.latencies, delay)
+	}
+}
+
+func TestHedge_Delay(t *testing.T) {
+	h := NewHedge(nil, nil, 0.9, time.Second)
+	for i := 1; i < hedgeMinSamples; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	if got := h.Delay(); got != time.Second {
+		t.Errorf("Delay() = %v, want the initial delay without enough samples", got)
+	}
+
+	for i := 1; i <= 2*hedgeWindow; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	// only the latest window of 101ms..200ms counts
+	if got, want := h.Delay(), 190*time.Millisecond; got != want {
+		t.Errorf("Delay() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,232 @@
+package aoai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrHistoryTooLong is returned when the pinned messages and the latest turn alone do not fit the token budget.
+var ErrHistoryTooLong = errors.New("chat history does not fit the token budget")
+
+// ChatCompleter is implemented by AzureOpenAI and by anything else that serves chat completions.
+type ChatCompleter interface {
+	ChatCompletion(ctx context.Context, request ChatRequest) (*ChatResponse, error)
+}
+
+// HistoryStrategy reduces turns, oldest first, so that they fit in budget tokens together with pinned. Pinned
+// messages are never removed; the strategy returns the messages that follow them.
+type HistoryStrategy interface {
+	Fit(ctx context.Context, counter *TokenCounter, pinned []ChatMessage, turns [][]ChatMessage, budget int) ([]ChatMessage, error)
+}
+
+// HistoryManager keeps a conversation within the context window of a deployment, leaving room for the reply.
+type HistoryManager struct {
+	counter              *TokenCounter
+	contextWindow        int
+	reservedOutputTokens int
+	strategy             HistoryStrategy
+}
+
+// NewHistoryManager returns a HistoryManager for a context window of contextWindow tokens, of which
+// reservedOutputTokens are kept for the reply.
+func NewHistoryManager(counter *TokenCounter, contextWindow int, reservedOutputTokens int, strategy HistoryStrategy) *HistoryManager {
+	return &HistoryManager{
+		counter:              counter,
+		contextWindow:        contextWindow,
+		reservedOutputTokens: reservedOutputTokens,
+		strategy:             strategy,
+	}
+}
+
+// Fit returns messages reduced by the strategy so that their prompt tokens fit the context window.
+// System and developer messages are pinned and always kept.
+func (m *HistoryManager) Fit(ctx context.Context, messages []ChatMessage) ([]ChatMessage, error) {
+	return m.fit(ctx, messages, m.contextWindow-m.reservedOutputTokens)
+}
+
+// FitRequest returns request with its messages reduced like Fit, also counting its tools. MaxTokens is set to
+// the reserved output tokens when it is not set.
+func (m *HistoryManager) FitRequest(ctx context.Context, request ChatRequest) (ChatRequest, error) {
+	reserved := m.reservedOutputTokens
+	for _, maxTokens := range []int{request.MaxTokens, request.MaxCompletionTokens} {
+		if maxTokens > reserved {
+			reserved = maxTokens
+		}
+	}
+	messages, err := m.fit(ctx, request.Messages, m.contextWindow-reserved-m.counter.CountTools(request.Tools))
+	if err != nil {
+		return request, err
+	}
+	request.Messages = messages
+	if request.MaxTokens == 0 && request.MaxCompletionTokens == 0 {
+		request.MaxTokens = m.reservedOutputTokens
+	}
+	return request, nil
+}
+
+func (m *HistoryManager) fit(ctx context.Context, messages []ChatMessage, budget int) ([]ChatMessage, error) {
+	pinned, turns := splitTurns(messages)
+	rest, err := m.strategy.Fit(ctx, m.counter, pinned, turns, budget)
+	if err != nil {
+		return nil, err
+	}
+	fitted := append(append([]ChatMessage{}, pinned...), rest...)
+	if tokens := m.counter.CountMessages(fitted); tokens > budget {
+		return nil, fmt.Errorf("%w: %d tokens, budget %d", ErrHistoryTooLong, tokens, budget)
+	}
+	return fitted, nil
+}
+
+func isPinned(message ChatMessage) bool {
+	return message.Role == RoleSystem || message.Role == RoleDeveloper
+}
+
+// splitTurns separates the pinned messages from the turns. A turn starts with a user message and holds the
+// replies and tool results that follow it, so that dropping a turn never leaves a tool result without its call.
+func splitTurns(messages []ChatMessage) ([]ChatMessage, [][]ChatMessage) {
+	var pinned []ChatMessage
+	var turns [][]ChatMessage
+	for _, message := range messages {
+		switch {
+		case isPinned(message) && message.Name != historySummaryName:
+			pinned = append(pinned, message)
+		case message.Role == RoleUser || len(turns) == 0:
+			turns = append(turns, []ChatMessage{message})
+		default:
+			turns[len(turns)-1] = append(turns[len(turns)-1], message)
+		}
+	}
+	return pinned, turns
+}
+
+func flatten(turns [][]ChatMessage) []ChatMessage {
+	var messages []ChatMessage
+	for _, turn := range turns {
+		messages = append(messages, turn...)
+	}
+	return messages
+}
+
+// fittingTurns returns the longest suffix of turns whose messages fit in budget together with pinned. It adds the
+// turns from the latest one, counting every message once.
+func fittingTurns(counter *TokenCounter, pinned []ChatMessage, turns [][]ChatMessage, budget int) [][]ChatMessage {
+	tokens := counter.CountMessages(pinned)
+	start := len(turns)
+	for i := len(turns) - 1; i >= 0; i-- {
+		for _, message := range turns[i] {
+			tokens += counter.countMessage(message)
+		}
+		if tokens > budget {
+			break
+		}
+		start = i
+	}
+	return turns[start:]
+}
+
+// DropOldest drops the oldest turns until the rest fits.
+type DropOldest struct{}
+
+func (DropOldest) Fit(_ context.Context, counter *TokenCounter, pinned []ChatMessage, turns [][]ChatMessage, budget int) ([]ChatMessage, error) {
+	return lastTurn(fittingTurns(counter, pinned, turns, budget), turns), nil
+}
+
+// lastTurn returns kept, or the latest turn if nothing fits, so that the caller reports the history as too long
+// instead of sending a request without the question.
+func lastTurn(kept [][]ChatMessage, turns [][]ChatMessage) []ChatMessage {
+	if len(kept) == 0 && len(turns) > 0 {
+		return turns[len(turns)-1]
+	}
+	return flatten(kept)
+}
+
+// SlidingWindow keeps at most the latest Turns turns, and fewer if they do not fit.
+type SlidingWindow struct {
+	Turns int
+}
+
+func (w SlidingWindow) Fit(_ context.Context, counter *TokenCounter, pinned []ChatMessage, turns [][]ChatMessage, budget int) ([]ChatMessage, error) {
+	if w.Turns > 0 && len(turns) > w.Turns {
+		turns = turns[len(turns)-w.Turns:]
+	}
+	return lastTurn(fittingTurns(counter, pinned, turns, budget), turns), nil
+}
+
+// historySummaryName names the system message that holds the summary of evicted turns, so that it is replaced
+// instead of pinned when the history is summarized again.
+const historySummaryName = "history_summary"
+
+// DefaultSummaryPrompt instructs the model to summarize evicted turns.
+const DefaultSummaryPrompt = "Summarize the following conversation concisely, keeping facts, decisions and open questions " +
+	"that later messages may refer to."
+
+// DefaultSummaryMaxTokens limits the length of a summary when Summarize.MaxTokens is not set.
+const DefaultSummaryMaxTokens = 256
+
+// Summarize replaces the oldest turns that do not fit with a system message summarizing them, written by a chat
+// completion of Client. If the latest turn does not fit next to the summary, Fit returns ErrHistoryTooLong rather
+// than dropping the older turns without a summary.
+type Summarize struct {
+	Client ChatCompleter
+
+	// Prompt instructs the model how to summarize. DefaultSummaryPrompt is used when it is empty.
+	Prompt string
+
+	// MaxTokens limits the length of the summary. Room for it is kept in the budget. DefaultSummaryMaxTokens is
+	// used when it is not positive.
+	MaxTokens int
+}
+
+func (s Summarize) Fit(ctx context.Context, counter *TokenCounter, pinned []ChatMessage, turns [][]ChatMessage, budget int) ([]ChatMessage, error) {
+	var previous []ChatMessage
+	if len(turns) > 0 && len(turns[0]) > 0 && turns[0][0].Name == historySummaryName {
+		previous, turns[0] = []ChatMessage{turns[0][0]}, turns[0][1:]
+	}
+
+	maxTokens := s.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultSummaryMaxTokens
+	}
+	summary := ChatMessage{Role: RoleSystem, Name: historySummaryName}
+	// reserve room for the summary message itself
+	reserved := counter.countMessage(summary) + maxTokens
+	kept := fittingTurns(counter, pinned, turns, budget-reserved)
+	if len(kept) == len(turns) {
+		return append(previous, flatten(kept)...), nil
+	}
+	if len(kept) == 0 {
+		if len(previous) == 0 && len(turns) == 1 {
+			// nothing to summarize, the caller reports the history as too long
+			return turns[0], nil
+		}
+		return nil, fmt.Errorf("%w: the latest turn does not fit next to a summary of %d tokens", ErrHistoryTooLong, maxTokens)
+	}
+	evicted := append(previous, flatten(turns[:len(turns)-len(kept)])...)
+
+	prompt := s.Prompt
+	if prompt == "" {
+		prompt = DefaultSummaryPrompt
+	}
+	var transcript strings.Builder
+	for _, message := range evicted {
+		fmt.Fprintf(&transcript, "%s: %s\n", message.Role, message.Content)
+	}
+	response, err := s.Client.ChatCompletion(ctx, ChatRequest{
+		Messages: []ChatMessage{
+			{Role: RoleSystem, Content: prompt},
+			{Role: RoleUser, Content: transcript.String()},
+		},
+		MaxTokens: maxTokens,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("summarize history: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("summarize history: no choices")
+	}
+
+	summary.Content = response.Choices[0].Message.Content
+	return append([]ChatMessage{summary}, flatten(kept)...), nil
+}
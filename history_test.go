@@ -0,0 +1,157 @@
+package aoai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeChatCompleter struct {
+	content  string
+	requests []ChatRequest
+}
+
+func (f *fakeChatCompleter) ChatCompletion(_ context.Context, request ChatRequest) (*ChatResponse, error) {
+	f.requests = append(f.requests, request)
+	return &ChatResponse{Choices: []ChatChoice{{Message: ChatMessage{Role: RoleAssistant, Content: f.content}}}}, nil
+}
+
+func TestHistoryManager_Fit(t *testing.T) {
+	// with one token per byte: system 10, user 11 and assistant 16 tokens, plus 3 to prime the reply
+	system := ChatMessage{Role: RoleSystem, Content: "s"}
+	user := ChatMessage{Role: RoleUser, Content: "aaaa"}
+	assistant := ChatMessage{Role: RoleAssistant, Content: "bbbb"}
+	messages := []ChatMessage{system, user, assistant, user, assistant, user}
+
+	tests := []struct {
+		name          string
+		strategy      HistoryStrategy
+		contextWindow int
+		want          []ChatMessage
+		wantErr       error
+	}{
+		{name: "fits", strategy: DropOldest{}, contextWindow: 88, want: messages},
+		{name: "dropOldest", strategy: DropOldest{}, contextWindow: 61, want: []ChatMessage{system, user, assistant, user}},
+		{name: "latestTurnOnly", strategy: DropOldest{}, contextWindow: 60, want: []ChatMessage{system, user}},
+		{name: "tooLong", strategy: DropOldest{}, contextWindow: 30, wantErr: ErrHistoryTooLong},
+		{name: "slidingWindow", strategy: SlidingWindow{Turns: 2}, contextWindow: 1000, want: []ChatMessage{system, user, assistant, user}},
+		{name: "slidingWindowOverBudget", strategy: SlidingWindow{Turns: 2}, contextWindow: 60, want: []ChatMessage{system, user}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewHistoryManager(newTestTokenCounter(t, "gpt-4o"), tt.contextWindow, 10, tt.strategy)
+			got, err := m.Fit(context.Background(), messages)
+			if !errors.Is(err, tt.wantErr) || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Fit() = %v, %v, want %v, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHistoryManager_FitRequest(t *testing.T) {
+	m := NewHistoryManager(newTestTokenCounter(t, "gpt-4o"), 61, 10, DropOldest{})
+	user := ChatMessage{Role: RoleUser, Content: "aaaa"}
+	request := ChatRequest{Messages: []ChatMessage{user, user, user}}
+
+	got, err := m.FitRequest(context.Background(), request)
+	if err != nil || len(got.Messages) != 3 || got.MaxTokens != 10 {
+		t.Errorf("FitRequest() = %+v, %v", got, err)
+	}
+
+	// a larger max_tokens takes room from the prompt
+	request.MaxTokens = 30
+	if got, err = m.FitRequest(context.Background(), request); err != nil || len(got.Messages) != 2 || got.MaxTokens != 30 {
+		t.Errorf("FitRequest() = %+v, %v", got, err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	completer := &fakeChatCompleter{content: "sum"}
+	m := NewHistoryManager(newTestTokenCounter(t, "gpt-4o"), 80, 10, Summarize{Client: completer, MaxTokens: 5})
+	system := ChatMessage{Role: RoleSystem, Content: "s"}
+	user := ChatMessage{Role: RoleUser, Content: "aaaa"}
+	assistant := ChatMessage{Role: RoleAssistant, Content: "bbbb"}
+	summary := ChatMessage{Role: RoleSystem, Name: historySummaryName, Content: "sum"}
+
+	got, err := m.Fit(context.Background(), []ChatMessage{system, user, assistant, user, assistant, user})
+	if want := []ChatMessage{system, summary, user}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("Fit() = %v, %v, want %v", got, err, want)
+	}
+	request := completer.requests[0]
+	if request.MaxTokens != 5 || request.Messages[0].Content != DefaultSummaryPrompt ||
+		request.Messages[1].Content != "user: aaaa\nassistant: bbbb\nuser: aaaa\nassistant: bbbb\n" {
+		t.Errorf("summary request = %+v", request)
+	}
+
+	// the summary is kept while the history fits and folded into the next summary otherwise
+	got, err = m.Fit(context.Background(), append(got, assistant))
+	if want := []ChatMessage{system, summary, user, assistant}; err != nil || !reflect.DeepEqual(got, want) || len(completer.requests) != 1 {
+		t.Fatalf("Fit() = %v, %v, want %v", got, err, want)
+	}
+	got, err = m.Fit(context.Background(), append(got, user))
+	if want := []ChatMessage{system, summary, user}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("Fit() = %v, %v, want %v", got, err, want)
+	}
+	if transcript := completer.requests[1].Messages[1].Content; !strings.HasPrefix(transcript, "system: sum\nuser: aaaa\n") {
+		t.Errorf("transcript = %q", transcript)
+	}
+}
+
+func TestSummarize_budget(t *testing.T) {
+	user := ChatMessage{Role: RoleUser, Content: "aaaa"}
+	assistant := ChatMessage{Role: RoleAssistant, Content: "bbbb"}
+	long := ChatMessage{Role: RoleUser, Content: strings.Repeat("c", 40)}
+	summary := ChatMessage{Role: RoleSystem, Name: historySummaryName, Content: "sum"}
+
+	tests := []struct {
+		name          string
+		maxTokens     int
+		contextWindow int
+		messages      []ChatMessage
+		want          []ChatMessage
+		wantErr       error
+		wantMaxTokens int
+	}{
+		{
+			name:          "default max tokens",
+			contextWindow: 310,
+			messages:      []ChatMessage{user, assistant, user},
+			want:          []ChatMessage{summary, user},
+			wantMaxTokens: DefaultSummaryMaxTokens,
+		},
+		{
+			name:          "latest turn does not fit next to a summary",
+			maxTokens:     5,
+			contextWindow: 80,
+			messages:      []ChatMessage{user, assistant, long},
+			wantErr:       ErrHistoryTooLong,
+		},
+		{
+			name:          "nothing to summarize",
+			maxTokens:     5,
+			contextWindow: 80,
+			messages:      []ChatMessage{long},
+			want:          []ChatMessage{long},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			completer := &fakeChatCompleter{content: "sum"}
+			m := NewHistoryManager(newTestTokenCounter(t, "gpt-4o"), tt.contextWindow, 10, Summarize{Client: completer, MaxTokens: tt.maxTokens})
+
+			got, err := m.Fit(context.Background(), tt.messages)
+			if !errors.Is(err, tt.wantErr) || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Fit() = %v, %v, want %v, %v", got, err, tt.want, tt.wantErr)
+			}
+			if tt.wantMaxTokens == 0 {
+				if len(completer.requests) != 0 {
+					t.Errorf("summarized %d times, want none", len(completer.requests))
+				}
+			} else if len(completer.requests) != 1 || completer.requests[0].MaxTokens != tt.wantMaxTokens {
+				t.Errorf("summary requests = %+v", completer.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package aoai
+
+import "math"
+
+type ChatLogprobs struct {
+	// content:
+	//   description: Log probabilities of the message content tokens.
+	//   type: []ChatTokenLogprob
+	Content []ChatTokenLogprob `json:"content,omitempty"`
+
+	// refusal:
+	//   description: Log probabilities of the message refusal tokens.
+	//   type: []ChatTokenLogprob
+	Refusal []ChatTokenLogprob `json:"refusal,omitempty"`
+}
+
+type ChatTokenLogprob struct {
+	// token:
+	//   type: string
+	Token string `json:"token"`
+
+	// logprob:
+	//   description: The log probability of this token, or -9999.0 if it is not in the top 20 most likely tokens.
+	//   type: number
+	Logprob float64 `json:"logprob"`
+
+	// bytes:
+	//   description: The UTF-8 bytes of the token, useful when a character is split across tokens.
+	//   type: array
+	//   items:
+	//     type: integer
+	Bytes []int `json:"bytes,omitempty"`
+
+	// top_logprobs:
+	//   description: The most likely tokens at this position, when `top_logprobs` is requested.
+	//   type: []ChatTokenLogprob
+	TopLogprobs []ChatTokenLogprob `json:"top_logprobs,omitempty"`
+}
+
+// Probability returns the linear probability of the token.
+func (t ChatTokenLogprob) Probability() float64 {
+	return math.Exp(t.Logprob)
+}
+
+type TokenConfidence struct {
+	Token       string
+	Probability float64
+}
+
+// Append adds the tokens of a streamed chunk to l.
+func (l *ChatLogprobs) Append(chunk *ChatLogprobs) {
+	if chunk == nil {
+		return
+	}
+	l.Content = append(l.Content, chunk.Content...)
+	l.Refusal = append(l.Refusal, chunk.Refusal...)
+}
+
+// SequenceLogprob returns the joint log probability of the content tokens.
+func (l *ChatLogprobs) SequenceLogprob() float64 {
+	var sum float64
+	for _, token := range l.Content {
+		sum += token.Logprob
+	}
+	return sum
+}
+
+// SequenceProbability returns the joint probability of the content tokens. It shrinks quickly with length, so
+// compare answers of similar length or use MeanProbability.
+func (l *ChatLogprobs) SequenceProbability() float64 {
+	return math.Exp(l.SequenceLogprob())
+}
+
+// MeanProbability returns the geometric mean of the probabilities of the content tokens, or 0 without tokens.
+func (l *ChatLogprobs) MeanProbability() float64 {
+	if len(l.Content) == 0 {
+		return 0
+	}
+	return math.Exp(l.SequenceLogprob() / float64(len(l.Content)))
+}
+
+// TokenConfidences returns the probability of every content token.
+func (l *ChatLogprobs) TokenConfidences() []TokenConfidence {
+	confidences := make([]TokenConfidence, len(l.Content))
+	for i, token := range l.Content {
+		confidences[i] = TokenConfidence{Token: token.Token, Probability: token.Probability()}
+	}
+	return confidences
+}
+
+// LowConfidenceTokens returns the content tokens whose probability is below threshold, to flag uncertain answers.
+func (l *ChatLogprobs) LowConfidenceTokens(threshold float64) []TokenConfidence {
+	var low []TokenConfidence
+	for _, confidence := range l.TokenConfidences() {
+		if confidence.Probability < threshold {
+			low = append(low, confidence)
+		}
+	}
+	return low
+}
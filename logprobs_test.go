@@ -0,0 +1,58 @@
+package aoai
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestChatLogprobs(t *testing.T) {
+	logprobs := &ChatLogprobs{Content: []ChatTokenLogprob{
+		{Token: "Paris", Logprob: math.Log(0.9)},
+		{Token: ".", Logprob: math.Log(0.4)},
+	}}
+	logprobs.Append(&ChatLogprobs{Content: []ChatTokenLogprob{{Token: "!", Logprob: 0}}})
+	logprobs.Append(nil)
+
+	if got := logprobs.SequenceProbability(); math.Abs(got-0.36) > 1e-9 {
+		t.Errorf("SequenceProbability() = %v, want 0.36", got)
+	}
+	if got := logprobs.MeanProbability(); math.Abs(got-math.Cbrt(0.36)) > 1e-9 {
+		t.Errorf("MeanProbability() = %v, want %v", got, math.Cbrt(0.36))
+	}
+	if got := (&ChatLogprobs{}).MeanProbability(); got != 0 {
+		t.Errorf("MeanProbability() without tokens = %v", got)
+	}
+	low := logprobs.LowConfidenceTokens(0.5)
+	if len(low) != 1 || low[0].Token != "." || math.Abs(low[0].Probability-0.4) > 1e-9 {
+		t.Errorf("LowConfidenceTokens() = %+v", low)
+	}
+}
+
+func TestAzureOpenAI_ChatCompletion_logprobs(t *testing.T) {
+	var sent ChatRequest
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","system_fingerprint":"fp_1","choices":[{"message":{"role":"assistant","content":"Hi"},
+			"logprobs":{"content":[{"token":"Hi","logprob":-0.1,"bytes":[72,105],"top_logprobs":[{"token":"Hi","logprob":-0.1},{"token":"Hello","logprob":-2.4}]}]}}]}`))
+	}))
+
+	seed := 0
+	response, err := a.ChatCompletion(context.Background(), ChatRequest{Logprobs: true, TopLogprobs: 2, Seed: &seed})
+	if err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if !sent.Logprobs || sent.TopLogprobs != 2 || sent.Seed == nil || *sent.Seed != 0 {
+		t.Errorf("ChatCompletion() sent %+v", sent)
+	}
+	want := &ChatLogprobs{Content: []ChatTokenLogprob{{
+		Token: "Hi", Logprob: -0.1, Bytes: []int{72, 105},
+		TopLogprobs: []ChatTokenLogprob{{Token: "Hi", Logprob: -0.1}, {Token: "Hello", Logprob: -2.4}},
+	}}}
+	if response.SystemFingerprint != "fp_1" || !reflect.DeepEqual(response.Choices[0].Logprobs, want) {
+		t.Errorf("ChatCompletion() = %+v", response)
+	}
+}
@@ -0,0 +1,467 @@
+package aoai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Operations passed to middleware as Call.Operation, named after the method of the client.
+const (
+	OperationCompletion           = "Completion"
+	OperationCompletionStream     = "CompletionStream"
+	OperationEmbedding            = "Embedding"
+	OperationChatCompletion       = "ChatCompletion"
+	OperationChatCompletionStream = "ChatCompletionStream"
+
+	OperationCreateResponse       = "CreateResponse"
+	OperationCreateResponseStream = "CreateResponseStream"
+	OperationGetResponse          = "GetResponse"
+	OperationDeleteResponse       = "DeleteResponse"
+	OperationCancelResponse       = "CancelResponse"
+
+	OperationListModels      = "ListModels"
+	OperationGetModel        = "GetModel"
+	OperationListDeployments = "ListDeployments"
+	OperationGetDeployment   = "GetDeployment"
+
+	OperationUploadFile     = "UploadFile"
+	OperationGetFile        = "GetFile"
+	OperationGetFileContent = "GetFileContent"
+	OperationDeleteFile     = "DeleteFile"
+
+	OperationCreateBatch = "CreateBatch"
+	OperationGetBatch    = "GetBatch"
+	OperationListBatches = "ListBatches"
+	OperationCancelBatch = "CancelBatch"
+
+	OperationCreateFineTuningJob          = "CreateFineTuningJob"
+	OperationListFineTuningJobs           = "ListFineTuningJobs"
+	OperationGetFineTuningJob             = "GetFineTuningJob"
+	OperationCancelFineTuningJob          = "CancelFineTuningJob"
+	OperationListFineTuningJobEvents      = "ListFineTuningJobEvents"
+	OperationListFineTuningJobCheckpoints = "ListFineTuningJobCheckpoints"
+
+	OperationCreateAssistant = "CreateAssistant"
+	OperationGetAssistant    = "GetAssistant"
+	OperationUpdateAssistant = "UpdateAssistant"
+	OperationDeleteAssistant = "DeleteAssistant"
+	OperationListAssistants  = "ListAssistants"
+
+	OperationCreateThread  = "CreateThread"
+	OperationGetThread     = "GetThread"
+	OperationUpdateThread  = "UpdateThread"
+	OperationDeleteThread  = "DeleteThread"
+	OperationCreateMessage = "CreateMessage"
+	OperationGetMessage    = "GetMessage"
+	OperationListMessages  = "ListMessages"
+	OperationDeleteMessage = "DeleteMessage"
+
+	OperationCreateRun                = "CreateRun"
+	OperationCreateThreadAndRun       = "CreateThreadAndRun"
+	OperationGetRun                   = "GetRun"
+	OperationListRuns                 = "ListRuns"
+	OperationCancelRun                = "CancelRun"
+	OperationSubmitToolOutputs        = "SubmitToolOutputs"
+	OperationGetRunStep               = "GetRunStep"
+	OperationListRunSteps             = "ListRunSteps"
+	OperationCreateRunStream          = "CreateRunStream"
+	OperationCreateThreadAndRunStream = "CreateThreadAndRunStream"
+	OperationSubmitToolOutputsStream  = "SubmitToolOutputsStream"
+)
+
+// Call is a request passing through the middleware of a client.
+type Call struct {
+	Operation string
+
+	// Request is the typed request, e.g. ChatRequest, or nil for requests without a JSON body. Middleware may
+	// replace it with another value of the same type.
+	Request any
+
+	// Header is sent with the request, so that middleware can add headers.
+	Header http.Header
+
+	// Stream reports whether the response is streamed to Consume instead of being set as Response.
+	Stream bool
+
+	// Consume receives the typed chunks of a stream, e.g. ChatResponse, or a ServerSentEvent for the event streams
+	// of runs and responses. Middleware may wrap it to observe chunks.
+	Consume func(chunk any) error
+
+	// Response is the typed response, e.g. *ChatResponse, once the next handler returned. Middleware that
+	// short-circuits sets it instead of calling the next handler.
+	Response any
+
+	// HTTPRequest and HTTPResponse are the last request sent and the response received, once the next handler
+	// returned. The body of HTTPResponse is already read.
+	HTTPRequest  *http.Request
+	HTTPResponse *http.Response
+
+	// Attempt counts the attempts of the call, starting at 1.
+	Attempt int
+}
+
+// ServerSentEvent is an event of the event streams of runs and responses, passed to Call.Consume.
+type ServerSentEvent struct {
+	Event string
+	Data  []byte
+}
+
+// Handler sends a Call.
+type Handler func(ctx context.Context, call *Call) error
+
+// Middleware wraps a Handler, e.g. to log, measure, retry or mock calls.
+type Middleware func(next Handler) Handler
+
+// WithMiddleware wraps the requests of the client with middleware. The first middleware is the outermost one.
+// Requests are validated before the middleware runs, and every attempt of a completion, chat or embedding request
+// is rate limited after it. Realtime sessions do not pass through middleware.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(a *AzureOpenAI) {
+		a.middleware = append(a.middleware, middleware...)
+	}
+}
+
+func (a *AzureOpenAI) chain(handler Handler) Handler {
+	for i := len(a.middleware) - 1; i >= 0; i-- {
+		handler = a.middleware[i](handler)
+	}
+	return handler
+}
+
+// captureTransport records the request and response of a call for middleware.
+type captureTransport struct {
+	call *Call
+	next http.RoundTripper
+}
+
+func (t captureTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	t.call.HTTPRequest = request
+	response, err := t.next.RoundTrip(request)
+	if err == nil {
+		t.call.HTTPResponse = response
+	}
+	return response, err
+}
+
+// callJson posts request through the middleware and returns the typed response. Every attempt reserves the
+// tokens estimated by estimate from the rate limiter of the client, so that retries are rate limited as well.
+func callJson[S, T any](ctx context.Context, a *AzureOpenAI, operation string, endpoint string, request S, estimate func(*RateLimiter, S) int) (*T, error) {
+	call := &Call{Operation: operation, Request: request, Header: a.header(), Attempt: 1}
+	err := a.chain(func(ctx context.Context, call *Call) error {
+		request, ok := call.Request.(S)
+		if !ok {
+			return fmt.Errorf("%s: middleware replaced the request with %T", call.Operation, call.Request)
+		}
+		reservation, err := a.reserve(ctx, func(l *RateLimiter) int { return estimate(l, request) })
+		if err != nil {
+			return err
+		}
+		response, err := postJsonRequest[S, T](ctx, a.inferenceHTTPClient(call), endpoint, call.Header, request)
+		if err != nil {
+			reservation.Done(0)
+			return err
+		}
+		usage, _ := responseUsage(response)
+		reservation.Done(usage.TotalTokens)
+		call.Response = response
+		return nil
+	})(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	response, ok := call.Response.(*T)
+	if !ok {
+		return nil, fmt.Errorf("%s: middleware returned a response of %T", operation, call.Response)
+	}
+	return response, nil
+}
+
+// callJsonStream posts request through the middleware and passes the typed chunks of the stream to consumer. Like
+// callJson, every attempt reserves capacity of the rate limiter; without usage in the stream, the reservation is
+// kept as estimated.
+func callJsonStream[S, T any](ctx context.Context, a *AzureOpenAI, operation string, endpoint string, request S, estimate func(*RateLimiter, S) int, consumer func(T) error) error {
+	call := &Call{
+		Operation: operation,
+		Request:   request,
+		Header:    a.header(),
+		Stream:    true,
+		Consume: func(chunk any) error {
+			typed, ok := chunk.(T)
+			if !ok {
+				return fmt.Errorf("%s: middleware passed a chunk of %T", operation, chunk)
+			}
+			return consumer(typed)
+		},
+		Attempt: 1,
+	}
+	return a.chain(func(ctx context.Context, call *Call) error {
+		request, ok := call.Request.(S)
+		if !ok {
+			return fmt.Errorf("%s: middleware replaced the request with %T", call.Operation, call.Request)
+		}
+		reservation, err := a.reserve(ctx, func(l *RateLimiter) int { return estimate(l, request) })
+		if err != nil {
+			return err
+		}
+		used := reservation.estimate()
+		err = postJsonRequestStream[S, T](ctx, a.inferenceHTTPClient(call), endpoint, call.Header, request, func(chunk T) error {
+			if usage, ok := responseUsage(chunk); ok && usage.TotalTokens > 0 {
+				used = usage.TotalTokens
+			}
+			return call.Consume(chunk)
+		})
+		reservation.Done(used)
+		return err
+	})(ctx, call)
+}
+
+// callRest sends a request of the REST API other than completions, chat and embeddings through the middleware.
+// send performs one attempt with the request and header of call.
+func callRest[T any](ctx context.Context, a *AzureOpenAI, operation string, request any, send func(ctx context.Context, httpClient *http.Client, call *Call) (*T, error)) (*T, error) {
+	call := &Call{Operation: operation, Request: request, Header: a.header(), Attempt: 1}
+	err := a.chain(func(ctx context.Context, call *Call) error {
+		response, err := send(ctx, a.restHTTPClient(call), call)
+		if err != nil {
+			return err
+		}
+		call.Response = response
+		return nil
+	})(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	response, ok := call.Response.(*T)
+	if !ok {
+		return nil, fmt.Errorf("%s: middleware returned a response of %T", operation, call.Response)
+	}
+	return response, nil
+}
+
+func callGet[T any](ctx context.Context, a *AzureOpenAI, operation string, endpoint string) (*T, error) {
+	return callRest(ctx, a, operation, nil, func(ctx context.Context, httpClient *http.Client, call *Call) (*T, error) {
+		return getJsonRequest[T](ctx, httpClient, endpoint, call.Header)
+	})
+}
+
+func callDelete[T any](ctx context.Context, a *AzureOpenAI, operation string, endpoint string) (*T, error) {
+	return callRest(ctx, a, operation, nil, func(ctx context.Context, httpClient *http.Client, call *Call) (*T, error) {
+		return deleteJsonRequest[T](ctx, httpClient, endpoint, call.Header)
+	})
+}
+
+func callPost[S, T any](ctx context.Context, a *AzureOpenAI, operation string, endpoint string, request S) (*T, error) {
+	return callRest(ctx, a, operation, request, func(ctx context.Context, httpClient *http.Client, call *Call) (*T, error) {
+		request, ok := call.Request.(S)
+		if !ok {
+			return nil, fmt.Errorf("%s: middleware replaced the request with %T", call.Operation, call.Request)
+		}
+		return postJsonRequest[S, T](ctx, httpClient, endpoint, call.Header, request)
+	})
+}
+
+// callEventStream posts request through the middleware and passes the server-sent events of the stream to
+// consumer.
+func callEventStream[S any](ctx context.Context, a *AzureOpenAI, operation string, endpoint string, request S, consumer func(ServerSentEvent) error) error {
+	call := &Call{
+		Operation: operation,
+		Request:   request,
+		Header:    a.header(),
+		Stream:    true,
+		Consume: func(chunk any) error {
+			event, ok := chunk.(ServerSentEvent)
+			if !ok {
+				return fmt.Errorf("%s: middleware passed a chunk of %T", operation, chunk)
+			}
+			return consumer(event)
+		},
+		Attempt: 1,
+	}
+	return a.chain(func(ctx context.Context, call *Call) error {
+		request, ok := call.Request.(S)
+		if !ok {
+			return fmt.Errorf("%s: middleware replaced the request with %T", call.Operation, call.Request)
+		}
+		return postEventStream(ctx, a.restHTTPClient(call), endpoint, call.Header, request, func(event string, data []byte) error {
+			return call.Consume(ServerSentEvent{Event: event, Data: data})
+		})
+	})(ctx, call)
+}
+
+// RetryMiddleware retries calls that fail with 429, 5xx or a transport error up to maxRetries times. It waits
+// for the retry-after of the response, or an exponential backoff starting at backoff without one. Streams are
+// retried only until their first chunk.
+func RetryMiddleware(maxRetries int, backoff time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			delivered := false
+			if call.Stream {
+				consume := call.Consume
+				call.Consume = func(chunk any) error {
+					delivered = true
+					return consume(chunk)
+				}
+			}
+
+			for retry := 0; ; retry++ {
+				err := next(ctx, call)
+				delay, ok := retryDelay(err)
+				if !ok || delivered || retry >= maxRetries || ctx.Err() != nil {
+					return err
+				}
+				if backoff := backoff << retry; delay < backoff {
+					delay = backoff
+				}
+
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return err
+				case <-timer.C:
+				}
+				call.Response, call.HTTPRequest, call.HTTPResponse = nil, nil, nil
+				call.Attempt++
+			}
+		}
+	}
+}
+
+// retryDelay reports whether err is worth retrying, and the retry-after of the response.
+func retryDelay(err error) (time.Duration, bool) {
+	if err == nil || errors.Is(err, ErrCircuitOpen) {
+		return 0, false
+	}
+	var apiError *Error
+	if errors.As(err, &apiError) {
+		return apiError.RetryAfter, apiError.StatusCode == http.StatusTooManyRequests || apiError.StatusCode >= 500
+	}
+	var urlError *url.Error
+	return 0, errors.As(err, &urlError)
+}
+
+// LoggingMiddleware logs the operation, status, attempt and duration of every call to logger, or to the standard
+// logger when it is nil.
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			start := time.Now()
+			err := next(ctx, call)
+			status := "-"
+			if call.HTTPResponse != nil {
+				status = call.HTTPResponse.Status
+			}
+			if err != nil {
+				logger.Printf("aoai: %s %s attempt %d in %v: %v", call.Operation, status, call.Attempt, time.Since(start), err)
+			} else {
+				logger.Printf("aoai: %s %s attempt %d in %v", call.Operation, status, call.Attempt, time.Since(start))
+			}
+			return err
+		}
+	}
+}
+
+// OperationMetrics are the metrics of one operation.
+type OperationMetrics struct {
+	Requests int
+	Errors   int
+
+	// Retries counts the attempts beyond the first one.
+	Retries int
+
+	Duration         time.Duration
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Metrics counts calls, errors, durations and token usage by operation. Streams report usage only when
+// `stream_options.include_usage` is set. It is safe for concurrent use.
+type Metrics struct {
+	mu         sync.Mutex
+	operations map[string]OperationMetrics
+}
+
+// NewMetrics returns empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{operations: map[string]OperationMetrics{}}
+}
+
+// Snapshot returns the metrics so far by operation.
+func (m *Metrics) Snapshot() map[string]OperationMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make(map[string]OperationMetrics, len(m.operations))
+	for operation, metrics := range m.operations {
+		snapshot[operation] = metrics
+	}
+	return snapshot
+}
+
+// Middleware records every call. Place it before RetryMiddleware, so that a call and its retries are recorded once.
+func (m *Metrics) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			var usage Usage
+			if call.Stream {
+				consume := call.Consume
+				call.Consume = func(chunk any) error {
+					if chunkUsage, ok := responseUsage(chunk); ok && chunkUsage.TotalTokens > 0 {
+						usage = chunkUsage
+					}
+					return consume(chunk)
+				}
+			}
+
+			start := time.Now()
+			err := next(ctx, call)
+			if responseUsage, ok := responseUsage(call.Response); ok {
+				usage = responseUsage
+			}
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			metrics := m.operations[call.Operation]
+			metrics.Requests++
+			if err != nil {
+				metrics.Errors++
+			}
+			metrics.Retries += call.Attempt - 1
+			metrics.Duration += time.Since(start)
+			metrics.PromptTokens += usage.PromptTokens
+			metrics.CompletionTokens += usage.CompletionTokens
+			m.operations[call.Operation] = metrics
+			return err
+		}
+	}
+}
+
+// responseUsage returns the usage of a typed response or chunk.
+func responseUsage(response any) (Usage, bool) {
+	switch response := response.(type) {
+	case ChatResponse:
+		return response.Usage, true
+	case CompletionResponse:
+		return response.Usage, true
+	case *ChatResponse:
+		if response != nil {
+			return response.Usage, true
+		}
+	case *CompletionResponse:
+		if response != nil {
+			return response.Usage, true
+		}
+	case *EmbeddingResponse:
+		if response != nil {
+			return response.Usage, true
+		}
+	}
+	return Usage{}, false
+}
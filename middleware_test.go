@@ -0,0 +1,281 @@
+package aoai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithMiddleware(t *testing.T) {
+	var sent ChatRequest
+	var header http.Header
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		_, _ = w.Write([]byte(okChatBody))
+	}))
+
+	var order []string
+	record := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) error {
+				order = append(order, name)
+				return next(ctx, call)
+			}
+		}
+	}
+	inspect := func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			request := call.Request.(ChatRequest)
+			request.MaxTokens = 42
+			call.Request = request
+			call.Header.Set("x-request-id", "r-1")
+
+			err := next(ctx, call)
+			if call.Operation != OperationChatCompletion || call.Attempt != 1 {
+				t.Errorf("call = %+v", call)
+			}
+			if call.HTTPRequest == nil || !strings.HasSuffix(call.HTTPRequest.URL.Path, "/chat/completions") {
+				t.Errorf("HTTPRequest = %+v", call.HTTPRequest)
+			}
+			if call.HTTPResponse == nil || call.HTTPResponse.StatusCode != http.StatusOK {
+				t.Errorf("HTTPResponse = %+v", call.HTTPResponse)
+			}
+			if response := call.Response.(*ChatResponse); response.Choices[0].Message.Content != "Hi" {
+				t.Errorf("Response = %+v", response)
+			}
+			return err
+		}
+	}
+	WithMiddleware(record("outer"), record("inner"), inspect)(a)
+
+	if _, err := a.ChatCompletion(context.Background(), poolChatRequest); err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if strings.Join(order, ",") != "outer,inner" {
+		t.Errorf("middleware ran in order %v", order)
+	}
+	if sent.MaxTokens != 42 || header.Get("x-request-id") != "r-1" || header.Get("api-key") != "dummy" {
+		t.Errorf("sent %+v with header %v", sent, header)
+	}
+}
+
+func TestWithMiddleware_ShortCircuit(t *testing.T) {
+	var calls int
+	a := countingBackend(t, &calls, http.StatusOK, nil, okChatBody)
+	mock := ChatResponse{Choices: []ChatChoice{{Message: ChatMessage{Role: RoleAssistant, Content: "mocked"}}}}
+	WithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			if call.Stream {
+				return call.Consume(mock)
+			}
+			call.Response = &mock
+			return nil
+		}
+	})(a)
+
+	response, err := a.ChatCompletion(context.Background(), poolChatRequest)
+	if err != nil || response.Choices[0].Message.Content != "mocked" {
+		t.Errorf("ChatCompletion() = %+v, %v", response, err)
+	}
+
+	request := poolChatRequest
+	request.Stream = true
+	var chunks []ChatResponse
+	err = a.ChatCompletionStream(context.Background(), request, func(chunk ChatResponse) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil || len(chunks) != 1 || chunks[0].Choices[0].Message.Content != "mocked" {
+		t.Errorf("ChatCompletionStream() = %+v, %v", chunks, err)
+	}
+	if calls != 0 {
+		t.Errorf("server received %d requests", calls)
+	}
+}
+
+func TestWithMiddleware_REST(t *testing.T) {
+	var uploads []string
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/files"):
+			body, _ := io.ReadAll(r.Body)
+			uploads = append(uploads, string(body))
+			if len(uploads) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"error":{"code":"503"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"file-1"}`))
+		case strings.HasSuffix(r.URL.Path, "/content"):
+			_, _ = w.Write([]byte("content"))
+		case strings.HasSuffix(r.URL.Path, "/batches/batch-1"):
+			_, _ = w.Write([]byte(`{"id":"batch-1"}`))
+		case strings.HasSuffix(r.URL.Path, "/runs"):
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(recordedRunStream))
+		}
+	}))
+	metrics := NewMetrics()
+	var events int
+	WithMiddleware(metrics.Middleware(), RetryMiddleware(1, time.Millisecond), func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			if call.Stream {
+				consume := call.Consume
+				call.Consume = func(chunk any) error {
+					if _, ok := chunk.(ServerSentEvent); ok {
+						events++
+					}
+					return consume(chunk)
+				}
+			}
+			return next(ctx, call)
+		}
+	})(a)
+
+	file, err := a.UploadFile(context.Background(), "input.jsonl", "batch", strings.NewReader(`{"custom_id":"1"}`))
+	if err != nil || file.ID != "file-1" {
+		t.Fatalf("UploadFile() = %+v, %v", file, err)
+	}
+	if len(uploads) != 2 || uploads[0] != uploads[1] || !strings.Contains(uploads[1], `{"custom_id":"1"}`) {
+		t.Errorf("uploads = %q, want the same body on retry", uploads)
+	}
+	if content, err := a.GetFileContent(context.Background(), "file-1"); err != nil || string(content) != "content" {
+		t.Errorf("GetFileContent() = %q, %v", content, err)
+	}
+	if batch, err := a.GetBatch(context.Background(), "batch-1"); err != nil || batch.ID != "batch-1" {
+		t.Errorf("GetBatch() = %+v, %v", batch, err)
+	}
+	if _, err := a.CreateRunStream(context.Background(), "thread-1", RunRequest{AssistantID: "asst-1"}, &recordingRunHandler{}); err != nil {
+		t.Errorf("CreateRunStream() error = %v", err)
+	}
+	if events == 0 {
+		t.Error("middleware received no events of the run stream")
+	}
+
+	snapshot := metrics.Snapshot()
+	for operation, retries := range map[string]int{
+		OperationUploadFile:      1,
+		OperationGetFileContent:  0,
+		OperationGetBatch:        0,
+		OperationCreateRunStream: 0,
+	} {
+		if got := snapshot[operation]; got.Requests != 1 || got.Retries != retries || got.Errors != 0 {
+			t.Errorf("metrics of %s = %+v", operation, got)
+		}
+	}
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantErr   bool
+		wantCalls int
+	}{
+		{"recovers", []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}, false, 3},
+		{"gives up", []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, true, 3},
+		{"bad request", []int{http.StatusBadRequest, http.StatusOK}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[calls]
+				calls++
+				w.WriteHeader(status)
+				if status == http.StatusOK {
+					_, _ = w.Write([]byte(okChatBody))
+				} else {
+					_, _ = w.Write([]byte(`{"error":{"code":"error"}}`))
+				}
+			}))
+			metrics := NewMetrics()
+			WithMiddleware(metrics.Middleware(), RetryMiddleware(2, time.Millisecond))(a)
+
+			_, err := a.ChatCompletion(context.Background(), poolChatRequest)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ChatCompletion() error = %v", err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("server received %d requests, want %d", calls, tt.wantCalls)
+			}
+			got := metrics.Snapshot()[OperationChatCompletion]
+			if got.Requests != 1 || got.Retries != tt.wantCalls-1 || (got.Errors == 1) != tt.wantErr {
+				t.Errorf("metrics = %+v", got)
+			}
+		})
+	}
+}
+
+func TestRetryMiddleware_RateLimiter(t *testing.T) {
+	var calls int
+	a := countingBackend(t, &calls, http.StatusServiceUnavailable, nil, `{"error":{"code":"503"}}`)
+	WithRateLimiter(newFrozenRateLimiter(0, 2))(a)
+	WithMiddleware(RetryMiddleware(3, time.Millisecond))(a)
+
+	// every attempt takes a request from the quota, so the third one waits until the context expires
+	_, err := a.ChatCompletion(shortContext(t), poolChatRequest)
+	if !errors.Is(err, context.DeadlineExceeded) || calls != 2 {
+		t.Errorf("ChatCompletion() error = %v after %d requests, want the third attempt to wait for the limiter", err, calls)
+	}
+}
+
+func TestRetryMiddleware_Stream(t *testing.T) {
+	var calls int
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(recordedChatStream))
+	}))
+	WithMiddleware(RetryMiddleware(2, time.Millisecond))(a)
+
+	request := poolChatRequest
+	request.Stream = true
+	// a retryable error after the first chunk is returned as is
+	unavailable := &Error{StatusCode: http.StatusServiceUnavailable}
+	err := a.ChatCompletionStream(context.Background(), request, func(ChatResponse) error { return unavailable })
+	if !errors.Is(err, unavailable) || calls != 1 {
+		t.Errorf("ChatCompletionStream() error = %v after %d requests, want no retry after the first chunk", err, calls)
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	var calls int
+	a := countingBackend(t, &calls, http.StatusOK, nil, okChatBody)
+	var buf bytes.Buffer
+	WithMiddleware(LoggingMiddleware(log.New(&buf, "", 0)))(a)
+
+	if _, err := a.ChatCompletion(context.Background(), poolChatRequest); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, "aoai: ChatCompletion 200 OK attempt 1 in ") {
+		t.Errorf("logged %q", got)
+	}
+}
+
+func TestMetrics_Stream(t *testing.T) {
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(recordedChatStream))
+	}))
+	metrics := NewMetrics()
+	WithMiddleware(metrics.Middleware())(a)
+
+	request := poolChatRequest
+	request.Stream = true
+	if _, err := a.ChatCompletionStreamAccumulate(context.Background(), request, func(ChatResponse) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	got := metrics.Snapshot()[OperationChatCompletionStream]
+	if got.Requests != 1 || got.PromptTokens != 9 || got.CompletionTokens != 3 {
+		t.Errorf("metrics = %+v", got)
+	}
+}
@@ -1,6 +1,9 @@
 package aoai
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 type CompletionRequest struct {
 	// prompt:
@@ -19,7 +22,10 @@ type CompletionRequest struct {
 	//         example: This is a test.
 	//         nullable: false
 	//       description: Array size minimum of 1 and maximum of 2048
-	Prompts []string `json:"prompt"`
+	Prompts StringOrSlice `json:"prompt"`
+
+	// PromptTokens sends pre-tokenized prompts, marshaled as `prompt` instead of Prompts.
+	PromptTokens TokenSlices `json:"-"`
 
 	// max_tokens:
 	//   description: The token count of your prompt plus max_tokens cannot exceed the model's context length. Most models have a context length of 2048 tokens (except for the newest models, which support 4096). Has minimum of 0.
@@ -123,7 +129,7 @@ type CompletionRequest struct {
 	//
 	//         nullable: false
 	//       description: Array minimum size of 1 and maximum of 4
-	Stop []string `json:"stop,omitempty"`
+	Stop StringOrSlice `json:"stop,omitempty"`
 
 	// completion_config:
 	//   type: string
@@ -155,6 +161,10 @@ type CompletionRequest struct {
 	//     Note: Because this parameter generates many completions, it can quickly consume your token quota. Use carefully and ensure that you have reasonable settings for max_tokens and stop. Has maximum value of 128.
 	//   type: integer
 	BestOf int `json:"best_of,omitempty"`
+
+	// ExtraBody holds parameters this package does not model yet. They are merged into the request JSON and take
+	// precedence over fields with the same name.
+	ExtraBody map[string]any `json:"-"`
 }
 
 type CompletionResponse struct {
@@ -179,6 +189,16 @@ type CompletionResponse struct {
 	//    items:
 	//      type: CompletionChoice
 	Choices []CompletionChoice `json:"choices,omitempty"`
+
+	//  usage:
+	//    type: Usage
+	Usage Usage `json:"usage,omitempty"`
+
+	// Raw is the response as received.
+	Raw json.RawMessage `json:"-"`
+
+	// ExtraFields holds the keys of the response this package does not model yet.
+	ExtraFields map[string]json.RawMessage `json:"-"`
 }
 
 type CompletionChoice struct {
@@ -197,6 +217,9 @@ type CompletionChoice struct {
 	// finish_reason:
 	//   type: string
 	FinishReason string `json:"finish_reason,omitempty"`
+
+	// ExtraFields holds the keys of the choice this package does not model yet.
+	ExtraFields map[string]json.RawMessage `json:"-"`
 }
 
 type Logprobs struct {
@@ -247,7 +270,10 @@ type EmbeddingRequest struct {
 	//         minLength: 1
 	//         example: This is a test.
 	//         nullable: false
-	Inputs []string `json:"input,omitempty"`
+	Inputs StringOrSlice `json:"input,omitempty"`
+
+	// InputTokens sends pre-tokenized inputs, marshaled as `input` instead of Inputs.
+	InputTokens TokenSlices `json:"-"`
 
 	// user:
 	//   description: A unique identifier representing your end-user, which can help monitoring and detecting abuse.
@@ -270,6 +296,10 @@ type EmbeddingRequest struct {
 	Model string `json:"model,omitempty"`
 
 	AdditionalProp1 map[string]interface{} `json:"additionalProp1,omitempty"`
+
+	// ExtraBody holds parameters this package does not model yet. They are merged into the request JSON and take
+	// precedence over fields with the same name.
+	ExtraBody map[string]any `json:"-"`
 }
 
 type EmbeddingResponse struct {
@@ -288,6 +318,12 @@ type EmbeddingResponse struct {
 	// usage:
 	//   type: Usage
 	Usage Usage `json:"usage,omitempty"`
+
+	// Raw is the response as received.
+	Raw json.RawMessage `json:"-"`
+
+	// ExtraFields holds the keys of the response this package does not model yet.
+	ExtraFields map[string]json.RawMessage `json:"-"`
 }
 
 type EmbeddingData struct {
@@ -318,6 +354,29 @@ type Usage struct {
 	// total_tokens:
 	//   type: integer
 	TotalTokens int `json:"total_tokens,omitempty"`
+
+	// prompt_tokens_details:
+	//   description: Breakdown of the prompt tokens, e.g. tokens served from the prompt cache.
+	//   type: PromptTokensDetails
+	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+
+	// completion_tokens_details:
+	//   description: Breakdown of the completion tokens, e.g. hidden reasoning tokens of reasoning models.
+	//   type: CompletionTokensDetails
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+}
+
+type PromptTokensDetails struct {
+	// cached_tokens:
+	//   type: integer
+	CachedTokens int `json:"cached_tokens,omitempty"`
+}
+
+type CompletionTokensDetails struct {
+	// reasoning_tokens:
+	//   description: Tokens the model used to reason. They are billed as completion tokens but not returned.
+	//   type: integer
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
 }
 
 type ChatRequest struct {
@@ -375,6 +434,12 @@ type ChatRequest struct {
 	//   default: false
 	Stream bool `json:"stream,omitempty"`
 
+	// stream_options:
+	//   description: Options for streaming responses. Only set this when `stream` is true.
+	//   type: StreamOptions
+	//   nullable: true
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+
 	// stop:
 	//   description: Up to 4 sequences where the API will stop generating further tokens.
 	//   oneOf:
@@ -388,7 +453,7 @@ type ChatRequest struct {
 	//       maxItems: 4
 	//       description: Array minimum size of 1 and maximum of 4
 	//   default: null
-	Stop []string `json:"stop,omitempty"`
+	Stop StringOrSlice `json:"stop,omitempty"`
 
 	// max_tokens:
 	//   description:
@@ -398,6 +463,49 @@ type ChatRequest struct {
 	//   default: inf
 	MaxTokens int `json:"max_tokens,omitempty"`
 
+	// max_completion_tokens:
+	//   description:
+	//  	An upper bound for the number of tokens that can be generated, including visible output tokens and
+	// 		reasoning tokens. Reasoning deployments reject `max_tokens` and require this instead.
+	//   type: integer
+	//   nullable: true
+	MaxCompletionTokens int `json:"max_completion_tokens,omitempty"`
+
+	// reasoning_effort:
+	//   description: Constrains the effort on reasoning for reasoning models.
+	//   type: string
+	//   enum:
+	//     - low
+	//     - medium
+	//     - high
+	//   nullable: true
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+
+	// logprobs:
+	//   description: Whether to return log probabilities of the output tokens in the `content` of `message`.
+	//   type: boolean
+	//   default: false
+	//   nullable: true
+	Logprobs bool `json:"logprobs,omitempty"`
+
+	// top_logprobs:
+	//   description:
+	//  	An integer between 0 and 20 specifying the number of most likely tokens to return at each token position.
+	// 		`logprobs` must be set to true if this parameter is used.
+	//   type: integer
+	//   minimum: 0
+	//   maximum: 20
+	//   nullable: true
+	TopLogprobs int `json:"top_logprobs,omitempty"`
+
+	// seed:
+	//   description:
+	//  	If specified, the system will make a best effort to sample deterministically. Determinism is not guaranteed,
+	// 		and `system_fingerprint` should be compared to monitor changes in the backend.
+	//   type: integer
+	//   nullable: true
+	Seed *int `json:"seed,omitempty"`
+
 	// presence_penalty:
 	//   description:
 	//  	Number between -2.0 and 2.0. Positive values penalize new tokens based on whether they appear in the text
@@ -428,12 +536,39 @@ type ChatRequest struct {
 	//   nullable: true
 	LogitBias map[string]float64 `json:"logit_bias,omitempty"`
 
+	// tools:
+	//   description: A list of tools the model may call. Currently, only functions are supported as a tool.
+	//   type: []Tool
+	//   nullable: true
+	Tools []Tool `json:"tools,omitempty"`
+
 	// user:
 	//   description: A unique identifier representing your end-user, which can help Azure OpenAI to monitor and detect abuse.
 	//   type: string
 	//   example: user-1234
 	//   nullable: false
 	User string `json:"user,omitempty"`
+
+	// model:
+	//   type: string
+	//   description:
+	//  	Name of the deployment to use. Only required for requests submitted through the Batch API, where the
+	// 		deployment is not part of the request URL.
+	//   nullable: true
+	Model string `json:"model,omitempty"`
+
+	// ExtraBody holds parameters this package does not model yet. They are merged into the request JSON and take
+	// precedence over fields with the same name.
+	ExtraBody map[string]any `json:"-"`
+}
+
+type StreamOptions struct {
+	// include_usage:
+	//   description:
+	//  	If set, an additional chunk is streamed before `data: [DONE]`. Its `usage` field holds the token usage of
+	// 		the entire request and its `choices` field is always empty.
+	//   type: boolean
+	IncludeUsage bool `json:"include_usage,omitempty"`
 }
 
 type ChatResponse struct {
@@ -454,6 +589,11 @@ type ChatResponse struct {
 	//   type: string
 	Model string `json:"model,omitempty"`
 
+	// system_fingerprint:
+	//   description: The backend configuration the model runs with, to be used together with `seed`.
+	//   type: string
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+
 	// choices:
 	//   type: []ChatChoice
 	Choices []ChatChoice `json:"choices,omitempty"`
@@ -461,6 +601,12 @@ type ChatResponse struct {
 	// usage:
 	//   type: Usage
 	Usage Usage `json:"usage,omitempty"`
+
+	// Raw is the response as received.
+	Raw json.RawMessage `json:"-"`
+
+	// ExtraFields holds the keys of the response this package does not model yet.
+	ExtraFields map[string]json.RawMessage `json:"-"`
 }
 
 // ChatChoice
@@ -495,9 +641,18 @@ type ChatChoice struct {
 	//   type: ChatMessage
 	Delta ChatMessage `json:"delta,omitempty"`
 
+	// logprobs:
+	//   description: Log probabilities of the tokens of `message`, or of `delta` in a chunk.
+	//   type: ChatLogprobs
+	//   nullable: true
+	Logprobs *ChatLogprobs `json:"logprobs,omitempty"`
+
 	// finish_reason:
 	//   type: string
 	FinishReason string `json:"finish_reason,omitempty"`
+
+	// ExtraFields holds the keys of the choice this package does not model yet.
+	ExtraFields map[string]json.RawMessage `json:"-"`
 }
 
 type ChatMessage struct {
@@ -505,8 +660,10 @@ type ChatMessage struct {
 	//   type: string
 	//   enum:
 	//     - system
+	//     - developer
 	//     - user
 	//     - assistant
+	//     - tool
 	//   description: The role of the author of this message.
 	Role string `json:"role,omitempty"`
 
@@ -519,6 +676,54 @@ type ChatMessage struct {
 	//   type: string
 	//   description: The name of the user in a multi-user chat
 	Name string `json:"name,omitempty"`
+
+	// ContentParts holds the parts of a multi-modal message, such as text and images. It is marshaled as `content`
+	// instead of Content when set.
+	ContentParts []ChatContentPart `json:"-"`
+
+	// tool_calls:
+	//   description: The tool calls generated by the model, such as function calls.
+	//   type: []ToolCall
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// tool_call_id:
+	//   description: Tool call that this message is responding to. Required for messages with the `tool` role.
+	//   type: string
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+type ChatContentPart struct {
+	// type:
+	//   type: string
+	//   enum:
+	//     - text
+	//     - image_url
+	Type string `json:"type"`
+
+	// text:
+	//   type: string
+	Text string `json:"text,omitempty"`
+
+	// image_url:
+	//   type: ChatImageURL
+	ImageURL *ChatImageURL `json:"image_url,omitempty"`
+}
+
+type ChatImageURL struct {
+	// url:
+	//   description: Either a URL of the image or the base64 encoded image data as a `data:` URL.
+	//   type: string
+	URL string `json:"url"`
+
+	// detail:
+	//   description: Specifies the detail level of the image.
+	//   type: string
+	//   enum:
+	//     - auto
+	//     - low
+	//     - high
+	//   default: auto
+	Detail string `json:"detail,omitempty"`
 }
 
 type Error struct {
@@ -526,6 +731,12 @@ type Error struct {
 	Message string `json:"message"`
 	Param   string `json:"param"`
 	Type    string `json:"type"`
+
+	// StatusCode is the HTTP status of the response.
+	StatusCode int `json:"-"`
+
+	// RetryAfter is the delay requested by the `retry-after-ms` or `retry-after` header, or 0 without them.
+	RetryAfter time.Duration `json:"-"`
 }
 
 func (e *Error) Error() string {
@@ -0,0 +1,217 @@
+package aoai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// StringOrSlice is a `oneOf: string | array of strings` field. A single value is marshaled as a plain string,
+// and both forms are accepted when unmarshaling.
+type StringOrSlice []string
+
+func (s StringOrSlice) MarshalJSON() ([]byte, error) {
+	if s == nil {
+		return []byte("null"), nil
+	}
+	if len(s) == 1 {
+		return json.Marshal(s[0])
+	}
+	return json.Marshal([]string(s))
+}
+
+func (s *StringOrSlice) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*s = nil
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*s = StringOrSlice{value}
+		return nil
+	}
+
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*s = values
+	return nil
+}
+
+// TokenSlices is a `oneOf: array of integers | array of arrays of integers` field holding pre-tokenized inputs.
+// A single input is marshaled as a flat token array.
+type TokenSlices [][]int
+
+func (t TokenSlices) MarshalJSON() ([]byte, error) {
+	if t == nil {
+		return []byte("null"), nil
+	}
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([][]int(t))
+}
+
+func (t *TokenSlices) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*t = nil
+		return nil
+	}
+
+	var tokens []int
+	if err := json.Unmarshal(data, &tokens); err == nil {
+		if len(tokens) == 0 {
+			*t = TokenSlices{}
+		} else {
+			*t = TokenSlices{tokens}
+		}
+		return nil
+	}
+
+	var slices [][]int
+	if err := json.Unmarshal(data, &slices); err != nil {
+		return err
+	}
+	*t = slices
+	return nil
+}
+
+// decodeTextOrTokens decodes a field that holds either strings or token arrays.
+func decodeTextOrTokens(data json.RawMessage) (StringOrSlice, TokenSlices, error) {
+	if len(data) == 0 {
+		return nil, nil, nil
+	}
+
+	var text StringOrSlice
+	textErr := json.Unmarshal(data, &text)
+	if textErr == nil {
+		return text, nil, nil
+	}
+
+	var tokens TokenSlices
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, nil, textErr
+	}
+	return nil, tokens, nil
+}
+
+func (r CompletionRequest) MarshalJSON() ([]byte, error) {
+	type alias CompletionRequest
+	if r.PromptTokens != nil && r.Prompts != nil {
+		return nil, fmt.Errorf("prompt and prompt tokens must not be set together")
+	}
+
+	var data []byte
+	var err error
+	if r.PromptTokens == nil {
+		data, err = json.Marshal(alias(r))
+	} else {
+		data, err = json.Marshal(struct {
+			alias
+			Prompt TokenSlices `json:"prompt"`
+		}{alias(r), r.PromptTokens})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mergeExtraBody(data, r.ExtraBody)
+}
+
+func (r *CompletionRequest) UnmarshalJSON(data []byte) error {
+	type alias CompletionRequest
+	raw := struct {
+		*alias
+		Prompt json.RawMessage `json:"prompt"`
+	}{alias: (*alias)(r)}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var err error
+	if r.Prompts, r.PromptTokens, err = decodeTextOrTokens(raw.Prompt); err != nil {
+		return err
+	}
+	r.ExtraBody, err = extraBody(data, reflect.TypeOf(*r))
+	return err
+}
+
+func (r EmbeddingRequest) MarshalJSON() ([]byte, error) {
+	type alias EmbeddingRequest
+	if r.InputTokens != nil && r.Inputs != nil {
+		return nil, fmt.Errorf("input and input tokens must not be set together")
+	}
+
+	var data []byte
+	var err error
+	if r.InputTokens == nil {
+		data, err = json.Marshal(alias(r))
+	} else {
+		data, err = json.Marshal(struct {
+			alias
+			Input TokenSlices `json:"input"`
+		}{alias(r), r.InputTokens})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mergeExtraBody(data, r.ExtraBody)
+}
+
+func (r *EmbeddingRequest) UnmarshalJSON(data []byte) error {
+	type alias EmbeddingRequest
+	raw := struct {
+		*alias
+		Input json.RawMessage `json:"input"`
+	}{alias: (*alias)(r)}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var err error
+	if r.Inputs, r.InputTokens, err = decodeTextOrTokens(raw.Input); err != nil {
+		return err
+	}
+	r.ExtraBody, err = extraBody(data, reflect.TypeOf(*r))
+	return err
+}
+
+func (m ChatMessage) MarshalJSON() ([]byte, error) {
+	type alias ChatMessage
+	if m.ContentParts == nil {
+		return json.Marshal(alias(m))
+	}
+	if m.Content != "" {
+		return nil, fmt.Errorf("content and content parts must not be set together")
+	}
+	return json.Marshal(struct {
+		alias
+		Content []ChatContentPart `json:"content"`
+	}{alias(m), m.ContentParts})
+}
+
+func (m *ChatMessage) UnmarshalJSON(data []byte) error {
+	type alias ChatMessage
+	raw := struct {
+		*alias
+		Content json.RawMessage `json:"content"`
+	}{alias: (*alias)(m)}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	content := bytes.TrimSpace(raw.Content)
+	if len(content) > 0 && content[0] == '[' {
+		return json.Unmarshal(content, &m.ContentParts)
+	}
+	if len(content) > 0 {
+		return json.Unmarshal(content, &m.Content)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package aoai
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStringOrSlice(t *testing.T) {
+	tests := []struct {
+		name  string
+		value StringOrSlice
+		json  string
+	}{
+		{name: "nil", value: nil, json: `null`},
+		{name: "single", value: StringOrSlice{"a"}, json: `"a"`},
+		{name: "multiple", value: StringOrSlice{"a", "b"}, json: `["a","b"]`},
+		{name: "empty", value: StringOrSlice{}, json: `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.value)
+			if err != nil || string(got) != tt.json {
+				t.Errorf("Marshal() = %s, %v, want %s", got, err, tt.json)
+			}
+			var value StringOrSlice
+			if err := json.Unmarshal([]byte(tt.json), &value); err != nil || !reflect.DeepEqual(value, tt.value) {
+				t.Errorf("Unmarshal() = %#v, %v, want %#v", value, err, tt.value)
+			}
+		})
+	}
+
+	var value StringOrSlice
+	if err := json.Unmarshal([]byte(`["a"]`), &value); err != nil || !reflect.DeepEqual(value, StringOrSlice{"a"}) {
+		t.Errorf("Unmarshal() = %#v, %v", value, err)
+	}
+	if err := json.Unmarshal([]byte(`[1]`), &value); err == nil {
+		t.Errorf("Unmarshal() accepted a token array")
+	}
+}
+
+func TestTokenSlices(t *testing.T) {
+	tests := []struct {
+		name  string
+		value TokenSlices
+		json  string
+	}{
+		{name: "nil", value: nil, json: `null`},
+		{name: "single", value: TokenSlices{{1, 2}}, json: `[1,2]`},
+		{name: "multiple", value: TokenSlices{{1}, {2, 3}}, json: `[[1],[2,3]]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.value)
+			if err != nil || string(got) != tt.json {
+				t.Errorf("Marshal() = %s, %v, want %s", got, err, tt.json)
+			}
+			var value TokenSlices
+			if err := json.Unmarshal([]byte(tt.json), &value); err != nil || !reflect.DeepEqual(value, tt.value) {
+				t.Errorf("Unmarshal() = %#v, %v, want %#v", value, err, tt.value)
+			}
+		})
+	}
+}
+
+func TestCompletionRequest_JSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		request CompletionRequest
+		json    string
+	}{
+		{name: "singlePrompt", request: CompletionRequest{Prompts: []string{"Hi"}, Stop: []string{"\n"}}, json: `{"prompt":"Hi","stop":"\n"}`},
+		{name: "prompts", request: CompletionRequest{Prompts: []string{"Hi", "Yo"}}, json: `{"prompt":["Hi","Yo"]}`},
+		{name: "promptTokens", request: CompletionRequest{PromptTokens: TokenSlices{{9906, 0}}}, json: `{"prompt":[9906,0]}`},
+		{name: "batchedPromptTokens", request: CompletionRequest{PromptTokens: TokenSlices{{1}, {2}}, MaxTokens: 5}, json: `{"prompt":[[1],[2]],"max_tokens":5}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.request)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var gotMap, wantMap map[string]any
+			_ = json.Unmarshal(got, &gotMap)
+			_ = json.Unmarshal([]byte(tt.json), &wantMap)
+			for key, want := range wantMap {
+				if !reflect.DeepEqual(gotMap[key], want) {
+					t.Errorf("Marshal() %s = %v, want %v", key, gotMap[key], want)
+				}
+			}
+
+			var request CompletionRequest
+			if err := json.Unmarshal([]byte(tt.json), &request); err != nil || !reflect.DeepEqual(request, tt.request) {
+				t.Errorf("Unmarshal() = %+v, %v, want %+v", request, err, tt.request)
+			}
+		})
+	}
+
+	if _, err := json.Marshal(CompletionRequest{Prompts: []string{"Hi"}, PromptTokens: TokenSlices{{1}}}); err == nil {
+		t.Errorf("Marshal() accepted both prompt forms")
+	}
+}
+
+func TestEmbeddingRequest_JSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		request EmbeddingRequest
+		json    string
+	}{
+		{name: "singleInput", request: EmbeddingRequest{Inputs: []string{"Hi"}}, json: `{"input":"Hi"}`},
+		{name: "inputTokens", request: EmbeddingRequest{InputTokens: TokenSlices{{1, 2}, {3}}, User: "u"}, json: `{"user":"u","input":[[1,2],[3]]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.request)
+			if err != nil || string(got) != tt.json {
+				t.Errorf("Marshal() = %s, %v, want %s", got, err, tt.json)
+			}
+			var request EmbeddingRequest
+			if err := json.Unmarshal([]byte(tt.json), &request); err != nil || !reflect.DeepEqual(request, tt.request) {
+				t.Errorf("Unmarshal() = %+v, %v, want %+v", request, err, tt.request)
+			}
+		})
+	}
+}
+
+func TestChatMessage_JSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		message ChatMessage
+		json    string
+	}{
+		{name: "text", message: ChatMessage{Role: RoleUser, Content: "Hi"}, json: `{"role":"user","content":"Hi"}`},
+		{
+			name: "parts",
+			message: ChatMessage{Role: RoleUser, ContentParts: []ChatContentPart{
+				{Type: "text", Text: "What is this?"},
+				{Type: "image_url", ImageURL: &ChatImageURL{URL: "https://example.com/a.png", Detail: "low"}},
+			}},
+			json: `{"role":"user","content":[{"type":"text","text":"What is this?"},{"type":"image_url","image_url":{"url":"https://example.com/a.png","detail":"low"}}]}`,
+		},
+		{
+			name: "toolCalls",
+			message: ChatMessage{Role: RoleAssistant, ToolCalls: []ToolCall{
+				{ID: "call_1", Type: "function", Function: FunctionCall{Name: "f", Arguments: `{}`}},
+			}},
+			json: `{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"f","arguments":"{}"}}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.message)
+			if err != nil || string(got) != tt.json {
+				t.Errorf("Marshal() = %s, %v, want %s", got, err, tt.json)
+			}
+			var message ChatMessage
+			if err := json.Unmarshal([]byte(tt.json), &message); err != nil || !reflect.DeepEqual(message, tt.message) {
+				t.Errorf("Unmarshal() = %+v, %v, want %+v", message, err, tt.message)
+			}
+		})
+	}
+
+	var message ChatMessage
+	if err := json.Unmarshal([]byte(`{"role":"assistant","content":null}`), &message); err != nil || message.Content != "" {
+		t.Errorf("Unmarshal() = %+v, %v", message, err)
+	}
+	if _, err := json.Marshal(ChatMessage{Content: "Hi", ContentParts: []ChatContentPart{{Type: "text"}}}); err == nil {
+		t.Errorf("Marshal() error = nil")
+	}
+}
@@ -0,0 +1,74 @@
+package aoai
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// ListResponse is the cursor based list envelope shared by management APIs such as batches.
+type ListResponse[T any] struct {
+	// object:
+	//   type: string
+	//   enum:
+	//     - list
+	Object string `json:"object,omitempty"`
+
+	// data:
+	//   type: array
+	Data []T `json:"data,omitempty"`
+
+	// first_id:
+	//   type: string
+	FirstID string `json:"first_id,omitempty"`
+
+	// last_id:
+	//   type: string
+	LastID string `json:"last_id,omitempty"`
+
+	// has_more:
+	//   type: boolean
+	HasMore bool `json:"has_more,omitempty"`
+}
+
+type ListOptions struct {
+	// after:
+	//   description: Identifier for the last item from the previous pagination request.
+	//   type: string
+	After string
+
+	// before:
+	//   description: Identifier for the first item from the previous pagination request.
+	//   type: string
+	Before string
+
+	// limit:
+	//   description: Number of items to retrieve. The service default is used when zero.
+	//   type: integer
+	Limit int
+
+	// order:
+	//   description: Sort order by the created_at timestamp of the items.
+	//   type: string
+	//   enum:
+	//     - asc
+	//     - desc
+	Order string
+}
+
+func (o ListOptions) values(apiVersion string) url.Values {
+	values := url.Values{}
+	values.Set("api-version", apiVersion)
+	if o.After != "" {
+		values.Set("after", o.After)
+	}
+	if o.Before != "" {
+		values.Set("before", o.Before)
+	}
+	if o.Limit > 0 {
+		values.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Order != "" {
+		values.Set("order", o.Order)
+	}
+	return values
+}
@@ -0,0 +1,57 @@
+package aoai
+
+import (
+	"context"
+	"time"
+)
+
+// PollOptions configures how long-running operations such as batches are polled.
+// The interval starts at Interval and grows by Multiplier after every attempt, up to MaxInterval.
+type PollOptions struct {
+	Interval    time.Duration
+	MaxInterval time.Duration
+	Multiplier  float64
+}
+
+var DefaultPollOptions = PollOptions{
+	Interval:    5 * time.Second,
+	MaxInterval: time.Minute,
+	Multiplier:  1.5,
+}
+
+func (o PollOptions) next(interval time.Duration) time.Duration {
+	if o.Multiplier > 1 {
+		interval = time.Duration(float64(interval) * o.Multiplier)
+	}
+	if o.MaxInterval > 0 && interval > o.MaxInterval {
+		interval = o.MaxInterval
+	}
+	return interval
+}
+
+// poll calls get until done reports a terminal state or ctx is cancelled.
+func poll[T any](ctx context.Context, options PollOptions, get func(ctx context.Context) (*T, error), done func(*T) bool) (*T, error) {
+	if options.Interval <= 0 {
+		options = DefaultPollOptions
+	}
+
+	interval := options.Interval
+	for {
+		current, err := get(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if done(current) {
+			return current, nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return current, ctx.Err()
+		case <-timer.C:
+		}
+		interval = options.next(interval)
+	}
+}
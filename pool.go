@@ -0,0 +1,314 @@
+package aoai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PoolStrategy decides the order in which a Pool tries its backends.
+type PoolStrategy int
+
+const (
+	// PriorityStrategy tries backends by ascending Priority and spills over to the next priority when a backend
+	// is unavailable, e.g. from a provisioned deployment to pay-as-you-go ones. Backends of equal priority take
+	// turns.
+	PriorityStrategy PoolStrategy = iota
+
+	// RoundRobinStrategy starts every request at the next backend.
+	RoundRobinStrategy
+
+	// LeastLoadedStrategy prefers the backends with the fewest requests in flight.
+	LeastLoadedStrategy
+
+	// WeightedStrategy picks backends at random in proportion to their Weight.
+	WeightedStrategy
+)
+
+// DefaultPoolCooldown is how long a backend is removed after a failure that does not come with a retry-after.
+const DefaultPoolCooldown = 10 * time.Second
+
+// ErrNoBackendAvailable is returned by a Pool when every backend is removed.
+var ErrNoBackendAvailable = errors.New("no backend available")
+
+// Backend is a deployment served by a Pool.
+type Backend struct {
+	Client *AzureOpenAI
+
+	// Name identifies the backend in errors. The resource and deployment name are used when it is empty.
+	Name string
+
+	// Priority orders backends for PriorityStrategy; lower values are tried first.
+	Priority int
+
+	// Weight is the share of requests for WeightedStrategy. Backends with a weight below 1 count as 1.
+	Weight int
+}
+
+type poolBackend struct {
+	Backend
+	inFlight         int
+	unavailableUntil time.Time
+}
+
+// Pool implements the request methods of AzureOpenAI over several backends, e.g. the same model deployed in
+// different regions. A backend that answers with 429 or 5xx, or cannot be reached, is removed for its
+// retry-after, or DefaultPoolCooldown without one, and the request is retried on the next backend. Streams fail
+// over only until their first chunk. Backends whose client has an open CircuitBreaker are skipped. It is safe for
+// concurrent use.
+type Pool struct {
+	strategy PoolStrategy
+
+	mu       sync.Mutex
+	backends []*poolBackend
+	next     int
+	random   *rand.Rand
+	now      func() time.Time
+}
+
+// NewPool returns a Pool that orders backends by strategy.
+func NewPool(strategy PoolStrategy, backends ...Backend) *Pool {
+	p := &Pool{
+		strategy: strategy,
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:      time.Now,
+	}
+	for _, backend := range backends {
+		if backend.Name == "" {
+			backend.Name = backend.Client.resourceName + "/" + backend.Client.deploymentName
+		}
+		p.backends = append(p.backends, &poolBackend{Backend: backend})
+	}
+	return p
+}
+
+// Available returns the names of the backends that are not removed and whose circuit is not open.
+func (p *Pool) Available() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var names []string
+	for _, backend := range p.available(p.now()) {
+		names = append(names, backend.Name)
+	}
+	return names
+}
+
+func (p *Pool) available(now time.Time) []*poolBackend {
+	var available []*poolBackend
+	for _, backend := range p.backends {
+		if !now.Before(backend.unavailableUntil) && backend.Client.CircuitState() != CircuitOpen {
+			available = append(available, backend)
+		}
+	}
+	return available
+}
+
+// order returns the available backends in the order to try them.
+func (p *Pool) order() []*poolBackend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	backends := p.available(p.now())
+	if len(backends) == 0 {
+		return nil
+	}
+
+	switch p.strategy {
+	case PriorityStrategy:
+		backends = p.rotate(backends)
+		sort.SliceStable(backends, func(i, j int) bool { return backends[i].Priority < backends[j].Priority })
+	case RoundRobinStrategy:
+		backends = p.rotate(backends)
+	case LeastLoadedStrategy:
+		backends = p.rotate(backends)
+		sort.SliceStable(backends, func(i, j int) bool { return backends[i].inFlight < backends[j].inFlight })
+	case WeightedStrategy:
+		backends = p.shuffleByWeight(backends)
+	}
+	return backends
+}
+
+// rotate returns backends starting at the next one in turn.
+func (p *Pool) rotate(backends []*poolBackend) []*poolBackend {
+	start := p.next % len(backends)
+	p.next++
+	return append(append([]*poolBackend{}, backends[start:]...), backends[:start]...)
+}
+
+// shuffleByWeight returns backends in a random order where heavier backends tend to come first.
+func (p *Pool) shuffleByWeight(backends []*poolBackend) []*poolBackend {
+	remaining := append([]*poolBackend{}, backends...)
+	ordered := make([]*poolBackend, 0, len(backends))
+	for len(remaining) > 0 {
+		total := 0
+		for _, backend := range remaining {
+			total += weight(backend)
+		}
+		pick := p.random.Intn(total)
+		for i, backend := range remaining {
+			if pick -= weight(backend); pick < 0 {
+				ordered = append(ordered, backend)
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+	return ordered
+}
+
+func weight(backend *poolBackend) int {
+	if backend.Weight < 1 {
+		return 1
+	}
+	return backend.Weight
+}
+
+func (p *Pool) start(backend *poolBackend) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	backend.inFlight++
+}
+
+// finish records the outcome of a request and reports whether it should be retried on another backend. A stream
+// that already delivered chunks still cools its backend down, but is never retried.
+func (p *Pool) finish(ctx context.Context, backend *poolBackend, err error) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	backend.inFlight--
+
+	cooldown, failover := poolFailure(ctx, err)
+	if failover {
+		if until := p.now().Add(cooldown); until.After(backend.unavailableUntil) {
+			backend.unavailableUntil = until
+		}
+	}
+	var partial *deliveredError
+	return failover && !errors.As(err, &partial)
+}
+
+// poolFailure reports whether err means the backend is unavailable, and for how long.
+func poolFailure(ctx context.Context, err error) (time.Duration, bool) {
+	if err == nil || ctx.Err() != nil {
+		return 0, false
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return 0, true
+	}
+	var apiError *Error
+	if errors.As(err, &apiError) {
+		if apiError.StatusCode != 429 && apiError.StatusCode < 500 {
+			return 0, false
+		}
+		if apiError.RetryAfter > 0 {
+			return apiError.RetryAfter, true
+		}
+		return DefaultPoolCooldown, true
+	}
+	// a network error, including a connection dropped while reading the body
+	var netError net.Error
+	if errors.As(err, &netError) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return DefaultPoolCooldown, true
+	}
+	return 0, false
+}
+
+// poolCall calls request on the backends in order until one succeeds or fails for a reason other than its
+// availability.
+func poolCall[T any](ctx context.Context, p *Pool, request func(client *AzureOpenAI) (T, error)) (T, error) {
+	var zero T
+	var errs []error
+	for _, backend := range p.order() {
+		p.start(backend)
+		response, err := request(backend.Client)
+		if !p.finish(ctx, backend, err) {
+			return response, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", backend.Name, err))
+	}
+	if len(errs) == 0 {
+		return zero, ErrNoBackendAvailable
+	}
+	return zero, fmt.Errorf("%w: %w", ErrNoBackendAvailable, errors.Join(errs...))
+}
+
+// streamConsumer wraps consumer to record whether a chunk was delivered, after which a stream cannot fail over.
+func streamConsumer[T any](consumer func(T) error, delivered *bool) func(T) error {
+	return func(chunk T) error {
+		*delivered = true
+		return consumer(chunk)
+	}
+}
+
+// streamCall works like poolCall, but stops failing over once a chunk was delivered.
+func streamCall[T any](ctx context.Context, p *Pool, consumer func(T) error, request func(client *AzureOpenAI, consumer func(T) error) error) error {
+	delivered := false
+	_, err := poolCall(ctx, p, func(client *AzureOpenAI) (struct{}, error) {
+		err := request(client, streamConsumer(consumer, &delivered))
+		if delivered && err != nil {
+			return struct{}{}, &deliveredError{err}
+		}
+		return struct{}{}, err
+	})
+	var partial *deliveredError
+	if errors.As(err, &partial) {
+		return partial.err
+	}
+	return err
+}
+
+// deliveredError marks the error of a stream that already delivered chunks, so that it is not retried.
+type deliveredError struct {
+	err error
+}
+
+func (e *deliveredError) Error() string { return e.err.Error() }
+
+func (e *deliveredError) Unwrap() error { return e.err }
+
+func (p *Pool) Completion(ctx context.Context, request CompletionRequest) (*CompletionResponse, error) {
+	return poolCall(ctx, p, func(client *AzureOpenAI) (*CompletionResponse, error) {
+		return client.Completion(ctx, request)
+	})
+}
+
+func (p *Pool) CompletionStream(ctx context.Context, request CompletionRequest, consumer func(CompletionResponse) error) error {
+	return streamCall(ctx, p, consumer, func(client *AzureOpenAI, consumer func(CompletionResponse) error) error {
+		return client.CompletionStream(ctx, request, consumer)
+	})
+}
+
+func (p *Pool) Embedding(ctx context.Context, request EmbeddingRequest) (*EmbeddingResponse, error) {
+	return poolCall(ctx, p, func(client *AzureOpenAI) (*EmbeddingResponse, error) {
+		return client.Embedding(ctx, request)
+	})
+}
+
+func (p *Pool) ChatCompletion(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+	return poolCall(ctx, p, func(client *AzureOpenAI) (*ChatResponse, error) {
+		return client.ChatCompletion(ctx, request)
+	})
+}
+
+func (p *Pool) ChatCompletionStream(ctx context.Context, request ChatRequest, consumer func(ChatResponse) error) error {
+	return streamCall(ctx, p, consumer, func(client *AzureOpenAI, consumer func(ChatResponse) error) error {
+		return client.ChatCompletionStream(ctx, request, consumer)
+	})
+}
+
+func (p *Pool) ChatCompletionStreamAccumulate(ctx context.Context, request ChatRequest, consumer func(ChatResponse) error) (*ChatResponse, error) {
+	var response *ChatResponse
+	err := streamCall(ctx, p, consumer, func(client *AzureOpenAI, consumer func(ChatResponse) error) error {
+		var err error
+		response, err = client.ChatCompletionStreamAccumulate(ctx, request, consumer)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
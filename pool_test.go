@@ -0,0 +1,167 @@
+package aoai
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// countingBackend returns a client whose server answers with status and body and counts its requests.
+func countingBackend(t *testing.T, calls *int, status int, header http.Header, body string) *AzureOpenAI {
+	return newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+const okChatBody = `{"choices":[{"message":{"role":"assistant","content":"Hi"}}]}`
+
+var poolChatRequest = ChatRequest{Messages: []ChatMessage{{Role: RoleUser, Content: "Hi"}}}
+
+func TestPool_PriorityStrategy(t *testing.T) {
+	var ptu, payg, standby int
+	p := NewPool(PriorityStrategy,
+		Backend{Name: "payg", Client: countingBackend(t, &payg, http.StatusOK, nil, okChatBody), Priority: 1},
+		Backend{Name: "ptu", Client: countingBackend(t, &ptu, http.StatusTooManyRequests, http.Header{"Retry-After": {"60"}}, `{"error":{"code":"429"}}`)},
+		Backend{Name: "standby", Client: countingBackend(t, &standby, http.StatusOK, nil, okChatBody), Priority: 2},
+	)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.ChatCompletion(ctx, poolChatRequest); err != nil {
+			t.Fatalf("ChatCompletion() error = %v", err)
+		}
+	}
+	if ptu != 1 || payg != 2 || standby != 0 {
+		t.Errorf("calls: ptu = %d, payg = %d, standby = %d", ptu, payg, standby)
+	}
+	if got := p.Available(); len(got) != 2 || got[0] != "payg" || got[1] != "standby" {
+		t.Errorf("Available() = %v", got)
+	}
+}
+
+func TestPool_Failover(t *testing.T) {
+	var bad, gateway, ok int
+	p := NewPool(RoundRobinStrategy,
+		Backend{Client: countingBackend(t, &bad, http.StatusBadRequest, nil, `{"error":{"code":"invalid"}}`)},
+		Backend{Client: countingBackend(t, &gateway, http.StatusBadGateway, nil, `<html>bad gateway</html>`)},
+		Backend{Client: countingBackend(t, &ok, http.StatusOK, nil, okChatBody)},
+	)
+	ctx := context.Background()
+
+	// a client error is not retried elsewhere
+	var apiError *Error
+	if _, err := p.ChatCompletion(ctx, poolChatRequest); !errors.As(err, &apiError) || apiError.StatusCode != http.StatusBadRequest {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	// the gateway error removes the backend and the request moves on
+	if _, err := p.ChatCompletion(ctx, poolChatRequest); err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if bad != 1 || gateway != 1 || ok != 1 || len(p.Available()) != 2 {
+		t.Errorf("calls: bad = %d, gateway = %d, ok = %d, available = %v", bad, gateway, ok, p.Available())
+	}
+}
+
+func TestPool_NoBackendAvailable(t *testing.T) {
+	var calls int
+	p := NewPool(RoundRobinStrategy, Backend{Client: countingBackend(t, &calls, http.StatusServiceUnavailable, nil, `{"error":{"code":"503"}}`)})
+	ctx := context.Background()
+
+	var apiError *Error
+	if _, err := p.Embedding(ctx, EmbeddingRequest{Inputs: []string{"Hi"}}); !errors.Is(err, ErrNoBackendAvailable) || !errors.As(err, &apiError) {
+		t.Errorf("Embedding() error = %v", err)
+	}
+	if _, err := p.Embedding(ctx, EmbeddingRequest{Inputs: []string{"Hi"}}); !errors.Is(err, ErrNoBackendAvailable) || calls != 1 {
+		t.Errorf("Embedding() error = %v, calls = %d", err, calls)
+	}
+}
+
+func TestPool_StreamFailover(t *testing.T) {
+	var unavailable int
+	streaming := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(recordedChatStream))
+	}))
+	p := NewPool(PriorityStrategy,
+		Backend{Client: countingBackend(t, &unavailable, http.StatusServiceUnavailable, nil, `{"error":{"code":"503"}}`)},
+		Backend{Client: streaming, Priority: 1},
+	)
+
+	request := poolChatRequest
+	request.Stream = true
+	response, err := p.ChatCompletionStreamAccumulate(context.Background(), request, func(ChatResponse) error { return nil })
+	if err != nil || unavailable != 1 || response.Choices[0].Message.Content != "Hello" {
+		t.Errorf("ChatCompletionStreamAccumulate() = %+v, %v", response, err)
+	}
+
+	// an error after the first chunk is returned as is
+	stop := errors.New("stop")
+	if err := p.ChatCompletionStream(context.Background(), request, func(ChatResponse) error { return stop }); err != stop {
+		t.Errorf("ChatCompletionStream() error = %v", err)
+	}
+}
+
+func TestPool_order(t *testing.T) {
+	a, b := Backend{Client: &AzureOpenAI{}, Name: "a", Weight: 3}, Backend{Client: &AzureOpenAI{}, Name: "b"}
+
+	p := NewPool(LeastLoadedStrategy, a, b)
+	p.backends[0].inFlight = 2
+	if got := p.order(); got[0].Name != "b" {
+		t.Errorf("least loaded order starts with %s", got[0].Name)
+	}
+
+	p = NewPool(WeightedStrategy, a, b)
+	p.random = rand.New(rand.NewSource(1))
+	first := 0
+	for i := 0; i < 1000; i++ {
+		if p.order()[0].Name == "a" {
+			first++
+		}
+	}
+	if first < 700 || first > 800 {
+		t.Errorf("weighted order started with a %d of 1000 times, want about 750", first)
+	}
+}
+
+func TestPool_StreamFailureCooldown(t *testing.T) {
+	var calls int
+	broken := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(strings.Join(strings.SplitAfter(recordedChatStream, "\n\n")[:2], "")))
+		w.(http.Flusher).Flush()
+		// drop the connection in the middle of the stream
+		panic(http.ErrAbortHandler)
+	}))
+	var standby int
+	p := NewPool(PriorityStrategy,
+		Backend{Name: "broken", Client: broken},
+		Backend{Name: "standby", Client: countingBackend(t, &standby, http.StatusOK, nil, okChatBody), Priority: 1},
+	)
+
+	request := poolChatRequest
+	request.Stream = true
+	chunks := 0
+	err := p.ChatCompletionStream(context.Background(), request, func(ChatResponse) error {
+		chunks++
+		return nil
+	})
+	if err == nil || chunks != 1 {
+		t.Errorf("ChatCompletionStream() error = %v, chunks = %d", err, chunks)
+	}
+	// the partly delivered stream is not retried, but its backend cools down
+	if calls != 1 || standby != 0 {
+		t.Errorf("calls: broken = %d, standby = %d", calls, standby)
+	}
+	if got := p.Available(); len(got) != 1 || got[0] != "standby" {
+		t.Errorf("Available() = %v", got)
+	}
+}
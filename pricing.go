@@ -0,0 +1,279 @@
+package aoai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Price is the price of a model in USD per one million tokens.
+type Price struct {
+	Input float64 `json:"input"`
+
+	// CachedInput is the price of prompt tokens served from the prompt cache. Input is charged when it is 0.
+	CachedInput float64 `json:"cached_input,omitempty"`
+
+	Output float64 `json:"output"`
+}
+
+// Cost returns the cost of usage in USD.
+func (p Price) Cost(usage Usage) float64 {
+	input, cached := usage.PromptTokens, 0
+	if usage.PromptTokensDetails != nil {
+		cached = usage.PromptTokensDetails.CachedTokens
+		input -= cached
+	}
+	cachedPrice := p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	return (float64(input)*p.Input + float64(cached)*cachedPrice + float64(usage.CompletionTokens)*p.Output) / 1e6
+}
+
+// Pricing maps model names to their price. A key also matches versioned names, e.g. `gpt-4o` matches
+// `gpt-4o-2024-08-06`.
+type Pricing map[string]Price
+
+// DefaultPriceKey is the Pricing key of the price Budget charges for models without a price of their own.
+const DefaultPriceKey = "default"
+
+// DefaultPricing holds the global standard pay-as-you-go prices of common models. Prices differ by region and
+// deployment type and change over time, so override them with LoadPricing where it matters.
+var DefaultPricing = Pricing{
+	"gpt-4o":                 {Input: 2.50, CachedInput: 1.25, Output: 10.00},
+	"gpt-4o-mini":            {Input: 0.15, CachedInput: 0.075, Output: 0.60},
+	"gpt-4.1":                {Input: 2.00, CachedInput: 0.50, Output: 8.00},
+	"gpt-4.1-mini":           {Input: 0.40, CachedInput: 0.10, Output: 1.60},
+	"gpt-4.1-nano":           {Input: 0.10, CachedInput: 0.025, Output: 0.40},
+	"o1":                     {Input: 15.00, CachedInput: 7.50, Output: 60.00},
+	"o3":                     {Input: 2.00, CachedInput: 0.50, Output: 8.00},
+	"o3-mini":                {Input: 1.10, CachedInput: 0.55, Output: 4.40},
+	"o4-mini":                {Input: 1.10, CachedInput: 0.275, Output: 4.40},
+	"gpt-4":                  {Input: 30.00, Output: 60.00},
+	"gpt-4-32k":              {Input: 60.00, Output: 120.00},
+	"gpt-4-turbo":            {Input: 10.00, Output: 30.00},
+	"gpt-35-turbo":           {Input: 0.50, Output: 1.50},
+	"gpt-35-turbo-instruct":  {Input: 1.50, Output: 2.00},
+	"text-embedding-ada-002": {Input: 0.10},
+	"text-embedding-3-small": {Input: 0.02},
+	"text-embedding-3-large": {Input: 0.13},
+}
+
+// LoadPricing returns DefaultPricing overridden by the JSON object in r, which maps model names to prices, e.g.
+// `{"gpt-4o": {"input": 2.75, "cached_input": 1.375, "output": 11}}`.
+func LoadPricing(r io.Reader) (Pricing, error) {
+	var overrides Pricing
+	if err := json.NewDecoder(r).Decode(&overrides); err != nil {
+		return nil, fmt.Errorf("load pricing: %w", err)
+	}
+	pricing := make(Pricing, len(DefaultPricing)+len(overrides))
+	for model, price := range DefaultPricing {
+		pricing[model] = price
+	}
+	for model, price := range overrides {
+		pricing[strings.ToLower(model)] = price
+	}
+	return pricing, nil
+}
+
+// Lookup returns the price of model, matching the longest key that is model itself or a prefix of it followed by
+// `-`.
+func (p Pricing) Lookup(model string) (Price, bool) {
+	model = strings.ToLower(model)
+	if price, ok := p[model]; ok {
+		return price, true
+	}
+	keys := make([]string, 0, len(p))
+	for key := range p {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	for _, key := range keys {
+		if strings.HasPrefix(model, key+"-") {
+			return p[key], true
+		}
+	}
+	return Price{}, false
+}
+
+// Cost returns the cost of usage by model in USD.
+func (p Pricing) Cost(model string, usage Usage) (float64, error) {
+	price, ok := p.Lookup(model)
+	if !ok {
+		return 0, fmt.Errorf("no price for model %q", model)
+	}
+	return price.Cost(usage), nil
+}
+
+// Cost returns the cost of the response in USD, priced by its model.
+func (r ChatResponse) Cost(pricing Pricing) (float64, error) {
+	return pricing.Cost(r.Model, r.Usage)
+}
+
+// Cost returns the cost of the response in USD, priced by its model.
+func (r CompletionResponse) Cost(pricing Pricing) (float64, error) {
+	return pricing.Cost(r.Model, r.Usage)
+}
+
+// Cost returns the cost of the response in USD, priced by its model.
+func (r EmbeddingResponse) Cost(pricing Pricing) (float64, error) {
+	return pricing.Cost(r.Model, r.Usage)
+}
+
+// ErrBudgetExceeded is returned by Budget once a spend limit is exhausted.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Budget wraps a client and refuses requests once the spend of the client, or of the `user` of a request,
+// reaches its limit. Requests already in flight when a limit is reached may exceed it. It is safe for concurrent
+// use.
+type Budget struct {
+	client    *AzureOpenAI
+	pricing   Pricing
+	limit     float64
+	userLimit float64
+
+	mu        sync.Mutex
+	spent     float64
+	userSpent map[string]float64
+	unpriced  Usage
+}
+
+// NewBudget returns a Budget that prices every response of client by its model with pricing. limit caps the total
+// spend and userLimit the spend of every `user`, both in USD; 0 means no limit. A model without a price is charged
+// as the deployment of client, or else at the DefaultPriceKey price. A response that none of them prices is
+// returned together with an error and its usage is added to Unpriced.
+func NewBudget(client *AzureOpenAI, pricing Pricing, limit float64, userLimit float64) *Budget {
+	return &Budget{
+		client:    client,
+		pricing:   pricing,
+		limit:     limit,
+		userLimit: userLimit,
+		userSpent: map[string]float64{},
+	}
+}
+
+// Spent returns the total spend in USD.
+func (b *Budget) Spent() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spent
+}
+
+// Unpriced returns the total usage of the responses that could not be priced.
+func (b *Budget) Unpriced() Usage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.unpriced
+}
+
+// SpentBy returns the spend of user in USD.
+func (b *Budget) SpentBy(user string) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.userSpent[user]
+}
+
+func (b *Budget) check(user string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.limit > 0 && b.spent >= b.limit {
+		return fmt.Errorf("%w: spent %.4f of %.4f USD", ErrBudgetExceeded, b.spent, b.limit)
+	}
+	if b.userLimit > 0 && user != "" && b.userSpent[user] >= b.userLimit {
+		return fmt.Errorf("%w: user %q spent %.4f of %.4f USD", ErrBudgetExceeded, user, b.userSpent[user], b.userLimit)
+	}
+	return nil
+}
+
+// record adds the cost of usage by model, falling back to the deployment of the client and to the default price.
+func (b *Budget) record(user string, model string, usage Usage) error {
+	price, ok := b.pricing.Lookup(model)
+	if !ok {
+		price, ok = b.pricing.Lookup(b.client.deploymentName)
+	}
+	if !ok {
+		price, ok = b.pricing[DefaultPriceKey]
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !ok {
+		b.unpriced.PromptTokens += usage.PromptTokens
+		b.unpriced.CompletionTokens += usage.CompletionTokens
+		b.unpriced.TotalTokens += usage.TotalTokens
+		return fmt.Errorf("budget: no price for model %q or deployment %q", model, b.client.deploymentName)
+	}
+	cost := price.Cost(usage)
+	b.spent += cost
+	if user != "" {
+		b.userSpent[user] += cost
+	}
+	return nil
+}
+
+func (b *Budget) ChatCompletion(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+	if err := b.check(request.User); err != nil {
+		return nil, err
+	}
+	response, err := b.client.ChatCompletion(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response, b.record(request.User, response.Model, response.Usage)
+}
+
+// ChatCompletionStreamAccumulate requests the usage of the stream, so that it can be priced. On api-versions
+// without `stream_options`, the usage is estimated from the length of the messages and the reply.
+func (b *Budget) ChatCompletionStreamAccumulate(ctx context.Context, request ChatRequest, consumer func(ChatResponse) error) (*ChatResponse, error) {
+	if err := b.check(request.User); err != nil {
+		return nil, err
+	}
+	if supported, _ := SupportsFeature(b.client.apiVersion, FeatureStreamOptions); supported {
+		options := StreamOptions{}
+		if request.StreamOptions != nil {
+			options = *request.StreamOptions
+		}
+		options.IncludeUsage = true
+		request.StreamOptions = &options
+	}
+	response, err := b.client.ChatCompletionStreamAccumulate(ctx, request, consumer)
+	if err != nil {
+		return nil, err
+	}
+	usage := response.Usage
+	if usage.TotalTokens == 0 {
+		usage = Usage{PromptTokens: estimatePrompt(nil, request)}
+		for _, choice := range response.Choices {
+			usage.CompletionTokens += estimateText(choice.Message.Content)
+		}
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return response, b.record(request.User, response.Model, usage)
+}
+
+func (b *Budget) Completion(ctx context.Context, request CompletionRequest) (*CompletionResponse, error) {
+	if err := b.check(request.User); err != nil {
+		return nil, err
+	}
+	response, err := b.client.Completion(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response, b.record(request.User, response.Model, response.Usage)
+}
+
+func (b *Budget) Embedding(ctx context.Context, request EmbeddingRequest) (*EmbeddingResponse, error) {
+	if err := b.check(request.User); err != nil {
+		return nil, err
+	}
+	response, err := b.client.Embedding(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response, b.record(request.User, response.Model, response.Usage)
+}
@@ -0,0 +1,192 @@
+package aoai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPrice_Cost(t *testing.T) {
+	price := Price{Input: 2, CachedInput: 1, Output: 8}
+	tests := []struct {
+		name  string
+		price Price
+		usage Usage
+		want  float64
+	}{
+		{name: "plain", price: price, usage: Usage{PromptTokens: 1000, CompletionTokens: 500}, want: 0.006},
+		{name: "cached", price: price, usage: Usage{PromptTokens: 1000, CompletionTokens: 500, PromptTokensDetails: &PromptTokensDetails{CachedTokens: 400}}, want: 0.0056},
+		{name: "noCachedPrice", price: Price{Input: 2, Output: 8}, usage: Usage{PromptTokens: 1000, PromptTokensDetails: &PromptTokensDetails{CachedTokens: 400}}, want: 0.002},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.price.Cost(tt.usage); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("Cost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPricing_Lookup(t *testing.T) {
+	tests := []struct {
+		model  string
+		want   Price
+		wantOk bool
+	}{
+		{model: "gpt-4o", want: DefaultPricing["gpt-4o"], wantOk: true},
+		{model: "gpt-4o-2024-08-06", want: DefaultPricing["gpt-4o"], wantOk: true},
+		{model: "GPT-4o-mini-2024-07-18", want: DefaultPricing["gpt-4o-mini"], wantOk: true},
+		{model: "gpt-4-32k-0613", want: DefaultPricing["gpt-4-32k"], wantOk: true},
+		{model: "o3-mini-2025-01-31", want: DefaultPricing["o3-mini"], wantOk: true},
+		{model: "gpt-4.5-preview"},
+		{model: "davinci"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got, ok := DefaultPricing.Lookup(tt.model); got != tt.want || ok != tt.wantOk {
+				t.Errorf("Lookup() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestLoadPricing(t *testing.T) {
+	pricing, err := LoadPricing(strings.NewReader(`{"GPT-4o": {"input": 3, "output": 12}, "my-model": {"input": 1, "output": 1}}`))
+	if err != nil {
+		t.Fatalf("LoadPricing() error = %v", err)
+	}
+	if pricing["gpt-4o"] != (Price{Input: 3, Output: 12}) || pricing["gpt-4o-mini"] != DefaultPricing["gpt-4o-mini"] {
+		t.Errorf("LoadPricing() = %+v", pricing)
+	}
+	if DefaultPricing["gpt-4o"].Input != 2.50 {
+		t.Errorf("DefaultPricing was modified")
+	}
+	if _, err := LoadPricing(strings.NewReader(`[]`)); err == nil {
+		t.Errorf("LoadPricing() error = nil")
+	}
+
+	response := ChatResponse{Model: "my-model", Usage: Usage{PromptTokens: 1e6, CompletionTokens: 1e6}}
+	if cost, err := response.Cost(pricing); err != nil || cost != 2 {
+		t.Errorf("Cost() = %v, %v", cost, err)
+	}
+	if _, err := response.Cost(DefaultPricing); err == nil {
+		t.Errorf("Cost() error = nil")
+	}
+}
+
+func TestBudget(t *testing.T) {
+	calls := 0
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"Hi"}}],"usage":{"prompt_tokens":1000000,"completion_tokens":0}}`))
+	}))
+	budget := NewBudget(a, Pricing{"gpt-4o": {Input: 1}}, 3, 2)
+	ctx := context.Background()
+	request := func(user string) ChatRequest {
+		return ChatRequest{Messages: []ChatMessage{{Role: RoleUser, Content: "Hi"}}, User: user}
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := budget.ChatCompletion(ctx, request("alice")); err != nil {
+			t.Fatalf("ChatCompletion() error = %v", err)
+		}
+	}
+	if _, err := budget.ChatCompletion(ctx, request("alice")); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("ChatCompletion() error = %v, want ErrBudgetExceeded for the user", err)
+	}
+	if _, err := budget.ChatCompletion(ctx, request("bob")); err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if _, err := budget.ChatCompletion(ctx, request("carol")); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("ChatCompletion() error = %v, want ErrBudgetExceeded for the client", err)
+	}
+	if calls != 3 || budget.Spent() != 3 || budget.SpentBy("alice") != 2 || budget.SpentBy("bob") != 1 {
+		t.Errorf("calls = %d, Spent() = %v, SpentBy(alice) = %v", calls, budget.Spent(), budget.SpentBy("alice"))
+	}
+}
+
+func TestBudget_pricing(t *testing.T) {
+	pricing := Pricing{"gpt-4o": {Input: 1}, "gpt-4o-mini": {Input: 0.5}}
+	tests := []struct {
+		name         string
+		model        string
+		pricing      Pricing
+		want         float64
+		wantUnpriced int
+		wantErr      bool
+	}{
+		{name: "gpt-4o", model: "gpt-4o-2024-08-06", pricing: pricing, want: 1},
+		{name: "gpt-4o-mini", model: "gpt-4o-mini-2024-07-18", pricing: pricing, want: 0.5},
+		{name: "deployment", model: "ada", pricing: Pricing{"gpt-35-turbo": {Input: 2}}, want: 2},
+		{name: "default", model: "ada", pricing: Pricing{"gpt-4o": {Input: 1}, DefaultPriceKey: {Input: 3}}, want: 3},
+		{name: "unknown", model: "my-model", pricing: pricing, wantUnpriced: 1000000, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprintf(w, `{"model":%q,"choices":[{"message":{"role":"assistant","content":"Hi"}}],"usage":{"prompt_tokens":1000000}}`, tt.model)
+			}))
+			budget := NewBudget(a, tt.pricing, 0, 0)
+
+			response, err := budget.ChatCompletion(context.Background(), poolChatRequest)
+			if (err != nil) != tt.wantErr || response == nil {
+				t.Fatalf("ChatCompletion() = %+v, %v", response, err)
+			}
+			if got := budget.Spent(); got != tt.want {
+				t.Errorf("Spent() = %v, want %v", got, tt.want)
+			}
+			if got := budget.Unpriced().PromptTokens; got != tt.wantUnpriced {
+				t.Errorf("Unpriced() = %v, want %v prompt tokens", got, tt.wantUnpriced)
+			}
+		})
+	}
+}
+
+func TestBudget_ChatCompletionStreamAccumulate(t *testing.T) {
+	request := poolChatRequest
+	request.Stream = true
+	tests := []struct {
+		name             string
+		apiVersion       string
+		wantIncludeUsage bool
+		want             float64
+	}{
+		{name: "stream options", apiVersion: APIVersion20241021, wantIncludeUsage: true, want: 12},
+		// "Hello" and "Yo" are estimated at 2 and 1 tokens
+		{name: "no stream options", apiVersion: APIVersion20240601, want: float64(estimatePrompt(nil, request) + 3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var includeUsage bool
+			a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var sent ChatRequest
+				_ = json.NewDecoder(r.Body).Decode(&sent)
+				includeUsage = sent.StreamOptions != nil && sent.StreamOptions.IncludeUsage
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, event := range strings.SplitAfter(recordedChatStream, "\n\n") {
+					if includeUsage || !strings.Contains(event, "total_tokens") {
+						_, _ = w.Write([]byte(event))
+					}
+				}
+			}))
+			a.apiVersion = tt.apiVersion
+			WithFeatureCheck(FeatureCheckError)(a)
+			budget := NewBudget(a, Pricing{"gpt-4o": {Input: 1e6, Output: 1e6}}, 0, 0)
+
+			if _, err := budget.ChatCompletionStreamAccumulate(context.Background(), request, func(ChatResponse) error { return nil }); err != nil {
+				t.Fatalf("ChatCompletionStreamAccumulate() error = %v", err)
+			}
+			if includeUsage != tt.wantIncludeUsage {
+				t.Errorf("include_usage = %v, want %v", includeUsage, tt.wantIncludeUsage)
+			}
+			if got := budget.Spent(); got != tt.want {
+				t.Errorf("Spent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package aoai
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter keeps requests within the tokens-per-minute and requests-per-minute quota of a deployment, so that
+// concurrent workers wait on the client side instead of receiving 429 responses. Each request reserves its
+// estimated prompt tokens plus its max tokens from a token bucket before it is sent; the reservation is reconciled
+// with the usage of the response. The buckets follow the `x-ratelimit-remaining-tokens` and
+// `x-ratelimit-remaining-requests` headers of every response, and a 429 pauses all requests for its retry-after.
+// It is safe for concurrent use and may be shared by clients of the same deployment.
+type RateLimiter struct {
+	counter *TokenCounter
+
+	mu          sync.Mutex
+	tokens      bucket
+	requests    bucket
+	pausedUntil time.Time
+	now         func() time.Time
+}
+
+// bucket is a token bucket refilled continuously to capacity within a minute. A capacity of 0 means no limit.
+type bucket struct {
+	capacity  float64
+	available float64
+	updated   time.Time
+}
+
+func (b *bucket) refill(now time.Time) {
+	if b.capacity == 0 {
+		return
+	}
+	b.available = math.Min(b.capacity, b.available+now.Sub(b.updated).Minutes()*b.capacity)
+	b.updated = now
+}
+
+// wait returns how long it takes until n can be taken.
+func (b *bucket) wait(n float64) time.Duration {
+	if b.capacity == 0 || b.available >= n {
+		return 0
+	}
+	return time.Duration((n - b.available) / b.capacity * float64(time.Minute))
+}
+
+// NewRateLimiter returns a RateLimiter for a quota of tokensPerMinute and requestsPerMinute; 0 means no limit.
+// Prompt tokens of chat requests are counted with counter, or estimated from their length when it is nil.
+func NewRateLimiter(tokensPerMinute int, requestsPerMinute int, counter *TokenCounter) *RateLimiter {
+	now := time.Now()
+	return &RateLimiter{
+		counter:  counter,
+		tokens:   bucket{capacity: float64(tokensPerMinute), available: float64(tokensPerMinute), updated: now},
+		requests: bucket{capacity: float64(requestsPerMinute), available: float64(requestsPerMinute), updated: now},
+		now:      time.Now,
+	}
+}
+
+// WithRateLimiter makes completion, chat and embedding requests wait for capacity of limiter before they are sent.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(a *AzureOpenAI) {
+		a.limiter = limiter
+	}
+}
+
+// Reservation is capacity taken from a RateLimiter for one request.
+type Reservation struct {
+	limiter *RateLimiter
+	tokens  int
+}
+
+// Wait blocks until tokens and one request are available and takes them. Estimates larger than the quota wait
+// for a full bucket.
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) (*Reservation, error) {
+	for {
+		l.mu.Lock()
+		now := l.now()
+		l.tokens.refill(now)
+		l.requests.refill(now)
+		need := math.Min(float64(tokens), l.tokens.capacity)
+		delay := l.pausedUntil.Sub(now)
+		if delay <= 0 {
+			delay = time.Duration(math.Max(float64(l.tokens.wait(need)), float64(l.requests.wait(1))))
+		}
+		if delay <= 0 {
+			l.tokens.available -= float64(tokens)
+			l.requests.available--
+			l.mu.Unlock()
+			return &Reservation{limiter: l, tokens: tokens}, nil
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// estimate returns the tokens reserved, or 0 for a nil reservation.
+func (r *Reservation) estimate() int {
+	if r == nil {
+		return 0
+	}
+	return r.tokens
+}
+
+// Done reconciles the reservation with the tokens the request actually used, returning unused tokens to the
+// bucket. It does nothing on a nil reservation.
+func (r *Reservation) Done(used int) {
+	if r == nil {
+		return
+	}
+	l := r.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tokens.capacity > 0 {
+		l.tokens.refill(l.now())
+		l.tokens.available = math.Min(l.tokens.capacity, l.tokens.available+float64(r.tokens-used))
+	}
+}
+
+// Observe adapts the buckets to the rate limit headers of a response.
+func (l *RateLimiter) Observe(statusCode int, header http.Header) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if remaining, err := strconv.Atoi(header.Get("x-ratelimit-remaining-tokens")); err == nil && l.tokens.capacity > 0 {
+		l.tokens.refill(now)
+		l.tokens.available = math.Min(l.tokens.available, float64(remaining))
+	}
+	if remaining, err := strconv.Atoi(header.Get("x-ratelimit-remaining-requests")); err == nil && l.requests.capacity > 0 {
+		l.requests.refill(now)
+		l.requests.available = math.Min(l.requests.available, float64(remaining))
+	}
+	if statusCode == http.StatusTooManyRequests {
+		if until := now.Add(retryAfter(header)); until.After(l.pausedUntil) {
+			l.pausedUntil = until
+		}
+	}
+}
+
+// retryAfter returns the delay of the `retry-after-ms` or `retry-after` header, or a second without them.
+func retryAfter(header http.Header) time.Duration {
+	if delay, ok := parseRetryAfter(header); ok {
+		return delay
+	}
+	return time.Second
+}
+
+// parseRetryAfter returns the delay of the `retry-after-ms` or `retry-after` header.
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	if ms, err := strconv.Atoi(header.Get("retry-after-ms")); err == nil {
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	if seconds, err := strconv.Atoi(header.Get("retry-after")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	return 0, false
+}
+
+type observingTransport struct {
+	limiter *RateLimiter
+	next    http.RoundTripper
+}
+
+func (t observingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := t.next.RoundTrip(request)
+	if err == nil {
+		t.limiter.Observe(response.StatusCode, response.Header)
+	}
+	return response, err
+}
+
+// estimateText estimates the tokens of text without an encoding, at about four bytes per token.
+func estimateText(text string) int {
+	return (len(text) + 3) / 4
+}
+
+func outputTokens(maxTokens int, n int) int {
+	if n < 1 {
+		n = 1
+	}
+	return maxTokens * n
+}
+
+// estimatePrompt counts the prompt tokens of request with counter, or estimates them from their length when it is
+// nil.
+func estimatePrompt(counter *TokenCounter, request ChatRequest) int {
+	if counter != nil {
+		return counter.CountRequest(request)
+	}
+	var prompt int
+	for _, message := range request.Messages {
+		prompt += 4 + estimateText(message.Content)
+		for _, part := range message.ContentParts {
+			prompt += estimateText(part.Text)
+		}
+		for _, call := range message.ToolCalls {
+			prompt += estimateText(call.Function.Name + call.Function.Arguments)
+		}
+	}
+	return prompt
+}
+
+func (l *RateLimiter) estimateChat(request ChatRequest) int {
+	prompt := estimatePrompt(l.counter, request)
+	maxTokens := request.MaxTokens
+	if request.MaxCompletionTokens > maxTokens {
+		maxTokens = request.MaxCompletionTokens
+	}
+	return prompt + outputTokens(maxTokens, request.N)
+}
+
+func (l *RateLimiter) estimateCompletion(request CompletionRequest) int {
+	var prompt int
+	for _, text := range request.Prompts {
+		prompt += estimateText(text)
+	}
+	for _, tokens := range request.PromptTokens {
+		prompt += len(tokens)
+	}
+	return prompt + outputTokens(request.MaxTokens, request.N)
+}
+
+func (l *RateLimiter) estimateEmbedding(request EmbeddingRequest) int {
+	var tokens int
+	for _, text := range request.Inputs {
+		tokens += estimateText(text)
+	}
+	for _, input := range request.InputTokens {
+		tokens += len(input)
+	}
+	return tokens
+}
+
+// reserve waits for capacity of the rate limiter. Without a rate limiter it returns a nil reservation.
+func (a *AzureOpenAI) reserve(ctx context.Context, estimate func(*RateLimiter) int) (*Reservation, error) {
+	if a.limiter == nil {
+		return nil, nil
+	}
+	return a.limiter.Wait(ctx, estimate(a.limiter))
+}
@@ -0,0 +1,111 @@
+package aoai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// newFrozenRateLimiter returns a RateLimiter whose clock never advances, so that its buckets do not refill.
+func newFrozenRateLimiter(tokensPerMinute int, requestsPerMinute int) *RateLimiter {
+	l := NewRateLimiter(tokensPerMinute, requestsPerMinute, nil)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	return l
+}
+
+func shortContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	l := newFrozenRateLimiter(100, 2)
+	ctx := context.Background()
+
+	first, err := l.Wait(ctx, 60)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if _, err := l.Wait(shortContext(t), 60); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want the tokens to be exhausted", err)
+	}
+
+	// 50 unused tokens are returned
+	first.Done(10)
+	if _, err := l.Wait(ctx, 60); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if _, err := l.Wait(shortContext(t), 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want the requests to be exhausted", err)
+	}
+
+	var reservation *Reservation
+	reservation.Done(1)
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	l := newFrozenRateLimiter(60, 0)
+	start := l.now()
+	if _, err := l.Wait(context.Background(), 60); err != nil {
+		t.Fatal(err)
+	}
+	l.now = func() time.Time { return start.Add(30 * time.Second) }
+	if _, err := l.Wait(context.Background(), 30); err != nil {
+		t.Errorf("Wait() error = %v, want half of the bucket to be refilled", err)
+	}
+}
+
+func TestRateLimiter_Observe(t *testing.T) {
+	l := newFrozenRateLimiter(100, 0)
+	l.Observe(http.StatusOK, http.Header{"X-Ratelimit-Remaining-Tokens": {"5"}})
+	if _, err := l.Wait(shortContext(t), 6); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want the remaining tokens of the header", err)
+	}
+
+	l = NewRateLimiter(0, 0, nil)
+	l.Observe(http.StatusTooManyRequests, http.Header{"Retry-After-Ms": {"50"}})
+	start := time.Now()
+	if _, err := l.Wait(context.Background(), 1); err != nil || time.Since(start) < 40*time.Millisecond {
+		t.Errorf("Wait() = %v after %v, want to wait for retry-after", err, time.Since(start))
+	}
+}
+
+func TestRateLimiter_estimate(t *testing.T) {
+	l := NewRateLimiter(0, 0, nil)
+	chat := ChatRequest{Messages: []ChatMessage{{Role: RoleUser, Content: "hello world!"}}, MaxTokens: 10, N: 2}
+	if got := l.estimateChat(chat); got != 4+3+20 {
+		t.Errorf("estimateChat() = %d", got)
+	}
+	completion := CompletionRequest{Prompts: []string{"hello"}, PromptTokens: nil, MaxTokens: 5}
+	if got := l.estimateCompletion(completion); got != 2+5 {
+		t.Errorf("estimateCompletion() = %d", got)
+	}
+	embedding := EmbeddingRequest{InputTokens: TokenSlices{{1, 2, 3}}}
+	if got := l.estimateEmbedding(embedding); got != 3 {
+		t.Errorf("estimateEmbedding() = %d", got)
+	}
+}
+
+func TestWithRateLimiter(t *testing.T) {
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ratelimit-remaining-requests", "0")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Hi"}}],"usage":{"total_tokens":12}}`))
+	}))
+	l := newFrozenRateLimiter(1000, 10)
+	WithRateLimiter(l)(a)
+	request := ChatRequest{Messages: []ChatMessage{{Role: RoleUser, Content: "Hi"}}, MaxTokens: 100}
+
+	if _, err := a.ChatCompletion(context.Background(), request); err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if l.tokens.available != 1000-12 {
+		t.Errorf("available tokens = %v, want the usage to be reconciled", l.tokens.available)
+	}
+	if _, err := a.ChatCompletion(shortContext(t), request); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ChatCompletion() error = %v, want the remaining requests of the header", err)
+	}
+}
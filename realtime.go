@@ -0,0 +1,521 @@
+package aoai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type RealtimeSessionConfig struct {
+	// modalities:
+	//   type: array
+	//   items:
+	//     type: string
+	//     enum:
+	//       - text
+	//       - audio
+	Modalities []string `json:"modalities,omitempty"`
+
+	// instructions:
+	//   type: string
+	Instructions string `json:"instructions,omitempty"`
+
+	// voice:
+	//   type: string
+	//   enum:
+	//     - alloy
+	//     - echo
+	//     - shimmer
+	Voice string `json:"voice,omitempty"`
+
+	// input_audio_format:
+	//   type: string
+	//   enum:
+	//     - pcm16
+	//     - g711_ulaw
+	//     - g711_alaw
+	InputAudioFormat string `json:"input_audio_format,omitempty"`
+
+	// output_audio_format:
+	//   type: string
+	//   enum:
+	//     - pcm16
+	//     - g711_ulaw
+	//     - g711_alaw
+	OutputAudioFormat string `json:"output_audio_format,omitempty"`
+
+	// input_audio_transcription:
+	//   type: object
+	InputAudioTranscription *struct {
+		Model string `json:"model,omitempty"`
+	} `json:"input_audio_transcription,omitempty"`
+
+	// turn_detection:
+	//   type: RealtimeTurnDetection
+	TurnDetection *RealtimeTurnDetection `json:"turn_detection,omitempty"`
+
+	// tools:
+	//   type: []RealtimeTool
+	Tools []RealtimeTool `json:"tools,omitempty"`
+
+	// tool_choice:
+	//   type: string
+	//   enum:
+	//     - auto
+	//     - none
+	//     - required
+	ToolChoice string `json:"tool_choice,omitempty"`
+
+	// temperature:
+	//   type: number
+	//   minimum: 0.6
+	//   maximum: 1.2
+	Temperature float64 `json:"temperature,omitempty"`
+
+	// max_response_output_tokens:
+	//   description: Maximum number of output tokens for a single response, an integer or `inf`.
+	MaxResponseOutputTokens any `json:"max_response_output_tokens,omitempty"`
+}
+
+type RealtimeTurnDetection struct {
+	// type:
+	//   type: string
+	//   enum:
+	//     - server_vad
+	Type string `json:"type,omitempty"`
+
+	// threshold:
+	//   type: number
+	Threshold float64 `json:"threshold,omitempty"`
+
+	// prefix_padding_ms:
+	//   type: integer
+	PrefixPaddingMs int `json:"prefix_padding_ms,omitempty"`
+
+	// silence_duration_ms:
+	//   type: integer
+	SilenceDurationMs int `json:"silence_duration_ms,omitempty"`
+}
+
+// RealtimeTool is a function the model can call during a realtime session. Unlike Tool, the function is
+// described inline.
+type RealtimeTool struct {
+	// type:
+	//   type: string
+	//   enum:
+	//     - function
+	Type string `json:"type"`
+
+	FunctionDefinition
+}
+
+type RealtimeResponseConfig struct {
+	// modalities:
+	//   type: array
+	Modalities []string `json:"modalities,omitempty"`
+
+	// instructions:
+	//   type: string
+	Instructions string `json:"instructions,omitempty"`
+
+	// voice:
+	//   type: string
+	Voice string `json:"voice,omitempty"`
+
+	// output_audio_format:
+	//   type: string
+	OutputAudioFormat string `json:"output_audio_format,omitempty"`
+
+	// tools:
+	//   type: []RealtimeTool
+	Tools []RealtimeTool `json:"tools,omitempty"`
+
+	// tool_choice:
+	//   type: string
+	ToolChoice string `json:"tool_choice,omitempty"`
+
+	// temperature:
+	//   type: number
+	Temperature float64 `json:"temperature,omitempty"`
+
+	// max_output_tokens:
+	//   description: Maximum number of output tokens, an integer or `inf`.
+	MaxOutputTokens any `json:"max_output_tokens,omitempty"`
+}
+
+type RealtimeItem struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// type:
+	//   type: string
+	//   enum:
+	//     - message
+	//     - function_call
+	//     - function_call_output
+	Type string `json:"type,omitempty"`
+
+	// status:
+	//   type: string
+	Status string `json:"status,omitempty"`
+
+	// role:
+	//   type: string
+	//   enum:
+	//     - user
+	//     - assistant
+	//     - system
+	Role string `json:"role,omitempty"`
+
+	// content:
+	//   type: []RealtimeContent
+	Content []RealtimeContent `json:"content,omitempty"`
+
+	// call_id:
+	//   type: string
+	CallID string `json:"call_id,omitempty"`
+
+	// name:
+	//   type: string
+	Name string `json:"name,omitempty"`
+
+	// arguments:
+	//   type: string
+	Arguments string `json:"arguments,omitempty"`
+
+	// output:
+	//   type: string
+	Output string `json:"output,omitempty"`
+}
+
+type RealtimeContent struct {
+	// type:
+	//   type: string
+	//   enum:
+	//     - input_text
+	//     - input_audio
+	//     - text
+	//     - audio
+	Type string `json:"type,omitempty"`
+
+	// text:
+	//   type: string
+	Text string `json:"text,omitempty"`
+
+	// audio:
+	//   description: Base64 encoded audio bytes.
+	//   type: string
+	Audio string `json:"audio,omitempty"`
+
+	// transcript:
+	//   type: string
+	Transcript string `json:"transcript,omitempty"`
+}
+
+// RealtimeClientEvent is an event sent to the service, e.g. `session.update` or `response.create`.
+type RealtimeClientEvent struct {
+	// type:
+	//   type: string
+	Type string `json:"type"`
+
+	// event_id:
+	//   type: string
+	EventID string `json:"event_id,omitempty"`
+
+	// session:
+	//   type: RealtimeSessionConfig
+	Session *RealtimeSessionConfig `json:"session,omitempty"`
+
+	// audio:
+	//   description: Base64 encoded audio bytes for `input_audio_buffer.append`.
+	//   type: string
+	Audio string `json:"audio,omitempty"`
+
+	// item:
+	//   type: RealtimeItem
+	Item *RealtimeItem `json:"item,omitempty"`
+
+	// response:
+	//   type: RealtimeResponseConfig
+	Response *RealtimeResponseConfig `json:"response,omitempty"`
+}
+
+// RealtimeServerEvent is an event received from the service. Only the fields relevant to its Type are set,
+// and the raw event is kept in Raw.
+type RealtimeServerEvent struct {
+	// type:
+	//   type: string
+	//   example: response.audio.delta
+	Type string `json:"type"`
+
+	// event_id:
+	//   type: string
+	EventID string `json:"event_id,omitempty"`
+
+	// session:
+	//   description: Set on `session.created` and `session.updated`.
+	//   type: RealtimeSessionConfig
+	Session *RealtimeSessionConfig `json:"session,omitempty"`
+
+	// item:
+	//   description: Set on `conversation.item.created` and `response.output_item.*`.
+	//   type: RealtimeItem
+	Item *RealtimeItem `json:"item,omitempty"`
+
+	// response:
+	//   description: Set on `response.created` and `response.done`.
+	//   type: object
+	Response json.RawMessage `json:"response,omitempty"`
+
+	// response_id:
+	//   type: string
+	ResponseID string `json:"response_id,omitempty"`
+
+	// item_id:
+	//   type: string
+	ItemID string `json:"item_id,omitempty"`
+
+	// output_index:
+	//   type: integer
+	OutputIndex int `json:"output_index,omitempty"`
+
+	// content_index:
+	//   type: integer
+	ContentIndex int `json:"content_index,omitempty"`
+
+	// delta:
+	//   description: Text, transcript or function arguments delta, or base64 encoded audio for `response.audio.delta`.
+	//   type: string
+	Delta string `json:"delta,omitempty"`
+
+	// transcript:
+	//   description: Set on `response.audio_transcript.done` and `conversation.item.input_audio_transcription.completed`.
+	//   type: string
+	Transcript string `json:"transcript,omitempty"`
+
+	// text:
+	//   description: Set on `response.text.done`.
+	//   type: string
+	Text string `json:"text,omitempty"`
+
+	// call_id:
+	//   description: Set on `response.function_call_arguments.*`.
+	//   type: string
+	CallID string `json:"call_id,omitempty"`
+
+	// name:
+	//   type: string
+	Name string `json:"name,omitempty"`
+
+	// arguments:
+	//   type: string
+	Arguments string `json:"arguments,omitempty"`
+
+	// error:
+	//   description: Set on `error`.
+	//   type: Error
+	Error *Error `json:"error,omitempty"`
+
+	Raw json.RawMessage `json:"-"`
+}
+
+// Audio decodes the base64 audio delta of a `response.audio.delta` event.
+func (e *RealtimeServerEvent) Audio() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(e.Delta)
+}
+
+// realtimeWriteTimeout bounds every write to a realtime session, so that a stalled connection fails Send instead
+// of blocking it.
+const realtimeWriteTimeout = 10 * time.Second
+
+// WithDialer replaces the WebSocket dialer of ConnectRealtime, e.g. to set a proxy, TLS configuration or
+// handshake timeout.
+func WithDialer(dialer *websocket.Dialer) Option {
+	return func(a *AzureOpenAI) {
+		a.dialer = dialer
+	}
+}
+
+// RealtimeSession is a realtime conversation over a WebSocket connection.
+type RealtimeSession struct {
+	conn   *websocket.Conn
+	events chan RealtimeServerEvent
+
+	writeMu sync.Mutex
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	errMu sync.Mutex
+	err   error
+}
+
+func (a *AzureOpenAI) realtimeEndpoint() string {
+	values := url.Values{}
+	values.Set("api-version", a.apiVersion)
+	values.Set("deployment", a.deploymentName)
+	return fmt.Sprintf("wss://%s.openai.azure.com/openai/realtime?%s", a.resourceName, values.Encode())
+}
+
+// ConnectRealtime opens a realtime session on the configured deployment. The session ends when ctx is done or
+// Close is called, and server events are delivered on Events until then.
+func (a *AzureOpenAI) ConnectRealtime(ctx context.Context) (*RealtimeSession, error) {
+	dialer := a.dialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+
+	header := http.Header{}
+	for key, values := range a.header() {
+		if key != "Content-Type" {
+			header[key] = values
+		}
+	}
+
+	conn, httpResponse, err := dialer.DialContext(ctx, a.realtimeEndpoint(), header)
+	if err != nil {
+		if httpResponse != nil && httpResponse.Body != nil {
+			var errorResponse ErrorResponse
+			if json.NewDecoder(httpResponse.Body).Decode(&errorResponse) == nil && errorResponse.Error.Message != "" {
+				return nil, &errorResponse.Error
+			}
+		}
+		return nil, err
+	}
+
+	session := &RealtimeSession{
+		conn:   conn,
+		events: make(chan RealtimeServerEvent, 64),
+		closed: make(chan struct{}),
+	}
+	go session.read()
+	go func() {
+		select {
+		case <-ctx.Done():
+			session.setErr(ctx.Err())
+			_ = session.Close()
+		case <-session.closed:
+		}
+	}()
+	return session, nil
+}
+
+// Events returns the channel of server events. It is closed when the session ends; Err then reports why.
+func (s *RealtimeSession) Events() <-chan RealtimeServerEvent {
+	return s.events
+}
+
+// Err returns the error that ended the session, or nil if it was closed by Close.
+func (s *RealtimeSession) Err() error {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	return s.err
+}
+
+func (s *RealtimeSession) setErr(err error) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+func (s *RealtimeSession) read() {
+	defer close(s.events)
+	for {
+		_, m, err := s.conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-s.closed:
+			default:
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+					s.setErr(err)
+				}
+				_ = s.Close()
+			}
+			return
+		}
+
+		var event RealtimeServerEvent
+		if err := json.Unmarshal(m, &event); err != nil {
+			s.setErr(err)
+			_ = s.Close()
+			return
+		}
+		event.Raw = m
+
+		select {
+		case s.events <- event:
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// Send sends a client event. It is safe to call from multiple goroutines.
+func (s *RealtimeSession) Send(event RealtimeClientEvent) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	select {
+	case <-s.closed:
+		return errors.New("realtime session is closed")
+	default:
+	}
+	if err := s.conn.SetWriteDeadline(time.Now().Add(realtimeWriteTimeout)); err != nil {
+		return err
+	}
+	return s.conn.WriteJSON(event)
+}
+
+func (s *RealtimeSession) UpdateSession(session RealtimeSessionConfig) error {
+	return s.Send(RealtimeClientEvent{Type: "session.update", Session: &session})
+}
+
+// AppendInputAudio appends raw audio bytes in the configured input format to the input audio buffer.
+func (s *RealtimeSession) AppendInputAudio(audio []byte) error {
+	return s.Send(RealtimeClientEvent{Type: "input_audio_buffer.append", Audio: base64.StdEncoding.EncodeToString(audio)})
+}
+
+func (s *RealtimeSession) CommitInputAudio() error {
+	return s.Send(RealtimeClientEvent{Type: "input_audio_buffer.commit"})
+}
+
+func (s *RealtimeSession) ClearInputAudio() error {
+	return s.Send(RealtimeClientEvent{Type: "input_audio_buffer.clear"})
+}
+
+func (s *RealtimeSession) CreateConversationItem(item RealtimeItem) error {
+	return s.Send(RealtimeClientEvent{Type: "conversation.item.create", Item: &item})
+}
+
+// CreateResponse asks the model to respond. response may be nil to use the session configuration.
+func (s *RealtimeSession) CreateResponse(response *RealtimeResponseConfig) error {
+	return s.Send(RealtimeClientEvent{Type: "response.create", Response: response})
+}
+
+func (s *RealtimeSession) CancelResponse() error {
+	return s.Send(RealtimeClientEvent{Type: "response.cancel"})
+}
+
+// Close ends the session. It does not wait for a Send in progress, which fails once the connection is closed.
+func (s *RealtimeSession) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		// unlike WriteMessage, WriteControl may be called concurrently with a Send
+		message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		_ = s.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(realtimeWriteTimeout))
+		err = s.conn.Close()
+	})
+	return err
+}
@@ -0,0 +1,207 @@
+package aoai
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// recordedRealtimeEvents is replayed by the stand-in server after `response.create`.
+var recordedRealtimeEvents = []string{
+	`{"type":"response.created","event_id":"event-1","response":{"id":"resp-1","status":"in_progress"}}`,
+	`{"type":"response.audio_transcript.delta","event_id":"event-2","response_id":"resp-1","item_id":"item-1","delta":"Hello"}`,
+	`{"type":"response.audio.delta","event_id":"event-3","response_id":"resp-1","item_id":"item-1","delta":"AAEC"}`,
+	`{"type":"response.audio_transcript.done","event_id":"event-4","response_id":"resp-1","item_id":"item-1","transcript":"Hello"}`,
+	`{"type":"response.function_call_arguments.done","event_id":"event-5","response_id":"resp-1","call_id":"call-1","name":"get_weather","arguments":"{\"city\":\"Tokyo\"}"}`,
+	`{"type":"response.done","event_id":"event-6","response":{"id":"resp-1","status":"completed"}}`,
+}
+
+// newRealtimeStandIn starts a TLS WebSocket server that acts like the realtime endpoint and returns a client
+// whose dialer connects to it.
+func newRealtimeStandIn(t *testing.T, handler func(conn *websocket.Conn, r *http.Request)) *AzureOpenAI {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/realtime" || r.URL.Query().Get("deployment") != "gpt-4o-realtime-preview" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"DeploymentNotFound","message":"deployment not found"}}`))
+			return
+		}
+		if r.Header.Get("api-key") != "dummy" && r.Header.Get("Authorization") != "Bearer dummy" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"code":"401","message":"unauthorized"}}`))
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handler(conn, r)
+	}))
+	t.Cleanup(server.Close)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	return New("example-aoai-02", "gpt-4o-realtime-preview", "2024-10-01-preview", "dummy", WithDialer(&websocket.Dialer{
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+		TLSClientConfig:  &tls.Config{RootCAs: pool, ServerName: "example.com"},
+		HandshakeTimeout: time.Second,
+	}))
+}
+
+func TestAzureOpenAI_ConnectRealtime(t *testing.T) {
+	var received []string
+	a := newRealtimeStandIn(t, func(conn *websocket.Conn, r *http.Request) {
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"session.created","event_id":"event-0","session":{"voice":"alloy"}}`))
+		for {
+			var event RealtimeClientEvent
+			if err := conn.ReadJSON(&event); err != nil {
+				return
+			}
+			received = append(received, event.Type)
+
+			switch event.Type {
+			case "session.update":
+				m, _ := json.Marshal(map[string]any{"type": "session.updated", "session": event.Session})
+				_ = conn.WriteMessage(websocket.TextMessage, m)
+			case "response.create":
+				for _, m := range recordedRealtimeEvents {
+					_ = conn.WriteMessage(websocket.TextMessage, []byte(m))
+				}
+			}
+		}
+	})
+
+	for _, useActiveDirectory := range []bool{false, true} {
+		a.useActiveDirectory = useActiveDirectory
+		received = nil
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		session, err := a.ConnectRealtime(ctx)
+		if err != nil {
+			cancel()
+			t.Fatalf("ConnectRealtime() error = %v", err)
+		}
+
+		if err := session.UpdateSession(RealtimeSessionConfig{
+			Instructions:  "Be brief.",
+			TurnDetection: &RealtimeTurnDetection{Type: "server_vad"},
+			Tools:         []RealtimeTool{{Type: "function", FunctionDefinition: FunctionDefinition{Name: "get_weather"}}},
+		}); err != nil {
+			t.Fatalf("UpdateSession() error = %v", err)
+		}
+		if err := session.AppendInputAudio([]byte{0, 1, 2}); err != nil {
+			t.Fatalf("AppendInputAudio() error = %v", err)
+		}
+		if err := session.CommitInputAudio(); err != nil {
+			t.Fatalf("CommitInputAudio() error = %v", err)
+		}
+		if err := session.CreateResponse(nil); err != nil {
+			t.Fatalf("CreateResponse() error = %v", err)
+		}
+
+		var types []string
+		var transcript, arguments string
+		var audio []byte
+		for event := range session.Events() {
+			types = append(types, event.Type)
+			switch event.Type {
+			case "session.updated":
+				if event.Session.Instructions != "Be brief." || event.Session.Tools[0].Name != "get_weather" {
+					t.Errorf("session.updated = %s", event.Raw)
+				}
+			case "response.audio.delta":
+				audio, _ = event.Audio()
+			case "response.audio_transcript.done":
+				transcript = event.Transcript
+			case "response.function_call_arguments.done":
+				arguments = event.Arguments
+			case "response.done":
+				_ = session.Close()
+			}
+		}
+		cancel()
+
+		if err := session.Err(); err != nil {
+			t.Errorf("Err() = %v", err)
+		}
+		want := "session.created,session.updated,response.created,response.audio_transcript.delta,response.audio.delta,response.audio_transcript.done,response.function_call_arguments.done,response.done"
+		if got := strings.Join(types, ","); got != want {
+			t.Errorf("events = %s, want %s", got, want)
+		}
+		if string(audio) != "\x00\x01\x02" || transcript != "Hello" || arguments != `{"city":"Tokyo"}` {
+			t.Errorf("audio = %v, transcript = %q, arguments = %q", audio, transcript, arguments)
+		}
+		if got := strings.Join(received, ","); got != "session.update,input_audio_buffer.append,input_audio_buffer.commit,response.create" {
+			t.Errorf("received = %s", got)
+		}
+		if err := session.CreateResponse(nil); err == nil {
+			t.Errorf("CreateResponse() after Close() error = nil")
+		}
+	}
+}
+
+func TestAzureOpenAI_ConnectRealtime_errors(t *testing.T) {
+	a := newRealtimeStandIn(t, func(conn *websocket.Conn, r *http.Request) {
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"session.created"}`))
+		// keep the connection open until the client goes away
+		_, _, _ = conn.ReadMessage()
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		b := *a
+		b.accessToken = "invalid"
+		var e *Error
+		if _, err := b.ConnectRealtime(context.Background()); !errors.As(err, &e) || e.Code != "401" {
+			t.Errorf("ConnectRealtime() error = %v", err)
+		}
+	})
+
+	t.Run("contextCancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		session, err := a.ConnectRealtime(ctx)
+		if err != nil {
+			t.Fatalf("ConnectRealtime() error = %v", err)
+		}
+		<-session.Events()
+		cancel()
+		for range session.Events() {
+		}
+		if !errors.Is(session.Err(), context.Canceled) {
+			t.Errorf("Err() = %v, want %v", session.Err(), context.Canceled)
+		}
+	})
+
+	t.Run("closeDuringSend", func(t *testing.T) {
+		session, err := a.ConnectRealtime(context.Background())
+		if err != nil {
+			t.Fatalf("ConnectRealtime() error = %v", err)
+		}
+		// a Send blocked on a stalled connection holds the write lock
+		session.writeMu.Lock()
+		defer session.writeMu.Unlock()
+
+		closed := make(chan error, 1)
+		go func() { closed <- session.Close() }()
+		select {
+		case err := <-closed:
+			if err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Close() blocked on a Send in progress")
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package aoai
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	RoleSystem    = "system"
+	RoleDeveloper = "developer"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// reasoningModelPrefixes are the model families that only accept reasoning parameters.
+var reasoningModelPrefixes = []string{"o1", "o3", "o4", "gpt-5"}
+
+// IsReasoningModel reports whether name, a model or a deployment named after its model, is an o-series style
+// reasoning model. Deployment names are arbitrary, so use WithReasoningModel for deployments that are not named
+// after their model.
+func IsReasoningModel(name string) bool {
+	name = strings.ToLower(name)
+	if strings.HasPrefix(name, "gpt-5") && strings.Contains(name, "chat") {
+		return false
+	}
+	for _, prefix := range reasoningModelPrefixes {
+		if name == prefix || strings.HasPrefix(name, prefix+"-") || strings.HasPrefix(name, prefix+"_") || strings.HasPrefix(name, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// WithReasoningModel declares whether the deployment runs a reasoning model, instead of guessing it from the
+// deployment name with IsReasoningModel.
+func WithReasoningModel(reasoning bool) Option {
+	return func(a *AzureOpenAI) {
+		a.reasoningModel = &reasoning
+	}
+}
+
+// isReasoningRequest reports whether request goes to a reasoning model: by its model when set, by
+// WithReasoningModel, or by the deployment name.
+func (a *AzureOpenAI) isReasoningRequest(request ChatRequest) bool {
+	if request.Model != "" {
+		return IsReasoningModel(request.Model)
+	}
+	if a.reasoningModel != nil {
+		return *a.reasoningModel
+	}
+	return IsReasoningModel(a.deploymentName)
+}
+
+// prepareReasoningRequest rejects the sampling parameters reasoning deployments do not support, moves `max_tokens`
+// to `max_completion_tokens` and sends system messages with the `developer` role when apiVersion supports it.
+func prepareReasoningRequest(request ChatRequest, apiVersion string) (ChatRequest, error) {
+	if request.Temperature != 0 {
+		return request, fmt.Errorf("temperature is not supported by reasoning deployments")
+	}
+	if len(request.LogitBias) > 0 {
+		return request, fmt.Errorf("logit_bias is not supported by reasoning deployments")
+	}
+	if request.MaxTokens != 0 {
+		if request.MaxCompletionTokens != 0 {
+			return request, fmt.Errorf("max_tokens is not supported by reasoning deployments. Use max_completion_tokens instead")
+		}
+		request.MaxCompletionTokens, request.MaxTokens = request.MaxTokens, 0
+	}
+
+	if supported, _ := SupportsFeature(apiVersion, FeatureDeveloperRole); !supported {
+		return request, nil
+	}
+	messages := make([]ChatMessage, len(request.Messages))
+	for i, message := range request.Messages {
+		if message.Role == RoleSystem {
+			message.Role = RoleDeveloper
+		}
+		messages[i] = message
+	}
+	request.Messages = messages
+	return request, nil
+}
@@ -0,0 +1,125 @@
+package aoai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestIsReasoningModel(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "o1", want: true},
+		{name: "o1-mini", want: true},
+		{name: "o3-mini-2025-01-31", want: true},
+		{name: "O4-mini", want: true},
+		{name: "gpt-5", want: true},
+		{name: "gpt-5-chat", want: false},
+		{name: "gpt-4o", want: false},
+		{name: "gpt-35-turbo-0301", want: false},
+		{name: "o100-custom", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsReasoningModel(tt.name); got != tt.want {
+				t.Errorf("IsReasoningModel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_prepareReasoningRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request ChatRequest
+		wantErr bool
+	}{
+		{name: "temperature", request: ChatRequest{Temperature: 0.7}, wantErr: true},
+		{name: "logitBias", request: ChatRequest{LogitBias: map[string]float64{"50256": -100}}, wantErr: true},
+		{name: "bothMaxTokens", request: ChatRequest{MaxTokens: 10, MaxCompletionTokens: 10}, wantErr: true},
+		{name: "supported", request: ChatRequest{MaxTokens: 10, ReasoningEffort: "low"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := prepareReasoningRequest(tt.request, APIVersion20241201Preview); (err != nil) != tt.wantErr {
+				t.Errorf("prepareReasoningRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAzureOpenAI_ChatCompletion_reasoning(t *testing.T) {
+	var sent map[string]any
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"message":{"role":"assistant","content":"4"}}],
+			"usage":{"prompt_tokens":20,"completion_tokens":130,"total_tokens":150,
+			"prompt_tokens_details":{"cached_tokens":16},"completion_tokens_details":{"reasoning_tokens":128}}}`))
+	}))
+	a.deploymentName = "o3-mini"
+	a.apiVersion = APIVersion20241201Preview
+
+	messages := []ChatMessage{{Role: RoleSystem, Content: "Be brief."}, {Role: RoleUser, Content: "2+2?"}}
+	response, err := a.ChatCompletion(context.Background(), ChatRequest{Messages: messages, MaxTokens: 500, ReasoningEffort: "low"})
+	if err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if _, ok := sent["max_tokens"]; ok || sent["max_completion_tokens"] != float64(500) || sent["reasoning_effort"] != "low" {
+		t.Errorf("ChatCompletion() sent %v", sent)
+	}
+	if role := sent["messages"].([]any)[0].(map[string]any)["role"]; role != RoleDeveloper {
+		t.Errorf("system message sent with role %v", role)
+	}
+	if messages[0].Role != RoleSystem {
+		t.Errorf("ChatCompletion() modified the caller's messages")
+	}
+	if response.Usage.CompletionTokensDetails.ReasoningTokens != 128 || response.Usage.PromptTokensDetails.CachedTokens != 16 {
+		t.Errorf("Usage = %+v", response.Usage)
+	}
+
+	sent = nil
+	if _, err := a.ChatCompletion(context.Background(), ChatRequest{Messages: messages, Temperature: 0.5}); err == nil || sent != nil {
+		t.Errorf("ChatCompletion() error = %v, sent %v", err, sent)
+	}
+}
+
+func TestAzureOpenAI_ChatCompletion_reasoningDetection(t *testing.T) {
+	tests := []struct {
+		name       string
+		deployment string
+		options    []Option
+		apiVersion string
+		wantRole   string
+		wantErr    bool
+	}{
+		{name: "named after the model", deployment: "o1", apiVersion: APIVersion20241201Preview, wantRole: RoleDeveloper},
+		{name: "GA api-version keeps the system role", deployment: "o1", apiVersion: APIVersion20241021, wantRole: RoleSystem},
+		{name: "declared reasoning", deployment: "prod-chat", options: []Option{WithReasoningModel(true)}, apiVersion: APIVersion20241201Preview, wantRole: RoleDeveloper},
+		{name: "declared not reasoning", deployment: "o4-team-gpt4o", options: []Option{WithReasoningModel(false)}, apiVersion: APIVersion20241201Preview, wantRole: RoleSystem},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent ChatRequest
+			a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(&sent)
+				_, _ = w.Write([]byte(okChatBody))
+			}))
+			a.deploymentName, a.apiVersion = tt.deployment, tt.apiVersion
+			WithFeatureCheck(FeatureCheckError)(a)
+			for _, option := range tt.options {
+				option(a)
+			}
+
+			messages := []ChatMessage{{Role: RoleSystem, Content: "Be brief."}, {Role: RoleUser, Content: "2+2?"}}
+			if _, err := a.ChatCompletion(context.Background(), ChatRequest{Messages: messages}); err != nil {
+				t.Fatalf("ChatCompletion() error = %v", err)
+			}
+			if role := sent.Messages[0].Role; role != tt.wantRole {
+				t.Errorf("system message sent with role %v, want %v", role, tt.wantRole)
+			}
+		})
+	}
+}
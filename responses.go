@@ -0,0 +1,609 @@
+package aoai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	ResponseStatusQueued     = "queued"
+	ResponseStatusInProgress = "in_progress"
+	ResponseStatusCompleted  = "completed"
+	ResponseStatusFailed     = "failed"
+	ResponseStatusIncomplete = "incomplete"
+	ResponseStatusCancelled  = "cancelled"
+)
+
+type ResponsesRequest struct {
+	// model:
+	//   description: The deployment name. Defaults to the deployment of the client.
+	//   type: string
+	Model string `json:"model,omitempty"`
+
+	// input:
+	//   description: Text, image or file inputs and previous outputs used to generate a response.
+	//   type: []ResponseItem
+	Input []ResponseItem `json:"input,omitempty"`
+
+	// instructions:
+	//   description: A system (or developer) message inserted into the model's context.
+	//   type: string
+	Instructions string `json:"instructions,omitempty"`
+
+	// previous_response_id:
+	//   description: The ID of the previous response, used to continue a conversation stored on the server.
+	//   type: string
+	PreviousResponseID string `json:"previous_response_id,omitempty"`
+
+	// tools:
+	//   description: Built-in tools such as `file_search`, `web_search_preview` and `code_interpreter`, or functions.
+	//   type: []ResponseTool
+	Tools []ResponseTool `json:"tools,omitempty"`
+
+	// tool_choice:
+	//   description: `none`, `auto`, `required` or an object naming a tool.
+	ToolChoice any `json:"tool_choice,omitempty"`
+
+	// parallel_tool_calls:
+	//   type: boolean
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
+
+	// reasoning:
+	//   description: Configuration options for reasoning models.
+	//   type: ResponseReasoning
+	Reasoning *ResponseReasoning `json:"reasoning,omitempty"`
+
+	// text:
+	//   description: Configuration options for a text response, e.g. `{"format": {"type": "json_object"}}`.
+	//   type: object
+	Text json.RawMessage `json:"text,omitempty"`
+
+	// include:
+	//   description: Additional output data to include, e.g. `reasoning.encrypted_content`.
+	//   type: array
+	Include []string `json:"include,omitempty"`
+
+	// temperature:
+	//   type: number
+	//   minimum: 0
+	//   maximum: 2
+	Temperature float64 `json:"temperature,omitempty"`
+
+	// top_p:
+	//   type: number
+	//   minimum: 0
+	//   maximum: 1
+	TopP float64 `json:"top_p,omitempty"`
+
+	// max_output_tokens:
+	//   description: An upper bound for the number of tokens that can be generated, including reasoning tokens.
+	//   type: integer
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+
+	// truncation:
+	//   type: string
+	//   enum:
+	//     - auto
+	//     - disabled
+	Truncation string `json:"truncation,omitempty"`
+
+	// store:
+	//   description: Whether to store the response so that it can be retrieved or chained later. Defaults to true.
+	//   type: boolean
+	Store *bool `json:"store,omitempty"`
+
+	// background:
+	//   description: Whether to run the response in the background so that it can be polled or cancelled.
+	//   type: boolean
+	Background bool `json:"background,omitempty"`
+
+	// stream:
+	//   type: boolean
+	Stream bool `json:"stream,omitempty"`
+
+	// metadata:
+	//   type: object
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// user:
+	//   type: string
+	User string `json:"user,omitempty"`
+}
+
+type ResponseReasoning struct {
+	// effort:
+	//   type: string
+	//   enum:
+	//     - low
+	//     - medium
+	//     - high
+	Effort string `json:"effort,omitempty"`
+
+	// summary:
+	//   type: string
+	//   enum:
+	//     - auto
+	//     - concise
+	//     - detailed
+	Summary string `json:"summary,omitempty"`
+}
+
+type ResponseTool struct {
+	// type:
+	//   type: string
+	//   enum:
+	//     - function
+	//     - file_search
+	//     - web_search_preview
+	//     - code_interpreter
+	//     - image_generation
+	//     - mcp
+	Type string `json:"type"`
+
+	// name:
+	//   description: The function name, for `function` tools.
+	//   type: string
+	Name string `json:"name,omitempty"`
+
+	// description:
+	//   type: string
+	Description string `json:"description,omitempty"`
+
+	// parameters:
+	//   type: object
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+
+	// strict:
+	//   type: boolean
+	Strict bool `json:"strict,omitempty"`
+
+	// vector_store_ids:
+	//   description: The vector stores to search, for `file_search` tools.
+	//   type: array
+	VectorStoreIDs []string `json:"vector_store_ids,omitempty"`
+
+	// container:
+	//   description: The code interpreter container, for `code_interpreter` tools.
+	Container any `json:"container,omitempty"`
+}
+
+// ResponseItem is an input or output item of a response: a message, a function call or its output, a reasoning
+// item or a built-in tool call.
+type ResponseItem struct {
+	// type:
+	//   type: string
+	//   enum:
+	//     - message
+	//     - function_call
+	//     - function_call_output
+	//     - reasoning
+	//     - file_search_call
+	//     - web_search_call
+	//     - code_interpreter_call
+	Type string `json:"type"`
+
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// status:
+	//   type: string
+	//   enum:
+	//     - in_progress
+	//     - completed
+	//     - incomplete
+	Status string `json:"status,omitempty"`
+
+	// role:
+	//   description: The role of a `message` item.
+	//   type: string
+	//   enum:
+	//     - system
+	//     - developer
+	//     - user
+	//     - assistant
+	Role string `json:"role,omitempty"`
+
+	// content:
+	//   description: The content parts of a `message` item.
+	//   type: []ResponseContent
+	Content []ResponseContent `json:"content,omitempty"`
+
+	// call_id:
+	//   description: The ID that links a `function_call` to its `function_call_output`.
+	//   type: string
+	CallID string `json:"call_id,omitempty"`
+
+	// name:
+	//   type: string
+	Name string `json:"name,omitempty"`
+
+	// arguments:
+	//   type: string
+	Arguments string `json:"arguments,omitempty"`
+
+	// output:
+	//   description: The output of a `function_call_output` item.
+	//   type: string
+	Output string `json:"output,omitempty"`
+
+	// summary:
+	//   description: The reasoning summary parts of a `reasoning` item.
+	//   type: []ResponseContent
+	Summary []ResponseContent `json:"summary,omitempty"`
+
+	// encrypted_content:
+	//   type: string
+	EncryptedContent string `json:"encrypted_content,omitempty"`
+}
+
+type ResponseContent struct {
+	// type:
+	//   type: string
+	//   enum:
+	//     - input_text
+	//     - input_image
+	//     - input_file
+	//     - output_text
+	//     - refusal
+	//     - summary_text
+	Type string `json:"type"`
+
+	// text:
+	//   type: string
+	Text string `json:"text,omitempty"`
+
+	// refusal:
+	//   type: string
+	Refusal string `json:"refusal,omitempty"`
+
+	// image_url:
+	//   type: string
+	ImageURL string `json:"image_url,omitempty"`
+
+	// file_id:
+	//   type: string
+	FileID string `json:"file_id,omitempty"`
+
+	// detail:
+	//   type: string
+	//   enum:
+	//     - auto
+	//     - low
+	//     - high
+	Detail string `json:"detail,omitempty"`
+
+	// annotations:
+	//   type: array
+	Annotations []json.RawMessage `json:"annotations,omitempty"`
+}
+
+type Response struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - response
+	Object string `json:"object,omitempty"`
+
+	// created_at:
+	//   type: integer
+	//   format: unixtime
+	CreatedAt int `json:"created_at,omitempty"`
+
+	// status:
+	//   type: string
+	//   enum:
+	//     - queued
+	//     - in_progress
+	//     - completed
+	//     - failed
+	//     - incomplete
+	//     - cancelled
+	Status string `json:"status,omitempty"`
+
+	// error:
+	//   type: Error
+	Error *Error `json:"error,omitempty"`
+
+	// incomplete_details:
+	//   type: object
+	IncompleteDetails *struct {
+		Reason string `json:"reason,omitempty"`
+	} `json:"incomplete_details,omitempty"`
+
+	// model:
+	//   type: string
+	Model string `json:"model,omitempty"`
+
+	// instructions:
+	//   type: string
+	Instructions string `json:"instructions,omitempty"`
+
+	// output:
+	//   type: []ResponseItem
+	Output []ResponseItem `json:"output,omitempty"`
+
+	// previous_response_id:
+	//   type: string
+	PreviousResponseID string `json:"previous_response_id,omitempty"`
+
+	// reasoning:
+	//   type: ResponseReasoning
+	Reasoning *ResponseReasoning `json:"reasoning,omitempty"`
+
+	// tools:
+	//   type: []ResponseTool
+	Tools []ResponseTool `json:"tools,omitempty"`
+
+	// usage:
+	//   type: ResponseUsage
+	Usage *ResponseUsage `json:"usage,omitempty"`
+
+	// temperature:
+	//   type: number
+	Temperature float64 `json:"temperature,omitempty"`
+
+	// top_p:
+	//   type: number
+	TopP float64 `json:"top_p,omitempty"`
+
+	// max_output_tokens:
+	//   type: integer
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+
+	// metadata:
+	//   type: object
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type ResponseUsage struct {
+	// input_tokens:
+	//   type: integer
+	InputTokens int `json:"input_tokens,omitempty"`
+
+	// input_tokens_details:
+	//   type: object
+	InputTokensDetails struct {
+		CachedTokens int `json:"cached_tokens,omitempty"`
+	} `json:"input_tokens_details,omitempty"`
+
+	// output_tokens:
+	//   type: integer
+	OutputTokens int `json:"output_tokens,omitempty"`
+
+	// output_tokens_details:
+	//   type: object
+	OutputTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+	} `json:"output_tokens_details,omitempty"`
+
+	// total_tokens:
+	//   type: integer
+	TotalTokens int `json:"total_tokens,omitempty"`
+}
+
+// OutputText concatenates the text of every `output_text` part of the output messages.
+func (r *Response) OutputText() string {
+	var builder strings.Builder
+	for _, item := range r.Output {
+		if item.Type != "message" {
+			continue
+		}
+		for _, content := range item.Content {
+			if content.Type == "output_text" {
+				builder.WriteString(content.Text)
+			}
+		}
+	}
+	return builder.String()
+}
+
+// FunctionCalls returns the `function_call` items of the output.
+func (r *Response) FunctionCalls() []ResponseItem {
+	var calls []ResponseItem
+	for _, item := range r.Output {
+		if item.Type == "function_call" {
+			calls = append(calls, item)
+		}
+	}
+	return calls
+}
+
+// Next returns a request that continues the conversation after this response with input, relying on the
+// server side state referenced by previous_response_id instead of resending the history.
+func (r *Response) Next(input ...ResponseItem) ResponsesRequest {
+	return ResponsesRequest{
+		Model:              r.Model,
+		Input:              input,
+		PreviousResponseID: r.ID,
+	}
+}
+
+// ResponseStreamEvent is a server-sent event of a streamed response. Only the fields relevant to its Type are set.
+type ResponseStreamEvent struct {
+	// type:
+	//   type: string
+	//   example: response.output_text.delta
+	Type string `json:"type"`
+
+	// sequence_number:
+	//   type: integer
+	SequenceNumber int `json:"sequence_number,omitempty"`
+
+	// response:
+	//   description: Set on `response.created`, `response.in_progress`, `response.completed`, `response.failed` and `response.incomplete`.
+	//   type: Response
+	Response *Response `json:"response,omitempty"`
+
+	// item:
+	//   description: Set on `response.output_item.added` and `response.output_item.done`.
+	//   type: ResponseItem
+	Item *ResponseItem `json:"item,omitempty"`
+
+	// part:
+	//   description: Set on `response.content_part.*` and `response.reasoning_summary_part.*`.
+	//   type: ResponseContent
+	Part *ResponseContent `json:"part,omitempty"`
+
+	// item_id:
+	//   type: string
+	ItemID string `json:"item_id,omitempty"`
+
+	// output_index:
+	//   type: integer
+	OutputIndex int `json:"output_index,omitempty"`
+
+	// content_index:
+	//   type: integer
+	ContentIndex int `json:"content_index,omitempty"`
+
+	// summary_index:
+	//   type: integer
+	SummaryIndex int `json:"summary_index,omitempty"`
+
+	// delta:
+	//   description: Set on `*.delta` events.
+	//   type: string
+	Delta string `json:"delta,omitempty"`
+
+	// text:
+	//   description: Set on `response.output_text.done` and `response.reasoning_summary_text.done`.
+	//   type: string
+	Text string `json:"text,omitempty"`
+
+	// arguments:
+	//   description: Set on `response.function_call_arguments.done`.
+	//   type: string
+	Arguments string `json:"arguments,omitempty"`
+
+	// code:
+	//   description: Set on `error`.
+	//   type: string
+	Code string `json:"code,omitempty"`
+
+	// message:
+	//   description: Set on `error`.
+	//   type: string
+	Message string `json:"message,omitempty"`
+
+	// param:
+	//   description: Set on `error`.
+	//   type: string
+	Param string `json:"param,omitempty"`
+}
+
+// ResponseInputFromChatMessages converts a chat history into Responses input items, so that conversations
+// started with ChatCompletion can be continued with CreateResponse. Tool calls of assistant messages become
+// `function_call` items, tool messages become `function_call_output` items and image parts become `input_image`
+// content.
+func ResponseInputFromChatMessages(messages []ChatMessage) []ResponseItem {
+	items := make([]ResponseItem, 0, len(messages))
+	for _, message := range messages {
+		if message.Role == RoleTool {
+			items = append(items, ResponseItem{Type: "function_call_output", CallID: message.ToolCallID, Output: message.Content})
+			continue
+		}
+
+		content := responseContent(message)
+		if len(content) > 0 || len(message.ToolCalls) == 0 {
+			items = append(items, ResponseItem{Type: "message", Role: message.Role, Content: content})
+		}
+		for _, call := range message.ToolCalls {
+			items = append(items, ResponseItem{
+				Type:      "function_call",
+				CallID:    call.ID,
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			})
+		}
+	}
+	return items
+}
+
+// responseContent converts the content of a chat message into Responses content parts.
+func responseContent(message ChatMessage) []ResponseContent {
+	textType := "input_text"
+	if message.Role == RoleAssistant {
+		textType = "output_text"
+	}
+	if message.ContentParts == nil {
+		if message.Content == "" && len(message.ToolCalls) > 0 {
+			return nil
+		}
+		return []ResponseContent{{Type: textType, Text: message.Content}}
+	}
+
+	content := make([]ResponseContent, 0, len(message.ContentParts))
+	for _, part := range message.ContentParts {
+		switch {
+		case part.Type == "image_url" && part.ImageURL != nil:
+			content = append(content, ResponseContent{Type: "input_image", ImageURL: part.ImageURL.URL, Detail: part.ImageURL.Detail})
+		default:
+			content = append(content, ResponseContent{Type: textType, Text: part.Text})
+		}
+	}
+	return content
+}
+
+func (a *AzureOpenAI) responsesEndpoint(path string) string {
+	return fmt.Sprintf("%s/responses%s?api-version=%s", a.resourceEndpoint(), path, a.apiVersion)
+}
+
+func (a *AzureOpenAI) CreateResponse(ctx context.Context, request ResponsesRequest) (*Response, error) {
+	if request.Stream {
+		return nil, fmt.Errorf("streaming is not supported. Try `CreateResponseStream` instead")
+	}
+	if err := a.checkFeatures([]Feature{FeatureResponses}); err != nil {
+		return nil, err
+	}
+	if request.Model == "" {
+		request.Model = a.deploymentName
+	}
+	return callPost[ResponsesRequest, Response](ctx, a, OperationCreateResponse, a.responsesEndpoint(""), request)
+}
+
+// CreateResponseStream streams the typed events of a response to consumer. An `error` event ends the stream
+// with that error.
+func (a *AzureOpenAI) CreateResponseStream(ctx context.Context, request ResponsesRequest, consumer func(ResponseStreamEvent) error) error {
+	request.Stream = true
+	if err := a.checkFeatures([]Feature{FeatureResponses}); err != nil {
+		return err
+	}
+	if request.Model == "" {
+		request.Model = a.deploymentName
+	}
+	return callEventStream(ctx, a, OperationCreateResponseStream, a.responsesEndpoint(""), request, func(event ServerSentEvent) error {
+		var e ResponseStreamEvent
+		if err := json.Unmarshal(event.Data, &e); err != nil {
+			return err
+		}
+		if e.Type == "" {
+			e.Type = event.Event
+		}
+		if e.Type == "error" {
+			return &Error{Code: e.Code, Message: e.Message, Param: e.Param}
+		}
+		return consumer(e)
+	})
+}
+
+func (a *AzureOpenAI) GetResponse(ctx context.Context, responseID string) (*Response, error) {
+	return callGet[Response](ctx, a, OperationGetResponse, a.responsesEndpoint("/"+url.PathEscape(responseID)))
+}
+
+func (a *AzureOpenAI) DeleteResponse(ctx context.Context, responseID string) (*DeleteResponse, error) {
+	return callDelete[DeleteResponse](ctx, a, OperationDeleteResponse, a.responsesEndpoint("/"+url.PathEscape(responseID)))
+}
+
+// CancelResponse cancels a response created with Background set.
+func (a *AzureOpenAI) CancelResponse(ctx context.Context, responseID string) (*Response, error) {
+	endpoint := a.responsesEndpoint("/" + url.PathEscape(responseID) + "/cancel")
+	return callPost[struct{}, Response](ctx, a, OperationCancelResponse, endpoint, struct{}{})
+}
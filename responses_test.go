@@ -0,0 +1,162 @@
+package aoai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResponseInputFromChatMessages(t *testing.T) {
+	got := ResponseInputFromChatMessages([]ChatMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "Hi", Name: "alice"},
+		{Role: "assistant", Content: "Hello"},
+		{Role: "user", ContentParts: []ChatContentPart{
+			{Type: "text", Text: "What is this?"},
+			{Type: "image_url", ImageURL: &ChatImageURL{URL: "https://example.com/cat.png", Detail: "low"}},
+		}},
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "call-1", Type: "function", Function: FunctionCall{Name: "lookup", Arguments: `{"q":"cat"}`}},
+		}},
+		{Role: "tool", ToolCallID: "call-1", Content: "a cat"},
+	})
+	want := []ResponseItem{
+		{Type: "message", Role: "system", Content: []ResponseContent{{Type: "input_text", Text: "Be brief."}}},
+		{Type: "message", Role: "user", Content: []ResponseContent{{Type: "input_text", Text: "Hi"}}},
+		{Type: "message", Role: "assistant", Content: []ResponseContent{{Type: "output_text", Text: "Hello"}}},
+		{Type: "message", Role: "user", Content: []ResponseContent{
+			{Type: "input_text", Text: "What is this?"},
+			{Type: "input_image", ImageURL: "https://example.com/cat.png", Detail: "low"},
+		}},
+		{Type: "function_call", CallID: "call-1", Name: "lookup", Arguments: `{"q":"cat"}`},
+		{Type: "function_call_output", CallID: "call-1", Output: "a cat"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResponseInputFromChatMessages() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAzureOpenAI_Responses(t *testing.T) {
+	var requests []ResponsesRequest
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /openai/responses":
+			var request ResponsesRequest
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			requests = append(requests, request)
+			if request.PreviousResponseID == "" {
+				_, _ = w.Write([]byte(`{"id":"resp-1","object":"response","status":"completed","model":"gpt-4o","output":[
+					{"type":"reasoning","id":"rs-1","summary":[{"type":"summary_text","text":"Need the weather."}]},
+					{"type":"function_call","id":"fc-1","call_id":"call-1","name":"get_weather","arguments":"{\"city\":\"Tokyo\"}","status":"completed"}
+				],"usage":{"input_tokens":10,"output_tokens":5,"output_tokens_details":{"reasoning_tokens":3},"total_tokens":15}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"resp-2","object":"response","status":"completed","previous_response_id":"resp-1","output":[
+				{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Sunny ","annotations":[]},{"type":"output_text","text":"in Tokyo"}]}
+			]}`))
+		case "GET /openai/responses/resp-1":
+			_, _ = w.Write([]byte(`{"id":"resp-1","status":"completed"}`))
+		case "DELETE /openai/responses/resp-1":
+			_, _ = w.Write([]byte(`{"id":"resp-1","object":"response","deleted":true}`))
+		case "POST /openai/responses/resp-3/cancel":
+			_, _ = w.Write([]byte(`{"id":"resp-3","status":"cancelled"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"404","message":"not found"}}`))
+		}
+	}))
+	ctx := context.Background()
+
+	first, err := a.CreateResponse(ctx, ResponsesRequest{
+		Input:     ResponseInputFromChatMessages([]ChatMessage{{Role: "user", Content: "How is the weather in Tokyo?"}}),
+		Tools:     []ResponseTool{{Type: "function", Name: "get_weather", Parameters: json.RawMessage(`{"type":"object"}`)}},
+		Reasoning: &ResponseReasoning{Effort: "low", Summary: "auto"},
+	})
+	if err != nil {
+		t.Fatalf("CreateResponse() error = %v", err)
+	}
+	if requests[0].Model != a.deploymentName || requests[0].Reasoning.Summary != "auto" {
+		t.Errorf("CreateResponse() sent %+v", requests[0])
+	}
+	calls := first.FunctionCalls()
+	if len(calls) != 1 || calls[0].CallID != "call-1" || first.Output[0].Summary[0].Text != "Need the weather." || first.Usage.OutputTokensDetails.ReasoningTokens != 3 {
+		t.Errorf("CreateResponse() = %+v", first)
+	}
+
+	second, err := a.CreateResponse(ctx, first.Next(ResponseItem{Type: "function_call_output", CallID: calls[0].CallID, Output: "sunny"}))
+	if err != nil {
+		t.Fatalf("CreateResponse() error = %v", err)
+	}
+	if requests[1].PreviousResponseID != "resp-1" || requests[1].Model != "gpt-4o" || requests[1].Input[0].Output != "sunny" {
+		t.Errorf("Next() sent %+v", requests[1])
+	}
+	if second.OutputText() != "Sunny in Tokyo" {
+		t.Errorf("OutputText() = %q", second.OutputText())
+	}
+
+	if got, err := a.GetResponse(ctx, "resp-1"); err != nil || got.ID != "resp-1" {
+		t.Errorf("GetResponse() = %+v, %v", got, err)
+	}
+	if got, err := a.DeleteResponse(ctx, "resp-1"); err != nil || !got.Deleted {
+		t.Errorf("DeleteResponse() = %+v, %v", got, err)
+	}
+	if got, err := a.CancelResponse(ctx, "resp-3"); err != nil || got.Status != ResponseStatusCancelled {
+		t.Errorf("CancelResponse() = %+v, %v", got, err)
+	}
+	if _, err := a.CreateResponse(ctx, ResponsesRequest{Stream: true}); err == nil {
+		t.Errorf("CreateResponse() accepted a streaming request")
+	}
+}
+
+func TestAzureOpenAI_CreateResponseStream(t *testing.T) {
+	stream := strings.Join([]string{
+		`event: response.created` + "\n" + `data: {"type":"response.created","sequence_number":0,"response":{"id":"resp-1","status":"in_progress"}}`,
+		`event: response.output_item.added` + "\n" + `data: {"type":"response.output_item.added","sequence_number":1,"output_index":0,"item":{"type":"message","id":"msg-1","role":"assistant"}}`,
+		`event: response.output_text.delta` + "\n" + `data: {"type":"response.output_text.delta","sequence_number":2,"item_id":"msg-1","delta":"Sunny"}`,
+		`event: response.output_text.delta` + "\n" + `data: {"type":"response.output_text.delta","sequence_number":3,"item_id":"msg-1","delta":" in Tokyo"}`,
+		`event: response.output_text.done` + "\n" + `data: {"type":"response.output_text.done","sequence_number":4,"item_id":"msg-1","text":"Sunny in Tokyo"}`,
+		`event: response.completed` + "\n" + `data: {"type":"response.completed","sequence_number":5,"response":{"id":"resp-1","status":"completed","usage":{"total_tokens":7}}}`,
+	}, "\n\n") + "\n\n"
+
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ResponsesRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		if !request.Stream {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"code":"bad_request","message":"stream must be true"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		if request.User == "broken" {
+			_, _ = w.Write([]byte("event: error\ndata: {\"type\":\"error\",\"code\":\"server_error\",\"message\":\"boom\"}\n\n"))
+			return
+		}
+		_, _ = w.Write([]byte(stream))
+	}))
+
+	var text strings.Builder
+	var completed *Response
+	err := a.CreateResponseStream(context.Background(), ResponsesRequest{Input: []ResponseItem{{Type: "message", Role: "user", Content: []ResponseContent{{Type: "input_text", Text: "Weather?"}}}}}, func(e ResponseStreamEvent) error {
+		switch e.Type {
+		case "response.output_text.delta":
+			text.WriteString(e.Delta)
+		case "response.completed":
+			completed = e.Response
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("CreateResponseStream() error = %v", err)
+	}
+	if text.String() != "Sunny in Tokyo" || completed == nil || completed.Usage.TotalTokens != 7 {
+		t.Errorf("CreateResponseStream() text = %q, completed = %+v", text.String(), completed)
+	}
+
+	err = a.CreateResponseStream(context.Background(), ResponsesRequest{User: "broken"}, func(e ResponseStreamEvent) error { return nil })
+	if e, ok := err.(*Error); !ok || e.Code != "server_error" {
+		t.Errorf("CreateResponseStream() error = %v", err)
+	}
+}
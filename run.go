@@ -0,0 +1,541 @@
+package aoai
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	RunStatusQueued         = "queued"
+	RunStatusInProgress     = "in_progress"
+	RunStatusRequiresAction = "requires_action"
+	RunStatusCancelling     = "cancelling"
+	RunStatusCancelled      = "cancelled"
+	RunStatusFailed         = "failed"
+	RunStatusCompleted      = "completed"
+	RunStatusIncomplete     = "incomplete"
+	RunStatusExpired        = "expired"
+)
+
+type RunRequest struct {
+	// assistant_id:
+	//   type: string
+	AssistantID string `json:"assistant_id"`
+
+	// model:
+	//   description: Overrides the deployment of the assistant for this run.
+	//   type: string
+	//   nullable: true
+	Model string `json:"model,omitempty"`
+
+	// instructions:
+	//   description: Overrides the instructions of the assistant for this run.
+	//   type: string
+	//   nullable: true
+	Instructions string `json:"instructions,omitempty"`
+
+	// additional_instructions:
+	//   description: Appends additional instructions at the end of the instructions for the run.
+	//   type: string
+	//   nullable: true
+	AdditionalInstructions string `json:"additional_instructions,omitempty"`
+
+	// additional_messages:
+	//   description: Adds additional messages to the thread before creating the run.
+	//   type: []MessageRequest
+	AdditionalMessages []MessageRequest `json:"additional_messages,omitempty"`
+
+	// tools:
+	//   description: Overrides the tools of the assistant for this run.
+	//   type: []Tool
+	Tools []Tool `json:"tools,omitempty"`
+
+	// metadata:
+	//   type: object
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// temperature:
+	//   type: number
+	//   minimum: 0
+	//   maximum: 2
+	Temperature float64 `json:"temperature,omitempty"`
+
+	// top_p:
+	//   type: number
+	//   minimum: 0
+	//   maximum: 1
+	TopP float64 `json:"top_p,omitempty"`
+
+	// stream:
+	//   description: If true, returns a stream of events that happen during the run as server-sent events.
+	//   type: boolean
+	Stream bool `json:"stream,omitempty"`
+
+	// max_prompt_tokens:
+	//   type: integer
+	//   minimum: 256
+	MaxPromptTokens int `json:"max_prompt_tokens,omitempty"`
+
+	// max_completion_tokens:
+	//   type: integer
+	//   minimum: 256
+	MaxCompletionTokens int `json:"max_completion_tokens,omitempty"`
+
+	// parallel_tool_calls:
+	//   type: boolean
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
+}
+
+type ThreadAndRunRequest struct {
+	RunRequest
+
+	// thread:
+	//   type: ThreadRequest
+	Thread ThreadRequest `json:"thread,omitempty"`
+}
+
+type Run struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - thread.run
+	Object string `json:"object,omitempty"`
+
+	// created_at:
+	//   type: integer
+	//   format: unixtime
+	CreatedAt int `json:"created_at,omitempty"`
+
+	// thread_id:
+	//   type: string
+	ThreadID string `json:"thread_id,omitempty"`
+
+	// assistant_id:
+	//   type: string
+	AssistantID string `json:"assistant_id,omitempty"`
+
+	// status:
+	//   type: string
+	//   enum:
+	//     - queued
+	//     - in_progress
+	//     - requires_action
+	//     - cancelling
+	//     - cancelled
+	//     - failed
+	//     - completed
+	//     - incomplete
+	//     - expired
+	Status string `json:"status,omitempty"`
+
+	// required_action:
+	//   description: Details on the action required to continue the run. Null if no action is required.
+	//   type: RequiredAction
+	RequiredAction *RequiredAction `json:"required_action,omitempty"`
+
+	// last_error:
+	//   type: Error
+	LastError *Error `json:"last_error,omitempty"`
+
+	// incomplete_details:
+	//   type: object
+	IncompleteDetails *struct {
+		Reason string `json:"reason,omitempty"`
+	} `json:"incomplete_details,omitempty"`
+
+	// expires_at:
+	//   type: integer
+	//   format: unixtime
+	ExpiresAt int `json:"expires_at,omitempty"`
+
+	// started_at:
+	//   type: integer
+	//   format: unixtime
+	StartedAt int `json:"started_at,omitempty"`
+
+	// cancelled_at:
+	//   type: integer
+	//   format: unixtime
+	CancelledAt int `json:"cancelled_at,omitempty"`
+
+	// failed_at:
+	//   type: integer
+	//   format: unixtime
+	FailedAt int `json:"failed_at,omitempty"`
+
+	// completed_at:
+	//   type: integer
+	//   format: unixtime
+	CompletedAt int `json:"completed_at,omitempty"`
+
+	// model:
+	//   type: string
+	Model string `json:"model,omitempty"`
+
+	// instructions:
+	//   type: string
+	Instructions string `json:"instructions,omitempty"`
+
+	// tools:
+	//   type: []Tool
+	Tools []Tool `json:"tools,omitempty"`
+
+	// metadata:
+	//   type: object
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// usage:
+	//   description: Usage statistics related to the run. Null until the run is in a terminal state.
+	//   type: Usage
+	Usage *Usage `json:"usage,omitempty"`
+
+	// temperature:
+	//   type: number
+	Temperature float64 `json:"temperature,omitempty"`
+
+	// top_p:
+	//   type: number
+	TopP float64 `json:"top_p,omitempty"`
+
+	// max_prompt_tokens:
+	//   type: integer
+	MaxPromptTokens int `json:"max_prompt_tokens,omitempty"`
+
+	// max_completion_tokens:
+	//   type: integer
+	MaxCompletionTokens int `json:"max_completion_tokens,omitempty"`
+}
+
+// Terminal reports whether the run reached a state it will never leave.
+func (r *Run) Terminal() bool {
+	switch r.Status {
+	case RunStatusCancelled, RunStatusFailed, RunStatusCompleted, RunStatusIncomplete, RunStatusExpired:
+		return true
+	}
+	return false
+}
+
+type RequiredAction struct {
+	// type:
+	//   type: string
+	//   enum:
+	//     - submit_tool_outputs
+	Type string `json:"type,omitempty"`
+
+	// submit_tool_outputs:
+	//   type: object
+	SubmitToolOutputs struct {
+		ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	} `json:"submit_tool_outputs,omitempty"`
+}
+
+type SubmitToolOutputsRequest struct {
+	// tool_outputs:
+	//   type: []ToolOutput
+	ToolOutputs []ToolOutput `json:"tool_outputs"`
+
+	// stream:
+	//   type: boolean
+	Stream bool `json:"stream,omitempty"`
+}
+
+type ToolOutput struct {
+	// tool_call_id:
+	//   type: string
+	ToolCallID string `json:"tool_call_id"`
+
+	// output:
+	//   type: string
+	Output string `json:"output"`
+}
+
+type RunStep struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - thread.run.step
+	Object string `json:"object,omitempty"`
+
+	// created_at:
+	//   type: integer
+	//   format: unixtime
+	CreatedAt int `json:"created_at,omitempty"`
+
+	// assistant_id:
+	//   type: string
+	AssistantID string `json:"assistant_id,omitempty"`
+
+	// thread_id:
+	//   type: string
+	ThreadID string `json:"thread_id,omitempty"`
+
+	// run_id:
+	//   type: string
+	RunID string `json:"run_id,omitempty"`
+
+	// type:
+	//   type: string
+	//   enum:
+	//     - message_creation
+	//     - tool_calls
+	Type string `json:"type,omitempty"`
+
+	// status:
+	//   type: string
+	//   enum:
+	//     - in_progress
+	//     - cancelled
+	//     - failed
+	//     - completed
+	//     - expired
+	Status string `json:"status,omitempty"`
+
+	// step_details:
+	//   type: RunStepDetails
+	StepDetails RunStepDetails `json:"step_details,omitempty"`
+
+	// last_error:
+	//   type: Error
+	LastError *Error `json:"last_error,omitempty"`
+
+	// expired_at:
+	//   type: integer
+	//   format: unixtime
+	ExpiredAt int `json:"expired_at,omitempty"`
+
+	// cancelled_at:
+	//   type: integer
+	//   format: unixtime
+	CancelledAt int `json:"cancelled_at,omitempty"`
+
+	// failed_at:
+	//   type: integer
+	//   format: unixtime
+	FailedAt int `json:"failed_at,omitempty"`
+
+	// completed_at:
+	//   type: integer
+	//   format: unixtime
+	CompletedAt int `json:"completed_at,omitempty"`
+
+	// usage:
+	//   type: Usage
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+type RunStepDetails struct {
+	// type:
+	//   type: string
+	//   enum:
+	//     - message_creation
+	//     - tool_calls
+	Type string `json:"type,omitempty"`
+
+	// message_creation:
+	//   type: object
+	MessageCreation *struct {
+		MessageID string `json:"message_id,omitempty"`
+	} `json:"message_creation,omitempty"`
+
+	// tool_calls:
+	//   type: []RunStepToolCall
+	ToolCalls []RunStepToolCall `json:"tool_calls,omitempty"`
+}
+
+type RunStepToolCall struct {
+	// index:
+	//   description: The index of the tool call, only set on run step deltas.
+	//   type: integer
+	Index int `json:"index,omitempty"`
+
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// type:
+	//   type: string
+	//   enum:
+	//     - code_interpreter
+	//     - file_search
+	//     - function
+	Type string `json:"type,omitempty"`
+
+	// code_interpreter:
+	//   type: CodeInterpreterCall
+	CodeInterpreter *CodeInterpreterCall `json:"code_interpreter,omitempty"`
+
+	// file_search:
+	//   type: object
+	FileSearch map[string]any `json:"file_search,omitempty"`
+
+	// function:
+	//   type: RunStepFunctionCall
+	Function *RunStepFunctionCall `json:"function,omitempty"`
+}
+
+type CodeInterpreterCall struct {
+	// input:
+	//   type: string
+	Input string `json:"input,omitempty"`
+
+	// outputs:
+	//   type: array
+	Outputs []CodeInterpreterOutput `json:"outputs,omitempty"`
+}
+
+type CodeInterpreterOutput struct {
+	// index:
+	//   type: integer
+	Index int `json:"index,omitempty"`
+
+	// type:
+	//   type: string
+	//   enum:
+	//     - logs
+	//     - image
+	Type string `json:"type,omitempty"`
+
+	// logs:
+	//   type: string
+	Logs string `json:"logs,omitempty"`
+
+	// image:
+	//   type: object
+	Image *struct {
+		FileID string `json:"file_id,omitempty"`
+	} `json:"image,omitempty"`
+}
+
+type RunStepFunctionCall struct {
+	// name:
+	//   type: string
+	Name string `json:"name,omitempty"`
+
+	// arguments:
+	//   type: string
+	Arguments string `json:"arguments,omitempty"`
+
+	// output:
+	//   description: The output of the function. Null if the outputs have not been submitted yet.
+	//   type: string
+	Output string `json:"output,omitempty"`
+}
+
+// ToolHandler computes the output of a function tool call requested by a run.
+type ToolHandler func(ctx context.Context, call ToolCall) (string, error)
+
+func (a *AzureOpenAI) runsEndpoint(threadID string, path string, values url.Values) string {
+	return a.assistantsEndpoint("/threads/"+url.PathEscape(threadID)+"/runs"+path, values)
+}
+
+func (a *AzureOpenAI) CreateRun(ctx context.Context, threadID string, request RunRequest) (*Run, error) {
+	if request.Stream {
+		return nil, fmt.Errorf("streaming is not supported. Try `CreateRunStream` instead")
+	}
+	endpoint := a.runsEndpoint(threadID, "", nil)
+	return callPost[RunRequest, Run](ctx, a, OperationCreateRun, endpoint, request)
+}
+
+func (a *AzureOpenAI) CreateThreadAndRun(ctx context.Context, request ThreadAndRunRequest) (*Run, error) {
+	if request.Stream {
+		return nil, fmt.Errorf("streaming is not supported. Try `CreateThreadAndRunStream` instead")
+	}
+	endpoint := a.assistantsEndpoint("/threads/runs", nil)
+	return callPost[ThreadAndRunRequest, Run](ctx, a, OperationCreateThreadAndRun, endpoint, request)
+}
+
+func (a *AzureOpenAI) GetRun(ctx context.Context, threadID string, runID string) (*Run, error) {
+	endpoint := a.runsEndpoint(threadID, "/"+url.PathEscape(runID), nil)
+	return callGet[Run](ctx, a, OperationGetRun, endpoint)
+}
+
+func (a *AzureOpenAI) ListRuns(ctx context.Context, threadID string, options ListOptions) (*ListResponse[Run], error) {
+	endpoint := a.runsEndpoint(threadID, "", options.values(a.apiVersion))
+	return callGet[ListResponse[Run]](ctx, a, OperationListRuns, endpoint)
+}
+
+func (a *AzureOpenAI) CancelRun(ctx context.Context, threadID string, runID string) (*Run, error) {
+	endpoint := a.runsEndpoint(threadID, "/"+url.PathEscape(runID)+"/cancel", nil)
+	return callPost[struct{}, Run](ctx, a, OperationCancelRun, endpoint, struct{}{})
+}
+
+func (a *AzureOpenAI) SubmitToolOutputs(ctx context.Context, threadID string, runID string, request SubmitToolOutputsRequest) (*Run, error) {
+	if request.Stream {
+		return nil, fmt.Errorf("streaming is not supported. Try `SubmitToolOutputsStream` instead")
+	}
+	endpoint := a.runsEndpoint(threadID, "/"+url.PathEscape(runID)+"/submit_tool_outputs", nil)
+	return callPost[SubmitToolOutputsRequest, Run](ctx, a, OperationSubmitToolOutputs, endpoint, request)
+}
+
+func (a *AzureOpenAI) GetRunStep(ctx context.Context, threadID string, runID string, stepID string) (*RunStep, error) {
+	endpoint := a.runsEndpoint(threadID, "/"+url.PathEscape(runID)+"/steps/"+url.PathEscape(stepID), nil)
+	return callGet[RunStep](ctx, a, OperationGetRunStep, endpoint)
+}
+
+func (a *AzureOpenAI) ListRunSteps(ctx context.Context, threadID string, runID string, options ListOptions) (*ListResponse[RunStep], error) {
+	endpoint := a.runsEndpoint(threadID, "/"+url.PathEscape(runID)+"/steps", options.values(a.apiVersion))
+	return callGet[ListResponse[RunStep]](ctx, a, OperationListRunSteps, endpoint)
+}
+
+// cancelRunTimeout bounds the CancelRun call that CompleteRun makes after handler fails.
+var cancelRunTimeout = 10 * time.Second
+
+// WaitRun polls the run with backoff until it reaches a terminal state or requires action.
+func (a *AzureOpenAI) WaitRun(ctx context.Context, threadID string, runID string, options PollOptions) (*Run, error) {
+	return poll[Run](ctx, options, func(ctx context.Context) (*Run, error) {
+		return a.GetRun(ctx, threadID, runID)
+	}, func(run *Run) bool {
+		return run.Terminal() || run.Status == RunStatusRequiresAction
+	})
+}
+
+// CompleteRun waits for the run to finish, answering every `submit_tool_outputs` action by calling handler for
+// each requested tool call. An error returned by handler cancels the run and is returned as is.
+func (a *AzureOpenAI) CompleteRun(ctx context.Context, threadID string, runID string, options PollOptions, handler ToolHandler) (*Run, error) {
+	for {
+		run, err := a.WaitRun(ctx, threadID, runID, options)
+		if err != nil {
+			return nil, err
+		}
+		if run.Status != RunStatusRequiresAction {
+			return run, nil
+		}
+		if run.RequiredAction == nil || run.RequiredAction.Type != "submit_tool_outputs" {
+			return run, fmt.Errorf("unsupported required action on run %s", run.ID)
+		}
+
+		request, err := handleToolCalls(ctx, run.RequiredAction.SubmitToolOutputs.ToolCalls, handler)
+		if err != nil {
+			// cancel even if ctx is done, but do not wait on a hung endpoint forever
+			cancelCtx, cancel := context.WithTimeout(context.Background(), cancelRunTimeout)
+			_, _ = a.CancelRun(cancelCtx, threadID, runID)
+			cancel()
+			return run, err
+		}
+		if _, err := a.SubmitToolOutputs(ctx, threadID, runID, request); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func handleToolCalls(ctx context.Context, calls []ToolCall, handler ToolHandler) (SubmitToolOutputsRequest, error) {
+	request := SubmitToolOutputsRequest{ToolOutputs: make([]ToolOutput, 0, len(calls))}
+	for _, call := range calls {
+		output, err := handler(ctx, call)
+		if err != nil {
+			return request, err
+		}
+		request.ToolOutputs = append(request.ToolOutputs, ToolOutput{ToolCallID: call.ID, Output: output})
+	}
+	return request, nil
+}
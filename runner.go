@@ -0,0 +1,333 @@
+package aoai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Runner executes a JSONL file of requests against a single deployment from the client side.
+//
+// The input file uses the same line format as a Batch API input file (see BatchInputLine), and `url` selects the
+// operation: `/chat/completions`, `/completions` or `/embeddings`. Results are written to the output file in input
+// order using the BatchOutputLine format. Every finished request is journaled to a progress file next to the
+// output, so running the same input again after a crash only sends the requests that have not finished yet.
+type Runner struct {
+	client            *AzureOpenAI
+	concurrency       int
+	requestsPerMinute int
+}
+
+type RunnerStats struct {
+	Total     int
+	Resumed   int
+	Succeeded int
+	Failed    int
+}
+
+// runnerProgress is a single line of the progress file.
+type runnerProgress struct {
+	Index  int             `json:"index"`
+	Output BatchOutputLine `json:"output"`
+}
+
+// NewRunner creates a Runner sending at most concurrency requests at once. If requestsPerMinute is positive,
+// requests are additionally paced to stay within that rate.
+func NewRunner(client *AzureOpenAI, concurrency int, requestsPerMinute int) *Runner {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Runner{
+		client:            client,
+		concurrency:       concurrency,
+		requestsPerMinute: requestsPerMinute,
+	}
+}
+
+func (r *Runner) progressPath(outputPath string) string {
+	return outputPath + ".progress"
+}
+
+// RunFile executes every request of inputPath that is not yet recorded as finished and, once all of them have
+// finished, writes outputPath and removes the progress file. Requests rejected by the API or by the client itself,
+// e.g. a streaming or invalid request, are finished with the error recorded in their output line; requests that are
+// throttled (429), fail with a server error (5xx), a network error or a cancelled ctx are left unfinished and RunFile
+// returns an error so that they are retried on the next run.
+func (r *Runner) RunFile(ctx context.Context, inputPath string, outputPath string) (*RunnerStats, error) {
+	lines, err := readRunnerInput(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	progressPath := r.progressPath(outputPath)
+	outputs, err := readRunnerProgress(progressPath, len(lines))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &RunnerStats{Total: len(lines), Resumed: len(outputs)}
+	for _, output := range outputs {
+		stats.count(output)
+	}
+	pending := make([]int, 0, len(lines)-len(outputs))
+	for index := range lines {
+		if _, ok := outputs[index]; !ok {
+			pending = append(pending, index)
+		}
+	}
+
+	progress, err := os.OpenFile(progressPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer progress.Close()
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		runErrors []error
+		encoder   = json.NewEncoder(progress)
+		semaphore = make(chan struct{}, r.concurrency)
+	)
+
+	var ticker *time.Ticker
+	if r.requestsPerMinute > 0 {
+		ticker = time.NewTicker(time.Minute / time.Duration(r.requestsPerMinute))
+		defer ticker.Stop()
+	}
+
+dispatch:
+	for i, index := range pending {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case semaphore <- struct{}{}:
+		}
+		if ticker != nil && i > 0 {
+			select {
+			case <-ctx.Done():
+				<-semaphore
+				break dispatch
+			case <-ticker.C:
+			}
+		}
+
+		wg.Add(1)
+		go func(index int, line BatchInputLine[json.RawMessage]) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			output, err := r.execute(ctx, line)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				runErrors = append(runErrors, fmt.Errorf("%s: %w", line.CustomID, err))
+				return
+			}
+			if err := encoder.Encode(runnerProgress{Index: index, Output: *output}); err != nil {
+				runErrors = append(runErrors, err)
+				return
+			}
+			outputs[index] = *output
+			stats.count(*output)
+		}(index, lines[index])
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
+	if len(runErrors) > 0 {
+		return stats, fmt.Errorf("%d of %d requests did not finish, run again to resume: %w",
+			len(lines)-len(outputs), len(lines), errors.Join(runErrors...))
+	}
+
+	if err := writeRunnerOutput(outputPath, lines, outputs); err != nil {
+		return stats, err
+	}
+	if err := progress.Close(); err != nil {
+		return stats, err
+	}
+	return stats, os.Remove(progressPath)
+}
+
+func (s *RunnerStats) count(output BatchOutputLine) {
+	if output.Error != nil {
+		s.Failed++
+	} else {
+		s.Succeeded++
+	}
+}
+
+// execute sends a single input line. Errors that would occur again on the next run are returned as part of the
+// output line, and transient errors (see runnerTransient) are returned as is.
+func (r *Runner) execute(ctx context.Context, line BatchInputLine[json.RawMessage]) (*BatchOutputLine, error) {
+	var (
+		response any
+		err      error
+	)
+	switch strings.TrimSuffix(line.URL, "/") {
+	case "/chat/completions":
+		response, err = runnerCall(ctx, line.Body, r.client.ChatCompletion)
+	case "/completions":
+		response, err = runnerCall(ctx, line.Body, r.client.Completion)
+	case "/embeddings":
+		response, err = runnerCall(ctx, line.Body, r.client.Embedding)
+	default:
+		err = &Error{Code: "invalid_url", Message: fmt.Sprintf("unsupported url: %s", line.URL), Param: "url"}
+	}
+
+	output := &BatchOutputLine{CustomID: line.CustomID}
+	var apiError *Error
+	switch {
+	case err == nil:
+	case runnerTransient(err):
+		return nil, err
+	case errors.As(err, &apiError):
+		output.Error = apiError
+		return output, nil
+	default:
+		output.Error = &Error{Code: "invalid_request", Message: err.Error()}
+		return output, nil
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	output.Response = &BatchOutputResponse{StatusCode: 200, Body: body}
+	return output, nil
+}
+
+// runnerTransient reports whether err may not occur again on the next run: throttling, server and network errors,
+// an open circuit, an exhausted budget or a cancelled context.
+func runnerTransient(err error) bool {
+	var (
+		apiError *Error
+		netError net.Error
+	)
+	if errors.As(err, &apiError) {
+		return apiError.StatusCode == http.StatusTooManyRequests || apiError.StatusCode >= 500
+	}
+	return errors.As(err, &netError) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrCircuitOpen) ||
+		errors.Is(err, ErrNoBackendAvailable) ||
+		errors.Is(err, ErrBudgetExceeded)
+}
+
+func runnerCall[S, T any](ctx context.Context, body json.RawMessage, call func(context.Context, S) (*T, error)) (*T, error) {
+	var request S
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, &Error{Code: "invalid_body", Message: err.Error(), Param: "body"}
+	}
+	return call(ctx, request)
+}
+
+func readRunnerInput(path string) ([]BatchInputLine[json.RawMessage], error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []BatchInputLine[json.RawMessage]
+	customIDs := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		m := strings.TrimSpace(scanner.Text())
+		if m == "" {
+			continue
+		}
+
+		var line BatchInputLine[json.RawMessage]
+		if err := json.Unmarshal([]byte(m), &line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, number, err)
+		}
+		if line.CustomID == "" {
+			line.CustomID = fmt.Sprintf("line-%d", number)
+		}
+		if customIDs[line.CustomID] {
+			return nil, fmt.Errorf("%s:%d: duplicate custom_id %q", path, number, line.CustomID)
+		}
+		customIDs[line.CustomID] = true
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// readRunnerProgress loads the outputs recorded by a previous run. A truncated last line left behind by a crash
+// is ignored, and any other line that cannot be parsed is an error.
+func readRunnerProgress(path string, total int) (map[int]BatchOutputLine, error) {
+	outputs := map[int]BatchOutputLine{}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return outputs, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	var corrupt error
+	for number := 1; scanner.Scan(); number++ {
+		if corrupt != nil {
+			return nil, corrupt
+		}
+		var progress runnerProgress
+		if err := json.Unmarshal(scanner.Bytes(), &progress); err != nil {
+			corrupt = fmt.Errorf("%s:%d: %w", path, number, err)
+			continue
+		}
+		if progress.Index < 0 || progress.Index >= total {
+			return nil, fmt.Errorf("%s does not belong to this input: index %d out of range", path, progress.Index)
+		}
+		outputs[progress.Index] = progress.Output
+	}
+	return outputs, scanner.Err()
+}
+
+func writeRunnerOutput(path string, lines []BatchInputLine[json.RawMessage], outputs map[int]BatchOutputLine) error {
+	temporary := path + ".tmp"
+	file, err := os.Create(temporary)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for index, line := range lines {
+		output := outputs[index]
+		if output.CustomID != line.CustomID {
+			file.Close()
+			return fmt.Errorf("progress does not match input at line %d: custom_id %q, want %q", index+1, output.CustomID, line.CustomID)
+		}
+		if err := encoder.Encode(output); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temporary, path)
+}
@@ -0,0 +1,261 @@
+package aoai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRunner_RunFile(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		calls    = map[string]int{}
+		brokenUp = true
+	)
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		key, _ := body["user"].(string)
+
+		mu.Lock()
+		calls[key]++
+		broken := brokenUp && (key == "flaky" || key == "throttled")
+		mu.Unlock()
+
+		switch {
+		case broken && key == "throttled":
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"code":"429","message":"rate limit exceeded"}}`))
+		case broken:
+			// drop the connection to emulate a network failure
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		case key == "rejected":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"code":"content_filter","message":"filtered"}}`))
+		case strings.HasSuffix(r.URL.Path, "/chat/completions"):
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"` + key + `"}}]}`))
+		case strings.HasSuffix(r.URL.Path, "/completions"):
+			_, _ = w.Write([]byte(`{"choices":[{"text":"` + key + `"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/embeddings"):
+			_, _ = w.Write([]byte(`{"data":[{"embedding":[0.5]}]}`))
+		}
+	}))
+
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "input.jsonl")
+	outputPath := filepath.Join(dir, "output.jsonl")
+	input := strings.Join([]string{
+		`{"custom_id":"chat","method":"POST","url":"/chat/completions","body":{"messages":[{"role":"user","content":"hi"}],"user":"chat"}}`,
+		`{"custom_id":"completion","method":"POST","url":"/completions","body":{"prompt":["hi"],"user":"completion"}}`,
+		``,
+		`{"custom_id":"embedding","method":"POST","url":"/embeddings","body":{"input":["hi"],"user":"embedding"}}`,
+		`{"custom_id":"rejected","method":"POST","url":"/chat/completions","body":{"messages":[],"user":"rejected"}}`,
+		`{"custom_id":"flaky","method":"POST","url":"/chat/completions","body":{"messages":[],"user":"flaky"}}`,
+		`{"custom_id":"unknown","method":"POST","url":"/images/generations","body":{}}`,
+		`{"custom_id":"throttled","method":"POST","url":"/chat/completions","body":{"messages":[],"user":"throttled"}}`,
+	}, "\n")
+	if err := os.WriteFile(inputPath, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := NewRunner(a, 3, 0)
+
+	stats, err := runner.RunFile(context.Background(), inputPath, outputPath)
+	if err == nil || !strings.Contains(err.Error(), "flaky") || !strings.Contains(err.Error(), "throttled") {
+		t.Fatalf("RunFile() error = %v, want an error for the flaky and throttled requests", err)
+	}
+	if stats.Succeeded != 3 || stats.Failed != 2 {
+		t.Errorf("RunFile() stats = %+v", stats)
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Errorf("RunFile() wrote output before every request finished")
+	}
+
+	mu.Lock()
+	brokenUp = false
+	mu.Unlock()
+
+	stats, err = runner.RunFile(context.Background(), inputPath, outputPath)
+	if err != nil {
+		t.Fatalf("RunFile() error = %v", err)
+	}
+	if stats.Total != 7 || stats.Resumed != 5 || stats.Succeeded != 5 || stats.Failed != 2 {
+		t.Errorf("RunFile() stats = %+v", stats)
+	}
+	for _, key := range []string{"chat", "completion", "embedding", "rejected"} {
+		if calls[key] != 1 {
+			t.Errorf("RunFile() sent %s %d times, want 1", key, calls[key])
+		}
+	}
+	if _, err := os.Stat(runner.progressPath(outputPath)); !os.IsNotExist(err) {
+		t.Errorf("RunFile() left progress file behind")
+	}
+
+	file, err := os.Open(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var customIDs []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line BatchOutputLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatal(err)
+		}
+		customIDs = append(customIDs, line.CustomID)
+
+		switch line.CustomID {
+		case "rejected", "unknown":
+			if line.Error == nil {
+				t.Errorf("output %s has no error", line.CustomID)
+			}
+		case "flaky", "throttled":
+			var response ChatResponse
+			_ = json.Unmarshal(line.Response.Body, &response)
+			if response.Choices[0].Message.Content != line.CustomID {
+				t.Errorf("output %s = %s", line.CustomID, line.Response.Body)
+			}
+		}
+	}
+	if got := strings.Join(customIDs, ","); got != "chat,completion,embedding,rejected,flaky,unknown,throttled" {
+		t.Errorf("output order = %s", got)
+	}
+}
+
+func Test_readRunnerInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "missingCustomID",
+			input: `{"url":"/embeddings","body":{}}` + "\n\n" + `{"custom_id":"x","url":"/embeddings","body":{}}`,
+			want:  []string{"line-1", "x"},
+		},
+		{
+			name:    "duplicateCustomID",
+			input:   `{"custom_id":"x","url":"/embeddings"}` + "\n" + `{"custom_id":"x","url":"/embeddings"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalidJson",
+			input:   `{"custom_id":`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "input.jsonl")
+			if err := os.WriteFile(path, []byte(tt.input), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			lines, err := readRunnerInput(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readRunnerInput() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var got []string
+			for _, line := range lines {
+				got = append(got, line.CustomID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("readRunnerInput() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunner_RunFile_clientErrors(t *testing.T) {
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	a.validate = true
+
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "input.jsonl")
+	outputPath := filepath.Join(dir, "output.jsonl")
+	input := strings.Join([]string{
+		`{"custom_id":"chat","method":"POST","url":"/chat/completions","body":{"messages":[{"role":"user","content":"hi"}]}}`,
+		`{"custom_id":"stream","method":"POST","url":"/chat/completions","body":{"messages":[{"role":"user","content":"hi"}],"stream":true}}`,
+		`{"custom_id":"invalid","method":"POST","url":"/chat/completions","body":{"messages":[{"role":"user","content":"hi"}],"temperature":5}}`,
+	}, "\n")
+	if err := os.WriteFile(inputPath, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := NewRunner(a, 2, 0).RunFile(context.Background(), inputPath, outputPath)
+	if err != nil {
+		t.Fatalf("RunFile() error = %v", err)
+	}
+	if stats.Succeeded != 1 || stats.Failed != 2 {
+		t.Errorf("RunFile() stats = %+v", stats)
+	}
+
+	m, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(m)), "\n") {
+		var output BatchOutputLine
+		if err := json.Unmarshal([]byte(line), &output); err != nil {
+			t.Fatal(err)
+		}
+		if (output.Error != nil) != (output.CustomID != "chat") {
+			t.Errorf("output %s error = %v", output.CustomID, output.Error)
+		}
+	}
+}
+
+func Test_readRunnerProgress(t *testing.T) {
+	const line = `{"index":0,"output":{"custom_id":"x"}}`
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{
+			name:  "truncatedLastLine",
+			input: line + "\n" + `{"index":1,"out`,
+			want:  1,
+		},
+		{
+			name:    "corruptLine",
+			input:   `{"index":1,"out` + "\n" + line,
+			wantErr: true,
+		},
+		{
+			name:    "outOfRange",
+			input:   `{"index":5,"output":{}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "output.jsonl.progress")
+			if err := os.WriteFile(path, []byte(tt.input), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			outputs, err := readRunnerProgress(path, 2)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readRunnerProgress() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(outputs) != tt.want {
+				t.Errorf("readRunnerProgress() = %v, want %d outputs", outputs, tt.want)
+			}
+		})
+	}
+}
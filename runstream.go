@@ -0,0 +1,322 @@
+package aoai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type MessageDelta struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - thread.message.delta
+	Object string `json:"object,omitempty"`
+
+	// delta:
+	//   type: object
+	Delta struct {
+		Role    string           `json:"role,omitempty"`
+		Content []MessageContent `json:"content,omitempty"`
+	} `json:"delta,omitempty"`
+}
+
+type RunStepDelta struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - thread.run.step.delta
+	Object string `json:"object,omitempty"`
+
+	// delta:
+	//   type: object
+	Delta struct {
+		StepDetails RunStepDetails `json:"step_details,omitempty"`
+	} `json:"delta,omitempty"`
+}
+
+// Handlers of the events of a run stream, combined in RunStreamHandler. Events are named as in the API, e.g.
+// `thread.run.requires_action`.
+type (
+	ThreadEventHandler interface {
+		OnThread(event string, thread *Thread) error
+	}
+
+	RunEventHandler interface {
+		OnRun(event string, run *Run) error
+	}
+
+	RunStepEventHandler interface {
+		OnRunStep(event string, step *RunStep) error
+	}
+
+	// RunStepDeltaEventHandler receives each delta together with the run step accumulated so far.
+	RunStepDeltaEventHandler interface {
+		OnRunStepDelta(delta *RunStepDelta, step *RunStep) error
+	}
+
+	MessageEventHandler interface {
+		OnMessage(event string, message *ThreadMessage) error
+	}
+
+	// MessageDeltaEventHandler receives each delta together with the message accumulated so far.
+	MessageDeltaEventHandler interface {
+		OnMessageDelta(delta *MessageDelta, message *ThreadMessage) error
+	}
+
+	// ErrorEventHandler receives `error` events. Returning e ends the stream with that error.
+	ErrorEventHandler interface {
+		OnError(e *Error) error
+	}
+)
+
+// RunStreamHandler receives the events of CreateRunStream, CreateThreadAndRunStream and SubmitToolOutputsStream.
+// Embed NopRunStreamHandler to implement only the methods of interest.
+type RunStreamHandler interface {
+	ThreadEventHandler
+	RunEventHandler
+	RunStepEventHandler
+	RunStepDeltaEventHandler
+	MessageEventHandler
+	MessageDeltaEventHandler
+	ErrorEventHandler
+}
+
+// NopRunStreamHandler skips every event except `error` events, which end the stream with their error.
+type NopRunStreamHandler struct{}
+
+func (NopRunStreamHandler) OnThread(string, *Thread) error                     { return nil }
+func (NopRunStreamHandler) OnRun(string, *Run) error                           { return nil }
+func (NopRunStreamHandler) OnRunStep(string, *RunStep) error                   { return nil }
+func (NopRunStreamHandler) OnRunStepDelta(*RunStepDelta, *RunStep) error       { return nil }
+func (NopRunStreamHandler) OnMessage(string, *ThreadMessage) error             { return nil }
+func (NopRunStreamHandler) OnMessageDelta(*MessageDelta, *ThreadMessage) error { return nil }
+func (NopRunStreamHandler) OnError(e *Error) error                             { return e }
+
+// RunStreamResult is the state of a run accumulated from its stream.
+type RunStreamResult struct {
+	Thread   *Thread
+	Run      *Run
+	Messages []*ThreadMessage
+	Steps    []*RunStep
+}
+
+func (r *RunStreamResult) message(id string) *ThreadMessage {
+	for _, message := range r.Messages {
+		if message.ID == id {
+			return message
+		}
+	}
+	message := &ThreadMessage{ID: id}
+	r.Messages = append(r.Messages, message)
+	return message
+}
+
+func (r *RunStreamResult) step(id string) *RunStep {
+	for _, step := range r.Steps {
+		if step.ID == id {
+			return step
+		}
+	}
+	step := &RunStep{ID: id}
+	r.Steps = append(r.Steps, step)
+	return step
+}
+
+func (a *AzureOpenAI) CreateRunStream(ctx context.Context, threadID string, request RunRequest, handler RunStreamHandler) (*RunStreamResult, error) {
+	request.Stream = true
+	endpoint := a.runsEndpoint(threadID, "", nil)
+	return runStream(ctx, a, OperationCreateRunStream, endpoint, request, handler)
+}
+
+func (a *AzureOpenAI) CreateThreadAndRunStream(ctx context.Context, request ThreadAndRunRequest, handler RunStreamHandler) (*RunStreamResult, error) {
+	request.Stream = true
+	endpoint := a.assistantsEndpoint("/threads/runs", nil)
+	return runStream(ctx, a, OperationCreateThreadAndRunStream, endpoint, request, handler)
+}
+
+func (a *AzureOpenAI) SubmitToolOutputsStream(ctx context.Context, threadID string, runID string, request SubmitToolOutputsRequest, handler RunStreamHandler) (*RunStreamResult, error) {
+	request.Stream = true
+	endpoint := a.runsEndpoint(threadID, "/"+url.PathEscape(runID)+"/submit_tool_outputs", nil)
+	return runStream(ctx, a, OperationSubmitToolOutputsStream, endpoint, request, handler)
+}
+
+func runStream[S any](ctx context.Context, a *AzureOpenAI, operation string, endpoint string, request S, handler RunStreamHandler) (*RunStreamResult, error) {
+	if handler == nil {
+		handler = NopRunStreamHandler{}
+	}
+	result := &RunStreamResult{}
+	err := callEventStream(ctx, a, operation, endpoint, request, func(event ServerSentEvent) error {
+		return result.dispatch(event.Event, event.Data, handler)
+	})
+	return result, err
+}
+
+func (r *RunStreamResult) dispatch(event string, data []byte, handler RunStreamHandler) error {
+	switch {
+	case event == "thread.created":
+		var thread Thread
+		if err := json.Unmarshal(data, &thread); err != nil {
+			return err
+		}
+		r.Thread = &thread
+		return handler.OnThread(event, &thread)
+
+	case event == "thread.run.step.delta":
+		var delta RunStepDelta
+		if err := json.Unmarshal(data, &delta); err != nil {
+			return err
+		}
+		step := r.step(delta.ID)
+		if err := step.applyDelta(&delta); err != nil {
+			return err
+		}
+		return handler.OnRunStepDelta(&delta, step)
+
+	case strings.HasPrefix(event, "thread.run.step."):
+		var step RunStep
+		if err := json.Unmarshal(data, &step); err != nil {
+			return err
+		}
+		*r.step(step.ID) = step
+		return handler.OnRunStep(event, &step)
+
+	case strings.HasPrefix(event, "thread.run."):
+		var run Run
+		if err := json.Unmarshal(data, &run); err != nil {
+			return err
+		}
+		r.Run = &run
+		return handler.OnRun(event, &run)
+
+	case event == "thread.message.delta":
+		var delta MessageDelta
+		if err := json.Unmarshal(data, &delta); err != nil {
+			return err
+		}
+		message := r.message(delta.ID)
+		if err := message.applyDelta(&delta); err != nil {
+			return err
+		}
+		return handler.OnMessageDelta(&delta, message)
+
+	case strings.HasPrefix(event, "thread.message."):
+		var message ThreadMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			return err
+		}
+		*r.message(message.ID) = message
+		return handler.OnMessage(event, &message)
+
+	case event == "error":
+		var e Error
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		if e.Message == "" && e.Code == "" {
+			var errorResponse ErrorResponse
+			if err := json.Unmarshal(data, &errorResponse); err == nil {
+				e = errorResponse.Error
+			}
+		}
+		return handler.OnError(&e)
+	}
+	return nil
+}
+
+func (m *ThreadMessage) applyDelta(delta *MessageDelta) error {
+	for _, part := range delta.Delta.Content {
+		if part.Index < 0 {
+			return fmt.Errorf("message delta %s: invalid content index %d", delta.ID, part.Index)
+		}
+	}
+	if delta.Delta.Role != "" {
+		m.Role = delta.Delta.Role
+	}
+	for _, part := range delta.Delta.Content {
+		for len(m.Content) <= part.Index {
+			m.Content = append(m.Content, MessageContent{Index: len(m.Content)})
+		}
+		content := &m.Content[part.Index]
+		if part.Type != "" {
+			content.Type = part.Type
+		}
+		if part.Text != nil {
+			if content.Text == nil {
+				content.Text = &MessageText{}
+			}
+			content.Text.Value += part.Text.Value
+			content.Text.Annotations = append(content.Text.Annotations, part.Text.Annotations...)
+		}
+		if part.ImageFile != nil {
+			content.ImageFile = part.ImageFile
+		}
+		if part.ImageURL != nil {
+			content.ImageURL = part.ImageURL
+		}
+	}
+	return nil
+}
+
+func (s *RunStep) applyDelta(delta *RunStepDelta) error {
+	details := delta.Delta.StepDetails
+	for _, part := range details.ToolCalls {
+		if part.Index < 0 {
+			return fmt.Errorf("run step delta %s: invalid tool call index %d", delta.ID, part.Index)
+		}
+	}
+	if details.Type != "" {
+		s.StepDetails.Type = details.Type
+		if s.Type == "" {
+			s.Type = details.Type
+		}
+	}
+	if details.MessageCreation != nil {
+		s.StepDetails.MessageCreation = details.MessageCreation
+	}
+	for _, part := range details.ToolCalls {
+		for len(s.StepDetails.ToolCalls) <= part.Index {
+			s.StepDetails.ToolCalls = append(s.StepDetails.ToolCalls, RunStepToolCall{Index: len(s.StepDetails.ToolCalls)})
+		}
+		call := &s.StepDetails.ToolCalls[part.Index]
+		if part.ID != "" {
+			call.ID = part.ID
+		}
+		if part.Type != "" {
+			call.Type = part.Type
+		}
+		if part.FileSearch != nil {
+			call.FileSearch = part.FileSearch
+		}
+		if part.Function != nil {
+			if call.Function == nil {
+				call.Function = &RunStepFunctionCall{}
+			}
+			if part.Function.Name != "" {
+				call.Function.Name = part.Function.Name
+			}
+			call.Function.Arguments += part.Function.Arguments
+			if part.Function.Output != "" {
+				call.Function.Output = part.Function.Output
+			}
+		}
+		if part.CodeInterpreter != nil {
+			if call.CodeInterpreter == nil {
+				call.CodeInterpreter = &CodeInterpreterCall{}
+			}
+			call.CodeInterpreter.Input += part.CodeInterpreter.Input
+			call.CodeInterpreter.Outputs = append(call.CodeInterpreter.Outputs, part.CodeInterpreter.Outputs...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package aoai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_readEventStream(t *testing.T) {
+	type event struct {
+		Event string
+		Data  string
+	}
+	tests := []struct {
+		name   string
+		stream string
+		want   []event
+	}{
+		{
+			name:   "dataOnly",
+			stream: "data: {\"a\":1}\n\ndata: {\"a\":2}\n\ndata: [DONE]\n\ndata: {\"a\":3}\n\n",
+			want:   []event{{Data: `{"a":1}`}, {Data: `{"a":2}`}},
+		},
+		{
+			name:   "namedEvents",
+			stream: ": keep-alive\r\nevent: thread.run.created\r\ndata: {\"id\":\"run\"}\r\n\r\nevent: done\ndata: [DONE]\n\n",
+			want:   []event{{Event: "thread.run.created", Data: `{"id":"run"}`}},
+		},
+		{
+			name:   "multiLineDataWithoutTrailingBlankLine",
+			stream: "event: message\ndata: line1\ndata: line2",
+			want:   []event{{Event: "message", Data: "line1\nline2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []event
+			err := readEventStream(context.Background(), strings.NewReader(tt.stream), func(name string, data []byte) error {
+				got = append(got, event{Event: name, Data: string(data)})
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("readEventStream() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readEventStream() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// recordingRunHandler implements every run stream handler method but OnError.
+type recordingRunHandler struct {
+	NopRunStreamHandler
+	events []string
+	text   string
+	args   string
+}
+
+func (h *recordingRunHandler) OnThread(event string, thread *Thread) error {
+	h.events = append(h.events, event)
+	return nil
+}
+
+func (h *recordingRunHandler) OnRun(event string, run *Run) error {
+	h.events = append(h.events, event)
+	return nil
+}
+
+func (h *recordingRunHandler) OnRunStep(event string, step *RunStep) error {
+	h.events = append(h.events, event)
+	return nil
+}
+
+func (h *recordingRunHandler) OnRunStepDelta(delta *RunStepDelta, step *RunStep) error {
+	h.args = step.StepDetails.ToolCalls[0].Function.Arguments
+	return nil
+}
+
+func (h *recordingRunHandler) OnMessage(event string, message *ThreadMessage) error {
+	h.events = append(h.events, event)
+	return nil
+}
+
+func (h *recordingRunHandler) OnMessageDelta(delta *MessageDelta, message *ThreadMessage) error {
+	h.text = message.Text()
+	return nil
+}
+
+// onlyRunHandler implements a single handler method.
+type onlyRunHandler struct {
+	NopRunStreamHandler
+	statuses []string
+}
+
+func (h *onlyRunHandler) OnRun(event string, run *Run) error {
+	h.statuses = append(h.statuses, run.Status)
+	return nil
+}
+
+const recordedRunStream = `event: thread.created
+data: {"id":"thread-1","object":"thread"}
+
+event: thread.run.created
+data: {"id":"run-1","object":"thread.run","status":"queued"}
+
+event: thread.run.step.created
+data: {"id":"step-1","object":"thread.run.step","type":"tool_calls","status":"in_progress"}
+
+event: thread.run.step.delta
+data: {"id":"step-1","object":"thread.run.step.delta","delta":{"step_details":{"type":"tool_calls","tool_calls":[{"index":0,"id":"call-1","type":"function","function":{"name":"get_weather","arguments":""}}]}}}
+
+event: thread.run.step.delta
+data: {"id":"step-1","object":"thread.run.step.delta","delta":{"step_details":{"type":"tool_calls","tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}}
+
+event: thread.run.step.delta
+data: {"id":"step-1","object":"thread.run.step.delta","delta":{"step_details":{"type":"tool_calls","tool_calls":[{"index":0,"function":{"arguments":"\"Tokyo\"}"}}]}}}
+
+event: thread.message.created
+data: {"id":"msg-1","object":"thread.message","role":"assistant","status":"in_progress","content":[]}
+
+event: thread.message.delta
+data: {"id":"msg-1","object":"thread.message.delta","delta":{"content":[{"index":0,"type":"text","text":{"value":"Sunny"}}]}}
+
+event: thread.message.delta
+data: {"id":"msg-1","object":"thread.message.delta","delta":{"content":[{"index":0,"type":"text","text":{"value":" in Tokyo"}}]}}
+
+event: thread.run.requires_action
+data: {"id":"run-1","object":"thread.run","status":"requires_action","required_action":{"type":"submit_tool_outputs","submit_tool_outputs":{"tool_calls":[{"id":"call-1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Tokyo\"}"}}]}}}
+
+event: done
+data: [DONE]
+
+`
+
+func TestAzureOpenAI_CreateThreadAndRunStream(t *testing.T) {
+	a := newTestAzureOpenAI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ThreadAndRunRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		if r.URL.Path != "/openai/threads/runs" || !request.Stream {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"code":"bad_request","message":"stream must be true"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(recordedRunStream))
+	}))
+
+	t.Run("allHandlers", func(t *testing.T) {
+		handler := &recordingRunHandler{}
+		got, err := a.CreateThreadAndRunStream(context.Background(), ThreadAndRunRequest{RunRequest: RunRequest{AssistantID: "asst-1"}}, handler)
+		if err != nil {
+			t.Fatalf("CreateThreadAndRunStream() error = %v", err)
+		}
+
+		wantEvents := []string{"thread.created", "thread.run.created", "thread.run.step.created", "thread.message.created", "thread.run.requires_action"}
+		if !reflect.DeepEqual(handler.events, wantEvents) {
+			t.Errorf("events = %v, want %v", handler.events, wantEvents)
+		}
+		if handler.text != "Sunny in Tokyo" || handler.args != `{"city":"Tokyo"}` {
+			t.Errorf("accumulated text = %q, arguments = %q", handler.text, handler.args)
+		}
+		if got.Thread.ID != "thread-1" || got.Run.Status != RunStatusRequiresAction || got.Run.RequiredAction.SubmitToolOutputs.ToolCalls[0].ID != "call-1" {
+			t.Errorf("result = %+v", got)
+		}
+		if len(got.Messages) != 1 || got.Messages[0].Role != "assistant" || got.Messages[0].Text() != "Sunny in Tokyo" {
+			t.Errorf("messages = %+v", got.Messages)
+		}
+		if len(got.Steps) != 1 || got.Steps[0].StepDetails.ToolCalls[0].Function.Name != "get_weather" {
+			t.Errorf("steps = %+v", got.Steps)
+		}
+	})
+
+	t.Run("partialHandler", func(t *testing.T) {
+		handler := &onlyRunHandler{}
+		if _, err := a.CreateThreadAndRunStream(context.Background(), ThreadAndRunRequest{}, handler); err != nil {
+			t.Fatalf("CreateThreadAndRunStream() error = %v", err)
+		}
+		if !reflect.DeepEqual(handler.statuses, []string{RunStatusQueued, RunStatusRequiresAction}) {
+			t.Errorf("statuses = %v", handler.statuses)
+		}
+	})
+
+	t.Run("noHandler", func(t *testing.T) {
+		got, err := a.CreateThreadAndRunStream(context.Background(), ThreadAndRunRequest{}, nil)
+		if err != nil || got.Run.Status != RunStatusRequiresAction {
+			t.Errorf("CreateThreadAndRunStream() = %+v, %v", got, err)
+		}
+	})
+}
+
+func TestRunStreamResult_errorEvent(t *testing.T) {
+	result := &RunStreamResult{}
+	err := result.dispatch("error", []byte(`{"code":"server_error","message":"boom"}`), NopRunStreamHandler{})
+	if e, ok := err.(*Error); !ok || e.Code != "server_error" {
+		t.Errorf("dispatch() error = %v", err)
+	}
+}
+
+func TestRunStreamResult_invalidDeltaIndex(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+		data  string
+	}{
+		{
+			name:  "messageDelta",
+			event: "thread.message.delta",
+			data:  `{"id":"msg-1","delta":{"content":[{"index":-1,"type":"text","text":{"value":"Hi"}}]}}`,
+		},
+		{
+			name:  "runStepDelta",
+			event: "thread.run.step.delta",
+			data:  `{"id":"step-1","delta":{"step_details":{"type":"tool_calls","tool_calls":[{"index":-1,"type":"function"}]}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &RunStreamResult{}
+			if err := result.dispatch(tt.event, []byte(tt.data), NopRunStreamHandler{}); err == nil {
+				t.Errorf("dispatch() error = nil, want an error")
+			}
+		})
+	}
+}
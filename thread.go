@@ -0,0 +1,307 @@
+package aoai
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+type ThreadRequest struct {
+	// messages:
+	//   description: A list of messages to start the thread with.
+	//   type: []MessageRequest
+	Messages []MessageRequest `json:"messages,omitempty"`
+
+	// tool_resources:
+	//   type: ToolResources
+	//   nullable: true
+	ToolResources *ToolResources `json:"tool_resources,omitempty"`
+
+	// metadata:
+	//   type: object
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type Thread struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - thread
+	Object string `json:"object,omitempty"`
+
+	// created_at:
+	//   type: integer
+	//   format: unixtime
+	CreatedAt int `json:"created_at,omitempty"`
+
+	// tool_resources:
+	//   type: ToolResources
+	ToolResources *ToolResources `json:"tool_resources,omitempty"`
+
+	// metadata:
+	//   type: object
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type MessageRequest struct {
+	// role:
+	//   type: string
+	//   enum:
+	//     - user
+	//     - assistant
+	Role string `json:"role"`
+
+	// content:
+	//   type: string
+	Content string `json:"content"`
+
+	// attachments:
+	//   description: A list of files attached to the message, and the tools they should be added to.
+	//   type: []MessageAttachment
+	Attachments []MessageAttachment `json:"attachments,omitempty"`
+
+	// metadata:
+	//   type: object
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type MessageAttachment struct {
+	// file_id:
+	//   type: string
+	FileID string `json:"file_id,omitempty"`
+
+	// tools:
+	//   description: The tools to add this file to, `code_interpreter` or `file_search`.
+	//   type: []Tool
+	Tools []Tool `json:"tools,omitempty"`
+}
+
+type ThreadMessage struct {
+	// id:
+	//   type: string
+	ID string `json:"id,omitempty"`
+
+	// object:
+	//   type: string
+	//   enum:
+	//     - thread.message
+	Object string `json:"object,omitempty"`
+
+	// created_at:
+	//   type: integer
+	//   format: unixtime
+	CreatedAt int `json:"created_at,omitempty"`
+
+	// thread_id:
+	//   type: string
+	ThreadID string `json:"thread_id,omitempty"`
+
+	// status:
+	//   type: string
+	//   enum:
+	//     - in_progress
+	//     - incomplete
+	//     - completed
+	Status string `json:"status,omitempty"`
+
+	// completed_at:
+	//   type: integer
+	//   format: unixtime
+	CompletedAt int `json:"completed_at,omitempty"`
+
+	// incomplete_at:
+	//   type: integer
+	//   format: unixtime
+	IncompleteAt int `json:"incomplete_at,omitempty"`
+
+	// role:
+	//   type: string
+	//   enum:
+	//     - user
+	//     - assistant
+	Role string `json:"role,omitempty"`
+
+	// content:
+	//   type: []MessageContent
+	Content []MessageContent `json:"content,omitempty"`
+
+	// assistant_id:
+	//   type: string
+	AssistantID string `json:"assistant_id,omitempty"`
+
+	// run_id:
+	//   type: string
+	RunID string `json:"run_id,omitempty"`
+
+	// attachments:
+	//   type: []MessageAttachment
+	Attachments []MessageAttachment `json:"attachments,omitempty"`
+
+	// metadata:
+	//   type: object
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Text concatenates the text content parts of the message.
+func (m *ThreadMessage) Text() string {
+	var builder strings.Builder
+	for _, content := range m.Content {
+		if content.Text != nil {
+			builder.WriteString(content.Text.Value)
+		}
+	}
+	return builder.String()
+}
+
+type MessageContent struct {
+	// index:
+	//   description: The index of the content part, only set on message deltas.
+	//   type: integer
+	Index int `json:"index,omitempty"`
+
+	// type:
+	//   type: string
+	//   enum:
+	//     - text
+	//     - image_file
+	//     - image_url
+	Type string `json:"type,omitempty"`
+
+	// text:
+	//   type: MessageText
+	Text *MessageText `json:"text,omitempty"`
+
+	// image_file:
+	//   type: MessageImageFile
+	ImageFile *MessageImageFile `json:"image_file,omitempty"`
+
+	// image_url:
+	//   type: MessageImageURL
+	ImageURL *MessageImageURL `json:"image_url,omitempty"`
+}
+
+type MessageText struct {
+	// value:
+	//   type: string
+	Value string `json:"value"`
+
+	// annotations:
+	//   type: []MessageAnnotation
+	Annotations []MessageAnnotation `json:"annotations,omitempty"`
+}
+
+type MessageAnnotation struct {
+	// type:
+	//   type: string
+	//   enum:
+	//     - file_citation
+	//     - file_path
+	Type string `json:"type,omitempty"`
+
+	// text:
+	//   description: The text in the message content that needs to be replaced.
+	//   type: string
+	Text string `json:"text,omitempty"`
+
+	// start_index:
+	//   type: integer
+	StartIndex int `json:"start_index,omitempty"`
+
+	// end_index:
+	//   type: integer
+	EndIndex int `json:"end_index,omitempty"`
+
+	// file_citation:
+	//   type: object
+	FileCitation *struct {
+		FileID string `json:"file_id,omitempty"`
+	} `json:"file_citation,omitempty"`
+
+	// file_path:
+	//   type: object
+	FilePath *struct {
+		FileID string `json:"file_id,omitempty"`
+	} `json:"file_path,omitempty"`
+}
+
+type MessageImageFile struct {
+	// file_id:
+	//   type: string
+	FileID string `json:"file_id,omitempty"`
+
+	// detail:
+	//   type: string
+	//   enum:
+	//     - auto
+	//     - low
+	//     - high
+	Detail string `json:"detail,omitempty"`
+}
+
+type MessageImageURL struct {
+	// url:
+	//   type: string
+	URL string `json:"url,omitempty"`
+
+	// detail:
+	//   type: string
+	//   enum:
+	//     - auto
+	//     - low
+	//     - high
+	Detail string `json:"detail,omitempty"`
+}
+
+type ThreadUpdateRequest struct {
+	// tool_resources:
+	//   type: ToolResources
+	ToolResources *ToolResources `json:"tool_resources,omitempty"`
+
+	// metadata:
+	//   type: object
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func (a *AzureOpenAI) CreateThread(ctx context.Context, request ThreadRequest) (*Thread, error) {
+	endpoint := a.assistantsEndpoint("/threads", nil)
+	return callPost[ThreadRequest, Thread](ctx, a, OperationCreateThread, endpoint, request)
+}
+
+func (a *AzureOpenAI) GetThread(ctx context.Context, threadID string) (*Thread, error) {
+	endpoint := a.assistantsEndpoint("/threads/"+url.PathEscape(threadID), nil)
+	return callGet[Thread](ctx, a, OperationGetThread, endpoint)
+}
+
+func (a *AzureOpenAI) UpdateThread(ctx context.Context, threadID string, request ThreadUpdateRequest) (*Thread, error) {
+	endpoint := a.assistantsEndpoint("/threads/"+url.PathEscape(threadID), nil)
+	return callPost[ThreadUpdateRequest, Thread](ctx, a, OperationUpdateThread, endpoint, request)
+}
+
+func (a *AzureOpenAI) DeleteThread(ctx context.Context, threadID string) (*DeleteResponse, error) {
+	endpoint := a.assistantsEndpoint("/threads/"+url.PathEscape(threadID), nil)
+	return callDelete[DeleteResponse](ctx, a, OperationDeleteThread, endpoint)
+}
+
+func (a *AzureOpenAI) CreateMessage(ctx context.Context, threadID string, request MessageRequest) (*ThreadMessage, error) {
+	endpoint := a.assistantsEndpoint("/threads/"+url.PathEscape(threadID)+"/messages", nil)
+	return callPost[MessageRequest, ThreadMessage](ctx, a, OperationCreateMessage, endpoint, request)
+}
+
+func (a *AzureOpenAI) GetMessage(ctx context.Context, threadID string, messageID string) (*ThreadMessage, error) {
+	endpoint := a.assistantsEndpoint("/threads/"+url.PathEscape(threadID)+"/messages/"+url.PathEscape(messageID), nil)
+	return callGet[ThreadMessage](ctx, a, OperationGetMessage, endpoint)
+}
+
+func (a *AzureOpenAI) ListMessages(ctx context.Context, threadID string, options ListOptions) (*ListResponse[ThreadMessage], error) {
+	endpoint := a.assistantsEndpoint("/threads/"+url.PathEscape(threadID)+"/messages", options.values(a.apiVersion))
+	return callGet[ListResponse[ThreadMessage]](ctx, a, OperationListMessages, endpoint)
+}
+
+func (a *AzureOpenAI) DeleteMessage(ctx context.Context, threadID string, messageID string) (*DeleteResponse, error) {
+	endpoint := a.assistantsEndpoint("/threads/"+url.PathEscape(threadID)+"/messages/"+url.PathEscape(messageID), nil)
+	return callDelete[DeleteResponse](ctx, a, OperationDeleteMessage, endpoint)
+}
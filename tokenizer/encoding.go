@@ -0,0 +1,148 @@
+package tokenizer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:generate go run ./internal/fetchranks -dir data
+
+// data holds the published `.tiktoken` rank files, which `go generate` downloads and verifies. They are embedded so
+// that no download is needed at runtime.
+//
+//go:embed data
+var data embed.FS
+
+const (
+	Cl100kBase = "cl100k_base"
+	O200kBase  = "o200k_base"
+)
+
+type spec struct {
+	special      map[string]int
+	alternatives []func(t *text, i int) int
+}
+
+var specs = map[string]spec{
+	Cl100kBase: {
+		special: map[string]int{
+			"<|endoftext|>":   100257,
+			"<|fim_prefix|>":  100258,
+			"<|fim_middle|>":  100259,
+			"<|fim_suffix|>":  100260,
+			"<|endofprompt|>": 100276,
+		},
+		alternatives: cl100kAlternatives,
+	},
+	O200kBase: {
+		special: map[string]int{
+			"<|endoftext|>":   199999,
+			"<|endofprompt|>": 200018,
+		},
+		alternatives: o200kAlternatives,
+	},
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = map[string]*Encoding{}
+)
+
+// GetEncoding returns the embedded encoding name, which is `cl100k_base` or `o200k_base`. Encodings are loaded on
+// first use and shared afterwards.
+func GetEncoding(name string) (*Encoding, error) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	if e, ok := encodings[name]; ok {
+		return e, nil
+	}
+	if _, ok := specs[name]; !ok {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+
+	ranks, err := data.ReadFile("data/" + name + ".tiktoken")
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := NewEncoding(name, bytes.NewReader(ranks))
+	if err != nil {
+		return nil, err
+	}
+	encodings[name] = e
+	return e, nil
+}
+
+// modelEncodings maps model names to their encoding.
+var modelEncodings = map[string]string{
+	"o1":                     O200kBase,
+	"o3":                     O200kBase,
+	"o4-mini":                O200kBase,
+	"gpt-5":                  O200kBase,
+	"gpt-4.1":                O200kBase,
+	"gpt-4.5":                O200kBase,
+	"gpt-4o":                 O200kBase,
+	"gpt-4":                  Cl100kBase,
+	"gpt-35-turbo":           Cl100kBase,
+	"gpt-3.5-turbo":          Cl100kBase,
+	"davinci-002":            Cl100kBase,
+	"babbage-002":            Cl100kBase,
+	"text-embedding-ada-002": Cl100kBase,
+	"text-embedding-3-small": Cl100kBase,
+	"text-embedding-3-large": Cl100kBase,
+}
+
+// modelPrefixEncodings maps versioned model names, e.g. `gpt-4o-2024-08-06` or `gpt-35-turbo-16k`, to their
+// encoding by prefix.
+var modelPrefixEncodings = map[string]string{
+	"o1-":            O200kBase,
+	"o3-":            O200kBase,
+	"o4-mini-":       O200kBase,
+	"gpt-5-":         O200kBase,
+	"gpt-4.1-":       O200kBase,
+	"gpt-4.5-":       O200kBase,
+	"gpt-4o-":        O200kBase,
+	"chatgpt-4o-":    O200kBase,
+	"gpt-4-":         Cl100kBase,
+	"gpt-35-turbo-":  Cl100kBase,
+	"gpt-3.5-turbo-": Cl100kBase,
+}
+
+// EncodingNameForModel returns the name of the encoding used by model, such as `gpt-35-turbo`, `gpt-4` or `gpt-4o`.
+// Fine-tuned models of the form `<base model>.ft-<id>` use the encoding of their base model.
+func EncodingNameForModel(model string) (string, error) {
+	name := strings.ToLower(model)
+	if base, _, ok := strings.Cut(name, ".ft-"); ok {
+		name = base
+	}
+
+	if encoding, ok := modelEncodings[name]; ok {
+		return encoding, nil
+	}
+	prefixes := make([]string, 0, len(modelPrefixEncodings))
+	for prefix := range modelPrefixEncodings {
+		prefixes = append(prefixes, prefix)
+	}
+	// prefer the most specific prefix
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return modelPrefixEncodings[prefix], nil
+		}
+	}
+	return "", fmt.Errorf("no encoding known for model %q", model)
+}
+
+// EncodingForModel returns the embedded encoding used by model.
+func EncodingForModel(model string) (*Encoding, error) {
+	name, err := EncodingNameForModel(model)
+	if err != nil {
+		return nil, err
+	}
+	return GetEncoding(name)
+}
@@ -0,0 +1,72 @@
+// Command fetchranks downloads the tiktoken rank files embedded by the tokenizer package and verifies their digests.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+var files = []struct {
+	name   string
+	url    string
+	sha256 string
+}{
+	{
+		name:   "cl100k_base.tiktoken",
+		url:    "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken",
+		sha256: "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+	},
+	{
+		name:   "o200k_base.tiktoken",
+		url:    "https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken",
+		sha256: "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+	},
+}
+
+func main() {
+	dir := flag.String("dir", "data", "directory to write the rank files to")
+	flag.Parse()
+
+	for _, file := range files {
+		path := filepath.Join(*dir, file.name)
+		if err := fetch(file.url, path, file.sha256); err != nil {
+			log.Fatalf("%s: %v", file.name, err)
+		}
+	}
+}
+
+func fetch(url, path, digest string) error {
+	if content, err := os.ReadFile(path); err == nil && sum(content) == digest {
+		return nil
+	}
+
+	response, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, response.Status)
+	}
+
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if got := sum(content); got != digest {
+		return fmt.Errorf("sha256 is %s, want %s", got, digest)
+	}
+	return os.WriteFile(path, content, 0o644)
+}
+
+func sum(content []byte) string {
+	digest := sha256.Sum256(content)
+	return hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,229 @@
+package tokenizer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// The pre-tokenizers split text into pieces the same way as the regular expressions of tiktoken. Go's regexp
+// package has no lookahead and no backtracking, so each alternative of the expressions is implemented by hand and
+// tried in order, returning the length of its first match like a backtracking engine would.
+
+// text is a string decoded into runes, keeping the byte offset of every rune so that invalid UTF-8 is preserved.
+type text struct {
+	s       string
+	runes   []rune
+	offsets []int
+}
+
+func newText(s string) *text {
+	t := &text{s: s}
+	for offset := 0; offset < len(s); {
+		r, size := utf8.DecodeRuneInString(s[offset:])
+		t.runes = append(t.runes, r)
+		t.offsets = append(t.offsets, offset)
+		offset += size
+	}
+	t.offsets = append(t.offsets, len(s))
+	return t
+}
+
+func (t *text) at(i int) (rune, bool) {
+	if i >= len(t.runes) {
+		return 0, false
+	}
+	return t.runes[i], true
+}
+
+// run returns the end of the run of runes matching class that starts at i.
+func (t *text) run(i int, class func(rune) bool) int {
+	for i < len(t.runes) && class(t.runes[i]) {
+		i++
+	}
+	return i
+}
+
+// is reports whether the rune at i exists and matches class.
+func (t *text) is(i int, class func(rune) bool) bool {
+	r, ok := t.at(i)
+	return ok && class(r)
+}
+
+// split applies alternatives at every position and returns the pieces. An alternative returns the length of its
+// match in runes, or 0 if it does not match.
+func (t *text) split(alternatives []func(t *text, i int) int) []string {
+	var pieces []string
+	for i := 0; i < len(t.runes); {
+		n := 1
+		for _, alternative := range alternatives {
+			if m := alternative(t, i); m > 0 {
+				n = m
+				break
+			}
+		}
+		pieces = append(pieces, t.s[t.offsets[i]:t.offsets[i+n]])
+		i += n
+	}
+	return pieces
+}
+
+func isLetter(r rune) bool  { return unicode.IsLetter(r) }
+func isNumber(r rune) bool  { return unicode.IsNumber(r) }
+func isSpace(r rune) bool   { return unicode.IsSpace(r) }
+func isNewline(r rune) bool { return r == '\r' || r == '\n' }
+
+// isWordPrefix is `[^\r\n\p{L}\p{N}]`.
+func isWordPrefix(r rune) bool {
+	return !isNewline(r) && !isLetter(r) && !isNumber(r)
+}
+
+// isPunctuation is `[^\s\p{L}\p{N}]`.
+func isPunctuation(r rune) bool {
+	return !isSpace(r) && !isLetter(r) && !isNumber(r)
+}
+
+// isUpper is `[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]`.
+func isUpper(r rune) bool {
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// isLower is `[\p{Ll}\p{Lm}\p{Lo}\p{M}]`.
+func isLower(r rune) bool {
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+var contractions = []string{"s", "t", "re", "ve", "m", "ll", "d"}
+
+// contraction matches `(?i:'s|'t|'re|'ve|'m|'ll|'d)`.
+func contraction(t *text, i int) int {
+	if r, ok := t.at(i); !ok || r != '\'' {
+		return 0
+	}
+	for _, suffix := range contractions {
+		matched := true
+		for j, c := range suffix {
+			if r, ok := t.at(i + 1 + j); !ok || unicode.ToLower(r) != c {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return 1 + len(suffix)
+		}
+	}
+	return 0
+}
+
+// prefixes returns the start positions after an optional `[^\r\n\p{L}\p{N}]?`, greedy first.
+func prefixes(t *text, i int) []int {
+	if t.is(i, isWordPrefix) {
+		return []int{i + 1, i}
+	}
+	return []int{i}
+}
+
+// numbers matches `\p{N}{1,3}`.
+func numbers(t *text, i int) int {
+	n := 0
+	for n < 3 && t.is(i+n, isNumber) {
+		n++
+	}
+	return n
+}
+
+// punctuation matches ` ?[^\s\p{L}\p{N}]+` followed by a run of trailing runes.
+func punctuation(t *text, i int, trailing func(rune) bool) int {
+	start := i
+	if r, ok := t.at(i); ok && r == ' ' && t.is(i+1, isPunctuation) {
+		start = i + 1
+	}
+	end := t.run(start, isPunctuation)
+	if end == start {
+		return 0
+	}
+	return t.run(end, trailing) - i
+}
+
+// newlines matches `\s*[\r\n]+`, which ends after the last newline of the whitespace run.
+func newlines(t *text, i int) int {
+	end := t.run(i, isSpace)
+	for j := end - 1; j >= i; j-- {
+		if isNewline(t.runes[j]) {
+			return j + 1 - i
+		}
+	}
+	return 0
+}
+
+// trailingSpace matches `\s+(?!\S)`, which leaves the last space of a run for the following word.
+func trailingSpace(t *text, i int) int {
+	end := t.run(i, isSpace)
+	if end == i {
+		return 0
+	}
+	if end == len(t.runes) {
+		return end - i
+	}
+	return end - 1 - i
+}
+
+// spaces matches `\s+`.
+func spaces(t *text, i int) int {
+	return t.run(i, isSpace) - i
+}
+
+// cl100kAlternatives implements
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+var cl100kAlternatives = []func(t *text, i int) int{
+	contraction,
+	func(t *text, i int) int {
+		for _, p := range prefixes(t, i) {
+			if end := t.run(p, isLetter); end > p {
+				return end - i
+			}
+		}
+		return 0
+	},
+	numbers,
+	func(t *text, i int) int { return punctuation(t, i, isNewline) },
+	newlines,
+	trailingSpace,
+	spaces,
+}
+
+// o200kAlternatives implements
+//
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?
+//	|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?
+//	|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+var o200kAlternatives = []func(t *text, i int) int{
+	func(t *text, i int) int {
+		for _, p := range prefixes(t, i) {
+			// backtrack the greedy upper case run until a lower case run can start
+			for k := t.run(p, isUpper); k >= p; k-- {
+				if t.is(k, isLower) {
+					end := t.run(k, isLower)
+					return end + contraction(t, end) - i
+				}
+			}
+		}
+		return 0
+	},
+	func(t *text, i int) int {
+		for _, p := range prefixes(t, i) {
+			if upper := t.run(p, isUpper); upper > p {
+				end := t.run(upper, isLower)
+				return end + contraction(t, end) - i
+			}
+		}
+		return 0
+	},
+	numbers,
+	func(t *text, i int) int {
+		return punctuation(t, i, func(r rune) bool { return isNewline(r) || r == '/' })
+	},
+	newlines,
+	trailingSpace,
+	spaces,
+}
@@ -0,0 +1,187 @@
+// Package tokenizer implements the byte pair encoding of tiktoken, so that tokens can be counted before a request
+// is sent to Azure OpenAI.
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Encoding turns text into tokens and back. It is safe for concurrent use.
+type Encoding struct {
+	name     string
+	ranks    map[string]int
+	decoder  map[int]string
+	special  map[string]int
+	specials []string
+	split    func(string) []string
+}
+
+// NewEncoding creates the encoding name, which is `cl100k_base` or `o200k_base`, from ranks in the `.tiktoken`
+// format: one base64 encoded token and its rank per line.
+func NewEncoding(name string, ranks io.Reader) (*Encoding, error) {
+	spec, ok := specs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+	parsed, err := parseRanks(ranks)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return newEncoding(name, parsed, spec.special, spec.alternatives), nil
+}
+
+func newEncoding(name string, ranks map[string]int, special map[string]int, alternatives []func(t *text, i int) int) *Encoding {
+	e := &Encoding{
+		name:    name,
+		ranks:   ranks,
+		decoder: make(map[int]string, len(ranks)+len(special)),
+		special: special,
+		split: func(s string) []string {
+			return newText(s).split(alternatives)
+		},
+	}
+	for token, rank := range ranks {
+		e.decoder[rank] = token
+	}
+	for token, rank := range special {
+		e.decoder[rank] = token
+		e.specials = append(e.specials, token)
+	}
+	// prefer the longest special token when one is a prefix of another
+	sort.Slice(e.specials, func(i, j int) bool { return len(e.specials[i]) > len(e.specials[j]) })
+	return e
+}
+
+func parseRanks(r io.Reader) (map[string]int, error) {
+	ranks := map[string]int{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		token, rank, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a token and a rank", line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		value, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ranks[string(decoded)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("no ranks")
+	}
+	return ranks, nil
+}
+
+// Name returns the name of the encoding, e.g. `cl100k_base`.
+func (e *Encoding) Name() string {
+	return e.name
+}
+
+// Encode returns the tokens of text. Special tokens such as `<|endoftext|>` are encoded as ordinary text.
+func (e *Encoding) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range e.split(text) {
+		tokens = e.appendPiece(tokens, piece)
+	}
+	return tokens
+}
+
+// EncodeWithSpecialTokens returns the tokens of text, encoding special tokens such as `<|endoftext|>` as their
+// reserved token.
+func (e *Encoding) EncodeWithSpecialTokens(text string) []int {
+	var tokens []int
+	for text != "" {
+		start, special := e.nextSpecial(text)
+		tokens = append(tokens, e.Encode(text[:start])...)
+		if special == "" {
+			break
+		}
+		tokens = append(tokens, e.special[special])
+		text = text[start+len(special):]
+	}
+	return tokens
+}
+
+// nextSpecial returns the position of the first special token in text, or len(text) and "" if there is none.
+func (e *Encoding) nextSpecial(text string) (int, string) {
+	first, found := len(text), ""
+	for _, special := range e.specials {
+		if i := strings.Index(text, special); i >= 0 && i < first {
+			first, found = i, special
+		}
+	}
+	return first, found
+}
+
+// Count returns the number of tokens of text, encoded like Encode.
+func (e *Encoding) Count(text string) int {
+	return len(e.Encode(text))
+}
+
+// Decode returns the text of tokens. Tokens unknown to the encoding are skipped.
+func (e *Encoding) Decode(tokens []int) string {
+	var buffer bytes.Buffer
+	for _, token := range tokens {
+		buffer.WriteString(e.decoder[token])
+	}
+	return buffer.String()
+}
+
+// appendPiece appends the tokens of a pre-tokenized piece, merging its bytes pairwise by lowest rank.
+func (e *Encoding) appendPiece(tokens []int, piece string) []int {
+	if rank, ok := e.ranks[piece]; ok {
+		return append(tokens, rank)
+	}
+
+	// parts[i] is the start of the i-th part; the last entry is the end of the piece
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+	rank := func(i int) int {
+		if i+2 >= len(parts) {
+			return math.MaxInt
+		}
+		if r, ok := e.ranks[piece[parts[i]:parts[i+2]]]; ok {
+			return r
+		}
+		return math.MaxInt
+	}
+
+	for len(parts) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i < len(parts)-2; i++ {
+			if r := rank(i); r < bestRank {
+				best, bestRank = i, r
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+
+	for i := 0; i < len(parts)-1; i++ {
+		tokens = append(tokens, e.ranks[piece[parts[i]:parts[i+1]]])
+	}
+	return tokens
+}
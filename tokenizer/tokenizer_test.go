@@ -0,0 +1,205 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_split(t *testing.T) {
+	tests := []struct {
+		name         string
+		alternatives []func(t *text, i int) int
+		text         string
+		want         []string
+	}{
+		{
+			name:         "cl100kMixed",
+			alternatives: cl100kAlternatives,
+			text:         "I'm 12345 ok!!\n\n  x",
+			want:         []string{"I", "'m", " ", "123", "45", " ok", "!!\n\n", " ", " x"},
+		},
+		{name: "cl100kTrailingSpaces", alternatives: cl100kAlternatives, text: "hi  ", want: []string{"hi", "  "}},
+		{name: "cl100kNewlineInSpaces", alternatives: cl100kAlternatives, text: "a \n b", want: []string{"a", " \n", " b"}},
+		{name: "cl100kUpperContraction", alternatives: cl100kAlternatives, text: "WE'RE", want: []string{"WE", "'RE"}},
+		{name: "cl100kInvalidUTF8", alternatives: cl100kAlternatives, text: "a\xffb", want: []string{"a", "\xffb"}},
+		{name: "o200kWords", alternatives: o200kAlternatives, text: "Hello World's test", want: []string{"Hello", " World's", " test"}},
+		{name: "o200kCamelCase", alternatives: o200kAlternatives, text: "HTTPServer ABC def", want: []string{"HTTPServer", " ABC", " def"}},
+		{name: "o200kSlash", alternatives: o200kAlternatives, text: "a/b ./\n", want: []string{"a", "/b", " ./\n"}},
+		{name: "o200kNumbers", alternatives: o200kAlternatives, text: "12345", want: []string{"123", "45"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newText(tt.text).split(tt.alternatives); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("split() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// testRanks ranks every byte by its value and adds a few merges.
+func testRanks() map[string]int {
+	ranks := map[string]int{}
+	for b := 0; b < 256; b++ {
+		ranks[string([]byte{byte(b)})] = b
+	}
+	ranks["ll"] = 256
+	ranks["he"] = 257
+	ranks["hell"] = 258
+	ranks[" w"] = 259
+	return ranks
+}
+
+func TestEncoding(t *testing.T) {
+	e := newEncoding("test", testRanks(), map[string]int{"<|endoftext|>": 300}, cl100kAlternatives)
+
+	tests := []struct {
+		text string
+		want []int
+	}{
+		{text: "hello", want: []int{258, 'o'}},
+		{text: "hello world", want: []int{258, 'o', 259, 'o', 'r', 'l', 'd'}},
+		{text: "", want: nil},
+		{text: "\xff", want: []int{0xff}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.text), func(t *testing.T) {
+			got := e.Encode(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Encode() = %v, want %v", got, tt.want)
+			}
+			if decoded := e.Decode(got); decoded != tt.text {
+				t.Errorf("Decode() = %q, want %q", decoded, tt.text)
+			}
+			if count := e.Count(tt.text); count != len(tt.want) {
+				t.Errorf("Count() = %d, want %d", count, len(tt.want))
+			}
+		})
+	}
+
+	withSpecial := e.EncodeWithSpecialTokens("he<|endoftext|>ll")
+	if !reflect.DeepEqual(withSpecial, []int{257, 300, 256}) {
+		t.Errorf("EncodeWithSpecialTokens() = %v", withSpecial)
+	}
+	if e.Decode(withSpecial) != "he<|endoftext|>ll" {
+		t.Errorf("Decode() = %q", e.Decode(withSpecial))
+	}
+	for _, token := range e.Encode("<|endoftext|>") {
+		if token == 300 {
+			t.Errorf("Encode() encoded a special token")
+		}
+	}
+}
+
+func TestNewEncoding(t *testing.T) {
+	var file strings.Builder
+	for token, rank := range testRanks() {
+		fmt.Fprintf(&file, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+
+	e, err := NewEncoding(O200kBase, strings.NewReader(file.String()))
+	if err != nil {
+		t.Fatalf("NewEncoding() error = %v", err)
+	}
+	if got := e.EncodeWithSpecialTokens("hello<|endoftext|>"); !reflect.DeepEqual(got, []int{258, 'o', 199999}) {
+		t.Errorf("EncodeWithSpecialTokens() = %v", got)
+	}
+
+	for name, ranks := range map[string]string{"unknown": "aGk= 1\n", O200kBase: "aGk=\n", Cl100kBase: ""} {
+		if _, err := NewEncoding(name, strings.NewReader(ranks)); err == nil {
+			t.Errorf("NewEncoding(%q, %q) error = nil", name, ranks)
+		}
+	}
+}
+
+func TestEncodingNameForModel(t *testing.T) {
+	tests := []struct {
+		model   string
+		want    string
+		wantErr bool
+	}{
+		{model: "gpt-35-turbo", want: Cl100kBase},
+		{model: "gpt-35-turbo-16k", want: Cl100kBase},
+		{model: "gpt-4", want: Cl100kBase},
+		{model: "gpt-4-32k-0613", want: Cl100kBase},
+		{model: "gpt-4o", want: O200kBase},
+		{model: "GPT-4o-mini-2024-07-18", want: O200kBase},
+		{model: "gpt-4.1-nano", want: O200kBase},
+		{model: "gpt-4o-2024-08-06.ft-0123456789abcdef", want: O200kBase},
+		{model: "o3-mini", want: O200kBase},
+		{model: "text-embedding-3-small", want: Cl100kBase},
+		{model: "davinci", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got, err := EncodingNameForModel(tt.model)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("EncodingNameForModel() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// knownTokens are the tokens of the reference tiktoken implementation.
+var knownTokens = []struct {
+	encoding string
+	text     string
+	want     []int
+}{
+	{Cl100kBase, "hello world", []int{15339, 1917}},
+	{Cl100kBase, "Hello, world!", []int{9906, 11, 1917, 0}},
+	{Cl100kBase, "tiktoken is great!", []int{83, 1609, 5963, 374, 2294, 0}},
+	{Cl100kBase, "I'm 12345 ok!!\n\n  x", []int{40, 2846, 220, 4513, 1774, 5509, 25833, 220, 865}},
+	{Cl100kBase, "WE'RE here, they'll see   you\n\n\n", []int{12739, 95253, 1618, 11, 814, 3358, 1518, 256, 499, 1432}},
+	{Cl100kBase, "HTTPServer ABC def", []int{9412, 5592, 19921, 711}},
+	{Cl100kBase, "a/b ./\n", []int{64, 3554, 662, 6018}},
+	{Cl100kBase, "func main() {\n\tfmt.Println(\"héllo\")\n}\n", []int{2900, 1925, 368, 341, 11254, 12701, 446, 71, 19010, 385, 1158, 534}},
+	{Cl100kBase, "日本語のテキスト、そして絵文字 🎉👍🏽", []int{9080, 22656, 45918, 252, 16144, 57933, 62903, 71634, 5486, 80539, 58254, 113, 88435, 11410, 236, 231, 9468, 239, 235, 9468, 237, 121}},
+	{Cl100kBase, "naïve café — “quotes” 3.14159 and 1,000,000", []int{3458, 38672, 588, 53050, 2001, 1054, 54382, 863, 220, 18, 13, 9335, 2946, 323, 220, 16, 11, 931, 11, 931}},
+	{Cl100kBase, "    indented\r\n\ttabs", []int{262, 1280, 16243, 319, 3324, 3518}},
+	{O200kBase, "hello world", []int{24912, 2375}},
+	{O200kBase, "Hello, world!", []int{13225, 11, 2375, 0}},
+	{O200kBase, "tiktoken is great!", []int{83, 8251, 2488, 382, 2212, 0}},
+	{O200kBase, "I'm 12345 ok!!\n\n  x", []int{15390, 220, 7633, 2548, 4763, 19886, 220, 1215}},
+	{O200kBase, "WE'RE here, they'll see   you\n\n\n", []int{18092, 6, 1099, 2105, 11, 57956, 1921, 256, 481, 2499}},
+	{O200kBase, "HTTPServer ABC def", []int{17893, 6444, 33047, 1056}},
+	{O200kBase, "a/b ./\n", []int{64, 7611, 887, 11124}},
+	{O200kBase, "func main() {\n\tfmt.Println(\"héllo\")\n}\n", []int{5652, 2758, 416, 405, 24728, 28250, 568, 79163, 72807, 1896, 739}},
+	{O200kBase, "日本語のテキスト、そして絵文字 🎉👍🏽", []int{9048, 40909, 3385, 16056, 18368, 38236, 1395, 59866, 13002, 113, 79831, 139786, 231, 82514, 52622, 121}},
+	{O200kBase, "naïve café — “quotes” 3.14159 and 1,000,000", []int{1503, 9954, 737, 30469, 2733, 966, 136724, 693, 220, 18, 13, 16926, 4621, 326, 220, 16, 11, 1302, 11, 1302}},
+	{O200kBase, "    indented\r\n\ttabs", []int{271, 1383, 23537, 370, 6264, 6071}},
+}
+
+func TestGetEncoding(t *testing.T) {
+	for _, tt := range knownTokens {
+		t.Run(fmt.Sprintf("%s/%q", tt.encoding, tt.text), func(t *testing.T) {
+			e, err := GetEncoding(tt.encoding)
+			if err != nil {
+				t.Fatalf("GetEncoding() error = %v", err)
+			}
+			got := e.Encode(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Encode() = %v, want %v", got, tt.want)
+			}
+			if decoded := e.Decode(got); decoded != tt.text {
+				t.Errorf("Decode() = %q", decoded)
+			}
+		})
+	}
+
+	for name, special := range map[string]int{Cl100kBase: 100257, O200kBase: 199999} {
+		e, err := GetEncoding(name)
+		if err != nil {
+			t.Fatalf("GetEncoding() error = %v", err)
+		}
+		if got := e.EncodeWithSpecialTokens("<|endoftext|>"); !reflect.DeepEqual(got, []int{special}) {
+			t.Errorf("%s: EncodeWithSpecialTokens() = %v", name, got)
+		}
+	}
+
+	if _, err := GetEncoding("p50k_base"); err == nil {
+		t.Errorf("GetEncoding() error = %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package aoai
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/anaregdesign/go-aoai/tokenizer"
+)
+
+// TokenCounter counts the prompt tokens of chat requests the way the service reports them in `prompt_tokens`,
+// including the tokens that format every message, function definitions and images.
+type TokenCounter struct {
+	model    string
+	encoding *tokenizer.Encoding
+}
+
+// NewTokenCounter returns a TokenCounter for model, such as `gpt-35-turbo` or `gpt-4o`, using its embedded
+// encoding.
+func NewTokenCounter(model string) (*TokenCounter, error) {
+	encoding, err := tokenizer.EncodingForModel(model)
+	if err != nil {
+		return nil, err
+	}
+	return NewTokenCounterWithEncoding(model, encoding), nil
+}
+
+// NewTokenCounterWithEncoding returns a TokenCounter for model that uses encoding instead of the embedded one.
+func NewTokenCounterWithEncoding(model string, encoding *tokenizer.Encoding) *TokenCounter {
+	return &TokenCounter{model: strings.ToLower(model), encoding: encoding}
+}
+
+// CountChatTokens returns the number of prompt tokens messages take for model.
+func CountChatTokens(model string, messages []ChatMessage) (int, error) {
+	counter, err := NewTokenCounter(model)
+	if err != nil {
+		return 0, err
+	}
+	return counter.CountMessages(messages), nil
+}
+
+// Text returns the number of tokens of s without any formatting.
+func (c *TokenCounter) Text(s string) int {
+	return c.encoding.Count(s)
+}
+
+// CountMessages returns the number of prompt tokens of messages, including the tokens that prime the reply.
+func (c *TokenCounter) CountMessages(messages []ChatMessage) int {
+	tokens := 3 // every reply is primed with <|start|>assistant<|message|>
+	for _, message := range messages {
+		tokens += c.countMessage(message)
+	}
+	return tokens
+}
+
+// countMessage returns the tokens a single message adds to CountMessages.
+func (c *TokenCounter) countMessage(message ChatMessage) int {
+	perMessage, perName := 3, 1
+	if strings.Contains(c.model, "turbo-0301") {
+		perMessage, perName = 4, -1
+	}
+
+	tokens := perMessage + c.Text(message.Role) + c.Text(message.Content) + c.Text(message.ToolCallID)
+	if message.Name != "" {
+		tokens += perName + c.Text(message.Name)
+	}
+	for _, part := range message.ContentParts {
+		tokens += c.countPart(part)
+	}
+	for _, call := range message.ToolCalls {
+		tokens += c.Text(call.Function.Name) + c.Text(call.Function.Arguments)
+	}
+	return tokens
+}
+
+func (c *TokenCounter) countPart(part ChatContentPart) int {
+	if part.ImageURL != nil {
+		return c.countImage(*part.ImageURL)
+	}
+	return c.Text(part.Text)
+}
+
+// imageTokens are the tokens an image takes: base for every image and tile for every 512px square of a high
+// detail image.
+type imageTokens struct {
+	base, tile int
+}
+
+var modelImageTokens = map[string]imageTokens{
+	"gpt-4o-mini": {base: 2833, tile: 5667},
+	"o1":          {base: 75, tile: 150},
+	"o3":          {base: 75, tile: 150},
+	"o4-mini":     {base: 75, tile: 150},
+}
+
+// countImage returns the tokens of an image. The size of `data:` URLs is read from the image; for other URLs it
+// is unknown and the largest possible size is assumed, so that the count is an upper bound.
+func (c *TokenCounter) countImage(url ChatImageURL) int {
+	cost := imageTokens{base: 85, tile: 170}
+	prefixes := make([]string, 0, len(modelImageTokens))
+	for prefix := range modelImageTokens {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(c.model, prefix) {
+			cost = modelImageTokens[prefix]
+			break
+		}
+	}
+
+	if url.Detail == "low" {
+		return cost.base
+	}
+	width, height, ok := imageSize(url.URL)
+	if !ok {
+		width, height = 2048, 768
+	}
+	return cost.base + cost.tile*imageTiles(float64(width), float64(height))
+}
+
+// imageTiles returns the number of 512px tiles of a high detail image, which is first scaled to fit in 2048x2048
+// and then so that its shortest side is at most 768px.
+func imageTiles(width, height float64) int {
+	if longest := math.Max(width, height); longest > 2048 {
+		width, height = width*2048/longest, height*2048/longest
+	}
+	if shortest := math.Min(width, height); shortest > 768 {
+		width, height = width*768/shortest, height*768/shortest
+	}
+	return int(math.Ceil(width/512) * math.Ceil(height/512))
+}
+
+func imageSize(url string) (int, int, bool) {
+	if !strings.HasPrefix(url, "data:") {
+		return 0, 0, false
+	}
+	_, encoded, ok := strings.Cut(url, ";base64,")
+	if !ok {
+		return 0, 0, false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, 0, false
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(decoded))
+	if err != nil {
+		return 0, 0, false
+	}
+	return config.Width, config.Height, true
+}
+
+// CountTools returns the number of prompt tokens function definitions take. The service renders them into the
+// system prompt; the count follows that rendering for each function and each of its top-level parameters.
+func (c *TokenCounter) CountTools(tools []Tool) int {
+	if len(tools) == 0 {
+		return 0
+	}
+
+	tokens := 12
+	for _, tool := range tools {
+		if tool.Function == nil {
+			continue
+		}
+		function := tool.Function
+		tokens += 7 + c.Text(function.Name+":"+strings.TrimSuffix(function.Description, "."))
+
+		var parameters struct {
+			Properties map[string]struct {
+				Type        any    `json:"type"`
+				Description string `json:"description"`
+				Enum        []any  `json:"enum"`
+			} `json:"properties"`
+		}
+		if len(function.Parameters) > 0 {
+			_ = json.Unmarshal(function.Parameters, &parameters)
+		}
+		if len(parameters.Properties) == 0 {
+			continue
+		}
+		tokens += 3
+		for name, property := range parameters.Properties {
+			tokens += 3
+			if len(property.Enum) > 0 {
+				tokens -= 3
+				for _, item := range property.Enum {
+					tokens += 3 + c.Text(fmt.Sprint(item))
+				}
+			}
+			kind, _ := property.Type.(string)
+			tokens += c.Text(name + ":" + kind + ":" + strings.TrimSuffix(property.Description, "."))
+		}
+	}
+	return tokens
+}
+
+// CountRequest returns the number of prompt tokens of the messages and tools of request.
+func (c *TokenCounter) CountRequest(request ChatRequest) int {
+	return c.CountMessages(request.Messages) + c.CountTools(request.Tools)
+}
+
+// MaxTokens returns the number of tokens left for the reply of request in a context window of contextWindow
+// tokens, or 0 if the prompt does not fit.
+func (c *TokenCounter) MaxTokens(contextWindow int, request ChatRequest) int {
+	if remaining := contextWindow - c.CountRequest(request); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
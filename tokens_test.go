@@ -0,0 +1,170 @@
+package aoai
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/anaregdesign/go-aoai/tokenizer"
+)
+
+// newTestTokenCounter returns a TokenCounter whose encoding has a token for every byte and no merges, so that
+// text counts as its length in bytes.
+func newTestTokenCounter(t *testing.T, model string) *TokenCounter {
+	t.Helper()
+	var ranks strings.Builder
+	for b := 0; b < 256; b++ {
+		fmt.Fprintf(&ranks, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	encoding, err := tokenizer.NewEncoding(tokenizer.O200kBase, strings.NewReader(ranks.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTokenCounterWithEncoding(model, encoding)
+}
+
+func pngDataURL(t *testing.T, width, height int) string {
+	t.Helper()
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buffer.Bytes())
+}
+
+func TestTokenCounter_CountMessages(t *testing.T) {
+	image := func(url, detail string) ChatMessage {
+		return ChatMessage{Role: RoleUser, ContentParts: []ChatContentPart{{Type: "image_url", ImageURL: &ChatImageURL{URL: url, Detail: detail}}}}
+	}
+	tests := []struct {
+		name     string
+		model    string
+		messages []ChatMessage
+		want     int
+	}{
+		{name: "empty", model: "gpt-4o", want: 3},
+		{
+			name:     "name",
+			model:    "gpt-4o",
+			messages: []ChatMessage{{Role: RoleSystem, Content: "hi"}, {Role: RoleUser, Content: "hello", Name: "bob"}},
+			want:     3 + (3 + 6 + 2) + (3 + 4 + 5 + 1 + 3),
+		},
+		{name: "legacyOverhead", model: "gpt-35-turbo-0301", messages: []ChatMessage{{Role: RoleUser, Content: "hi"}}, want: 3 + 4 + 4 + 2},
+		{
+			name:  "toolCalls",
+			model: "gpt-4o",
+			messages: []ChatMessage{
+				{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "1", Type: "function", Function: FunctionCall{Name: "f", Arguments: "{}"}}}},
+				{Role: RoleTool, Content: "ok", ToolCallID: "1"},
+			},
+			want: 3 + (3 + 9 + 1 + 2) + (3 + 4 + 2 + 1),
+		},
+		{name: "lowDetailImage", model: "gpt-4o", messages: []ChatMessage{image("https://example.com/a.png", "low")}, want: 3 + 3 + 4 + 85},
+		{name: "miniImage", model: "gpt-4o-mini", messages: []ChatMessage{image("https://example.com/a.png", "low")}, want: 3 + 3 + 4 + 2833},
+		{name: "smallImage", model: "gpt-4o", messages: []ChatMessage{image(pngDataURL(t, 100, 100), "high")}, want: 3 + 3 + 4 + 85 + 170},
+		{name: "unknownSizeImage", model: "gpt-4o", messages: []ChatMessage{image("https://example.com/a.png", "")}, want: 3 + 3 + 4 + 85 + 8*170},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newTestTokenCounter(t, tt.model).CountMessages(tt.messages); got != tt.want {
+				t.Errorf("CountMessages() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_imageTiles(t *testing.T) {
+	tests := []struct {
+		width, height float64
+		want          int
+	}{
+		{width: 512, height: 512, want: 1},
+		{width: 1024, height: 1024, want: 4},
+		{width: 4096, height: 4096, want: 4},
+		{width: 2048, height: 4096, want: 6},
+		{width: 800, height: 100, want: 2},
+	}
+	for _, tt := range tests {
+		if got := imageTiles(tt.width, tt.height); got != tt.want {
+			t.Errorf("imageTiles(%v, %v) = %d, want %d", tt.width, tt.height, got, tt.want)
+		}
+	}
+}
+
+func TestTokenCounter_CountTools(t *testing.T) {
+	c := newTestTokenCounter(t, "gpt-4o")
+	tests := []struct {
+		name  string
+		tools []Tool
+		want  int
+	}{
+		{name: "none", want: 0},
+		{name: "noParameters", tools: []Tool{{Type: "function", Function: &FunctionDefinition{Name: "f", Description: "Do."}}}, want: 12 + 7 + 4},
+		{
+			name: "enum",
+			tools: []Tool{{Type: "function", Function: &FunctionDefinition{
+				Name:        "f",
+				Description: "Do.",
+				Parameters:  json.RawMessage(`{"type":"object","properties":{"x":{"type":"string","description":"X.","enum":["a","bc"]}}}`),
+			}}},
+			want: 12 + 7 + 4 + 3 + (3 - 3 + (3 + 1) + (3 + 2) + len("x:string:X")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.CountTools(tt.tools); got != tt.want {
+				t.Errorf("CountTools() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenCounter_MaxTokens(t *testing.T) {
+	c := newTestTokenCounter(t, "gpt-4o")
+	request := ChatRequest{Messages: []ChatMessage{{Role: RoleUser, Content: "hi"}}}
+	if got := c.MaxTokens(100, request); got != 100-12 {
+		t.Errorf("MaxTokens() = %d, want %d", got, 100-12)
+	}
+	if got := c.MaxTokens(10, request); got != 0 {
+		t.Errorf("MaxTokens() = %d, want 0", got)
+	}
+}
+
+// cookbookMessages are the example messages of the OpenAI cookbook on counting tokens, which lists their counts.
+var cookbookMessages = []ChatMessage{
+	{Role: RoleSystem, Content: "You are a helpful, pattern-following assistant that translates corporate jargon into plain English."},
+	{Role: RoleSystem, Name: "example_user", Content: "New synergies will help drive top-line growth."},
+	{Role: RoleSystem, Name: "example_assistant", Content: "Things working well together will increase revenue."},
+	{Role: RoleSystem, Name: "example_user", Content: "Let's circle back when we have more bandwidth to touch base on opportunities for increased leverage."},
+	{Role: RoleSystem, Name: "example_assistant", Content: "Let's talk later when we're less busy about how to do better."},
+	{Role: RoleUser, Content: "This late pivot means we don't have time to boil the ocean for the client deliverable."},
+}
+
+func TestCountChatTokens(t *testing.T) {
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{model: "gpt-35-turbo-0301", want: 127},
+		{model: "gpt-35-turbo", want: 129},
+		{model: "gpt-4", want: 129},
+		{model: "gpt-4o", want: 124},
+		{model: "gpt-4o-mini", want: 124},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got, err := CountChatTokens(tt.model, cookbookMessages)
+			if err != nil {
+				t.Fatalf("CountChatTokens() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CountChatTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package aoai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FieldError is a constraint violated by a single request field. Field is the JSON path of the field, e.g. `messages[2].role`.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationError is returned by Validate with every violated constraint of a request.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid request: %s", strings.Join(messages, "; "))
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// validator collects field errors of a request.
+type validator struct {
+	errors []*FieldError
+}
+
+func (v *validator) check(ok bool, field string, format string, args ...any) {
+	if !ok {
+		v.errors = append(v.errors, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+func (v *validator) between(value, min, max float64, field string) {
+	v.check(min <= value && value <= max, field, "must be between %v and %v, got %v", min, max, value)
+}
+
+func (v *validator) err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errors}
+}
+
+// sortedKeys returns the keys of m in order, so that errors are reported deterministically.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Validate checks the documented constraints of the request without calling the API.
+func (r CompletionRequest) Validate() error {
+	v := &validator{}
+	v.check(len(r.Prompts) <= 2048, "prompt", "must have at most 2048 items, got %d", len(r.Prompts))
+	v.check(len(r.PromptTokens) <= 2048, "prompt", "must have at most 2048 items, got %d", len(r.PromptTokens))
+	v.check(r.Prompts == nil || r.PromptTokens == nil, "prompt", "must not be set as both text and tokens")
+	v.check(r.MaxTokens >= 0, "max_tokens", "must not be negative, got %d", r.MaxTokens)
+	v.between(r.Temperature, 0, 2, "temperature")
+	v.between(r.TopP, 0, 1, "top_p")
+	for _, key := range sortedKeys(r.LogitBias) {
+		v.between(float64(r.LogitBias[key]), -100, 100, fmt.Sprintf("logit_bias[%s]", key))
+	}
+	if r.N != 0 {
+		v.between(float64(r.N), 1, 128, "n")
+	}
+	v.between(float64(r.Logprobs), 0, 5, "logprobs")
+	v.check(len(r.Stop) <= 4, "stop", "must have at most 4 items, got %d", len(r.Stop))
+	v.between(r.PresencePenalty, -2, 2, "presence_penalty")
+	v.between(r.FrequencyPenalty, -2, 2, "frequency_penalty")
+	if r.BestOf != 0 {
+		v.between(float64(r.BestOf), 1, 128, "best_of")
+		// the service accepts best_of equal to n, which returns every candidate
+		v.check(r.N == 0 || r.BestOf >= r.N, "best_of", "must not be less than n (%d), got %d", r.N, r.BestOf)
+		v.check(!r.Stream, "best_of", "must not be set when streaming")
+	}
+	return v.err()
+}
+
+// Validate checks the documented constraints of the request without calling the API.
+func (r ChatRequest) Validate() error {
+	v := &validator{}
+	v.check(len(r.Messages) > 0, "messages", "must not be empty")
+	for i, message := range r.Messages {
+		v.check(message.Role != "", fmt.Sprintf("messages[%d].role", i), "must not be empty")
+	}
+	v.between(r.Temperature, 0, 2, "temperature")
+	v.between(r.TopP, 0, 1, "top_p")
+	if r.N != 0 {
+		v.between(float64(r.N), 1, 128, "n")
+	}
+	v.check(len(r.Stop) <= 4, "stop", "must have at most 4 items, got %d", len(r.Stop))
+	v.check(r.MaxTokens >= 0, "max_tokens", "must not be negative, got %d", r.MaxTokens)
+	v.check(r.MaxCompletionTokens >= 0, "max_completion_tokens", "must not be negative, got %d", r.MaxCompletionTokens)
+	v.between(float64(r.TopLogprobs), 0, 20, "top_logprobs")
+	v.check(r.TopLogprobs == 0 || r.Logprobs, "top_logprobs", "requires logprobs to be true")
+	v.between(r.PresencePenalty, -2, 2, "presence_penalty")
+	v.between(r.FrequencyPenalty, -2, 2, "frequency_penalty")
+	for _, key := range sortedKeys(r.LogitBias) {
+		v.between(r.LogitBias[key], -100, 100, fmt.Sprintf("logit_bias[%s]", key))
+	}
+	return v.err()
+}
+
+// Validate checks the documented constraints of the request without calling the API.
+func (r EmbeddingRequest) Validate() error {
+	v := &validator{}
+	count := len(r.Inputs) + len(r.InputTokens)
+	v.check(count > 0, "input", "must not be empty")
+	v.check(count <= 2048, "input", "must have at most 2048 items, got %d", count)
+	v.check(r.Inputs == nil || r.InputTokens == nil, "input", "must not be set as both text and tokens")
+	for i, input := range r.Inputs {
+		v.check(input != "", fmt.Sprintf("input[%d]", i), "must not be empty")
+	}
+	for i, tokens := range r.InputTokens {
+		v.check(len(tokens) > 0, fmt.Sprintf("input[%d]", i), "must not be empty")
+	}
+	return v.err()
+}
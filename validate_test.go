@@ -0,0 +1,139 @@
+package aoai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fieldsOf returns the field paths of a validation error.
+func fieldsOf(err error) []string {
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		return nil
+	}
+	fields := make([]string, len(validationError.Errors))
+	for i, e := range validationError.Errors {
+		fields[i] = e.Field
+	}
+	return fields
+}
+
+func TestCompletionRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request CompletionRequest
+		want    []string
+	}{
+		{name: "valid", request: CompletionRequest{Prompts: []string{"Hi"}, N: 2, BestOf: 3, Logprobs: 5, Temperature: 2}},
+		{name: "bestOfEqualToN", request: CompletionRequest{N: 2, BestOf: 2}},
+		{
+			name:    "outOfRange",
+			request: CompletionRequest{Temperature: 2.5, TopP: -0.1, N: 129, Logprobs: 6, PresencePenalty: 3, LogitBias: map[string]int{"2": 101, "1": -101}},
+			want:    []string{"temperature", "top_p", "logit_bias[1]", "logit_bias[2]", "n", "logprobs", "presence_penalty"},
+		},
+		{name: "tooManyStops", request: CompletionRequest{Stop: []string{"a", "b", "c", "d", "e"}}, want: []string{"stop"}},
+		{name: "bestOfLessThanN", request: CompletionRequest{N: 3, BestOf: 2}, want: []string{"best_of"}},
+		{name: "bestOfWithStream", request: CompletionRequest{BestOf: 2, Stream: true}, want: []string{"best_of"}},
+		{name: "bothPromptForms", request: CompletionRequest{Prompts: []string{"Hi"}, PromptTokens: TokenSlices{{1}}}, want: []string{"prompt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.request.Validate()
+			if got := fieldsOf(err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %v, want fields %v", err, tt.want)
+			}
+			if (err != nil) != (tt.want != nil) {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestChatRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request ChatRequest
+		want    []string
+	}{
+		{name: "valid", request: ChatRequest{Messages: []ChatMessage{{Role: RoleUser, Content: "Hi"}}, Logprobs: true, TopLogprobs: 20}},
+		{name: "noMessages", request: ChatRequest{}, want: []string{"messages"}},
+		{
+			name:    "invalidFields",
+			request: ChatRequest{Messages: []ChatMessage{{Role: RoleUser}, {Content: "Hi"}}, TopLogprobs: 21, FrequencyPenalty: -2.5},
+			want:    []string{"messages[1].role", "top_logprobs", "top_logprobs", "frequency_penalty"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fieldsOf(tt.request.Validate()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddingRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request EmbeddingRequest
+		want    []string
+	}{
+		{name: "valid", request: EmbeddingRequest{Inputs: []string{"Hi"}}},
+		{name: "validTokens", request: EmbeddingRequest{InputTokens: TokenSlices{{1, 2}}}},
+		{name: "empty", request: EmbeddingRequest{}, want: []string{"input"}},
+		{name: "emptyItems", request: EmbeddingRequest{Inputs: []string{"Hi", ""}}, want: []string{"input[1]"}},
+		{name: "tooMany", request: EmbeddingRequest{Inputs: strings.Fields(strings.Repeat("x ", 2049))}, want: []string{"input"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fieldsOf(tt.request.Validate()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	err := ChatRequest{Temperature: 3}.Validate()
+	want := "invalid request: messages: must not be empty; temperature: must be between 0 and 2, got 3"
+	if err == nil || err.Error() != want {
+		t.Errorf("Error() = %v, want %s", err, want)
+	}
+	var fieldError *FieldError
+	if !errors.As(err, &fieldError) || fieldError.Field != "messages" {
+		t.Errorf("errors.As() = %v", fieldError)
+	}
+}
+
+func TestWithValidation(t *testing.T) {
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	})
+	a := newTestAzureOpenAI(t, handler)
+	WithValidation()(a)
+
+	invalid := ChatRequest{Messages: []ChatMessage{{Role: RoleUser, Content: "Hi"}}, Temperature: 3}
+	if _, err := a.ChatCompletion(context.Background(), invalid); fieldsOf(err) == nil {
+		t.Errorf("ChatCompletion() error = %v", err)
+	}
+	if _, err := a.Embedding(context.Background(), EmbeddingRequest{}); fieldsOf(err) == nil {
+		t.Errorf("Embedding() error = %v", err)
+	}
+	if _, err := a.Completion(context.Background(), CompletionRequest{N: 200}); fieldsOf(err) == nil {
+		t.Errorf("Completion() error = %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("invalid requests were sent %d times", calls)
+	}
+
+	b := newTestAzureOpenAI(t, handler)
+	if _, err := b.ChatCompletion(context.Background(), invalid); err != nil || calls != 1 {
+		t.Errorf("ChatCompletion() without validation error = %v, calls = %d", err, calls)
+	}
+}